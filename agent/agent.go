@@ -1,7 +1,10 @@
 package agent
 
 import (
+	"bytes"
+	"calculator/evaluator"
 	"calculator/models"
+	"calculator/parser"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,8 +50,8 @@ func worker(id int) {
 			continue
 		}
 		time.Sleep(time.Duration(task.OperationTime) * time.Millisecond)
-		result := compute(task)
-		err = submitTaskResult(task.ID, result)
+		report := computeReport(task)
+		err = submitTaskReport(task.ID, report)
 		if err != nil {
 			fmt.Printf("Error submitting task %s: %v\n", task.ID, err)
 		}
@@ -78,9 +81,20 @@ func getTaskFromServer() (*models.Task, error) {
 }
 
 func submitTaskResult(taskID string, result float64) error {
+	return submitTaskValue(taskID, evaluator.Float(result))
+}
+
+func submitTaskValue(taskID string, result evaluator.Value) error {
+	return submitTaskReport(taskID, valueReport(result))
+}
+
+func submitTaskReport(taskID string, report *models.TaskResult) error {
 	url := fmt.Sprintf("%s/internal/task/%s", serverURL, taskID)
-	payload := fmt.Sprintf(`{"result":%v}`, result)
-	resp, err := http.Post(url, "application/json", strings.NewReader(payload))
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
 	if err != nil {
 		return err
 	}
@@ -92,40 +106,153 @@ func submitTaskResult(taskID string, result float64) error {
 	return nil
 }
 
+func valueReport(result evaluator.Value) *models.TaskResult {
+	report := &models.TaskResult{Result: result.Float()}
+	if _, isFloat := result.(evaluator.Float); !isFloat {
+		value := result.String()
+		report.Value = &value
+	}
+	return report
+}
+
+func errorReport(err error) *models.TaskResult {
+	message := err.Error()
+	return &models.TaskResult{Error: &message}
+}
+
+func computeReport(task *models.Task) *models.TaskResult {
+	if !evaluator.IsNumeric(task.Operation) {
+		result, err := computeValue(task)
+		if err != nil {
+			return errorReport(err)
+		}
+		return valueReport(result)
+	}
+	estimate, err := computeNumeric(task)
+	if err != nil {
+		return errorReport(err)
+	}
+	return &models.TaskResult{Result: estimate.Value, ErrorEstimate: &estimate.Error, Iterations: estimate.Iterations}
+}
+
+func computeNumeric(task *models.Task) (evaluator.Estimate, error) {
+	body, err := parser.Parse(task.Body)
+	if err != nil {
+		return evaluator.Estimate{}, err
+	}
+	from, err := evaluator.ParseValue(task.Arg1)
+	if err != nil {
+		return evaluator.Estimate{}, err
+	}
+	to, err := evaluator.ParseValue(task.Arg2)
+	if err != nil {
+		return evaluator.Estimate{}, err
+	}
+	return evaluator.EstimateNumeric(task.Operation, task.Index, from.Float(), to.Float(), body, nil)
+}
+
 func compute(task *models.Task) float64 {
-	getArgValue := func(arg string) float64 {
+	result, err := computeValue(task)
+	if err != nil {
+		return 0
+	}
+	return result.Float()
+}
+
+func computeValue(task *models.Task) (evaluator.Value, error) {
+	resolved := make(map[string]evaluator.Value)
+	getArgValue := func(arg string) (evaluator.Value, error) {
+		if value, ok := resolved[arg]; ok {
+			return value, nil
+		}
 		if strings.HasPrefix(arg, "$") {
-			taskID := strings.TrimPrefix(arg, "$")
-			for {
-				prevTask, err := getTaskResult(taskID)
-				if err == nil && prevTask.Result != nil {
-					return *prevTask.Result
-				}
-				time.Sleep(100 * time.Millisecond)
+			value, err := waitForDependency(strings.TrimPrefix(arg, "$"))
+			if err != nil {
+				return nil, err
 			}
+			resolved[arg] = value
+			return value, nil
+		}
+		return evaluator.ParseValue(arg)
+	}
+
+	if evaluator.IsNumeric(task.Operation) {
+		estimate, err := computeNumeric(task)
+		if err != nil {
+			return nil, err
 		}
-		val, err := strconv.ParseFloat(arg, 64)
+		return evaluator.Float(estimate.Value), nil
+	}
+
+	if task.Body != "" {
+		return computeChunk(task, getArgValue)
+	}
+
+	refs := []string{task.Arg1}
+	if task.Arg2 != "" {
+		refs = append(refs, task.Arg2)
+	}
+	if task.Arg3 != "" {
+		refs = append(refs, task.Arg3)
+	}
+
+	args := make([]evaluator.Value, len(refs))
+	for i, ref := range refs {
+		value, err := getArgValue(ref)
 		if err != nil {
-			return 0
+			return nil, err
 		}
-		return val
-	}
-
-	switch task.Operation {
-	case "+":
-		return getArgValue(task.Arg1) + getArgValue(task.Arg2)
-	case "-":
-		return getArgValue(task.Arg1) - getArgValue(task.Arg2)
-	case "*":
-		return getArgValue(task.Arg1) * getArgValue(task.Arg2)
-	case "/":
-		b := getArgValue(task.Arg2)
-		if b == 0 {
-			return 0
+		args[i] = value
+	}
+
+	return evaluator.ApplyValue(task.Operation, args...)
+}
+
+func computeChunk(task *models.Task, getArgValue func(string) (evaluator.Value, error)) (evaluator.Value, error) {
+	body, err := parser.Parse(task.Body)
+	if err != nil {
+		return nil, err
+	}
+	first, err := getArgValue(task.Arg1)
+	if err != nil {
+		return nil, err
+	}
+	from, err := evaluator.RangeBound(first)
+	if err != nil {
+		return nil, err
+	}
+	last, err := getArgValue(task.Arg2)
+	if err != nil {
+		return nil, err
+	}
+	to, err := evaluator.RangeBound(last)
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.ReduceRange(task.Operation, task.Index, from, to, body, nil, evaluator.ModeOf(first))
+}
+
+func waitForDependency(taskID string) (evaluator.Value, error) {
+	for {
+		prevTask, err := getTaskResult(taskID)
+		switch {
+		case err != nil:
+		case prevTask.Status == "failed":
+			return nil, fmt.Errorf("dependency %s failed", taskID)
+		case prevTask.Status == "skipped":
+			return nil, fmt.Errorf("dependency %s was skipped", taskID)
+		case prevTask.Value != nil:
+			value, err := evaluator.ParseValue(*prevTask.Value)
+			if err != nil {
+				return nil, fmt.Errorf("dependency %s has an invalid value: %v", taskID, err)
+			}
+			return value, nil
+		case prevTask.Result != nil:
+			return evaluator.Float(*prevTask.Result), nil
+		case prevTask.Status == "done":
+			return nil, fmt.Errorf("dependency %s finished without a result", taskID)
 		}
-		return getArgValue(task.Arg1) / b
-	default:
-		return 0
+		time.Sleep(100 * time.Millisecond)
 	}
 }
 
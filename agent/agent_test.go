@@ -1,8 +1,11 @@
 package agent
 
 import (
+	"calculator/evaluator"
 	"calculator/models"
+	"calculator/units"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -101,12 +104,55 @@ func TestCompute(t *testing.T) {
 			},
 			expected: 0,
 		},
+		{
+			name: "negation",
+			task: &models.Task{
+				Arg1:      "4",
+				Operation: "neg",
+			},
+			expected: -4,
+		},
+		{
+			name: "negative literal",
+			task: &models.Task{
+				Arg1:      "2",
+				Arg2:      "-3",
+				Operation: "*",
+			},
+			expected: -6,
+		},
+		{
+			name: "power",
+			task: &models.Task{
+				Arg1:      "2",
+				Arg2:      "3",
+				Operation: "^",
+			},
+			expected: 8,
+		},
+		{
+			name: "exponent notation",
+			task: &models.Task{
+				Arg1:      "6.02e+23",
+				Arg2:      "1e-23",
+				Operation: "*",
+			},
+			expected: 6.02,
+		},
+		{
+			name: "function",
+			task: &models.Task{
+				Arg1:      "16",
+				Operation: "sqrt",
+			},
+			expected: 4,
+		},
 		{
 			name: "unknown operation",
 			task: &models.Task{
 				Arg1:      "6",
 				Arg2:      "2",
-				Operation: "^",
+				Operation: "?",
 			},
 			expected: 0,
 		},
@@ -327,9 +373,8 @@ func TestComputeWithInvalidArg(t *testing.T) {
 		Operation: "+",
 	}
 
-	result := compute(task)
-	if result != 3.0 {
-		t.Errorf("Expected 3.0 (0 + 3), got %f", result)
+	if result, err := computeValue(task); err == nil {
+		t.Errorf("Expected error for invalid argument, got %v", result)
 	}
 }
 
@@ -548,6 +593,65 @@ func TestComputeWithDependencyError(t *testing.T) {
 	}
 }
 
+func TestComputeWithFailedDependency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message := "деление на ноль"
+		json.NewEncoder(w).Encode(&models.Task{ID: "dep-task", Status: "failed", Error: &message})
+	}))
+	defer server.Close()
+
+	originalURL := serverURL
+	serverURL = server.URL
+	defer func() { serverURL = originalURL }()
+
+	report := computeReport(&models.Task{Arg1: "$dep-task", Arg2: "3", Operation: "+"})
+	if report.Error == nil || report.Value != nil {
+		t.Errorf("Expected the failed dependency to fail the task, got %+v", report)
+	}
+}
+
+func TestComputeWithSkippedDependency(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+	}{
+		{"skipped after a failing sibling", "skipped"},
+		{"done without a value", "done"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				polls++
+				status := "waiting"
+				if polls > 1 {
+					status = tt.status
+				}
+				json.NewEncoder(w).Encode(&models.Task{ID: "dep-task", Status: status})
+			}))
+			defer server.Close()
+
+			originalURL := serverURL
+			serverURL = server.URL
+			defer func() { serverURL = originalURL }()
+
+			done := make(chan *models.TaskResult)
+			go func() {
+				done <- computeReport(&models.Task{Arg1: "$dep-task", Arg2: "3", Operation: "+"})
+			}()
+			select {
+			case report := <-done:
+				if report.Error == nil || report.Value != nil {
+					t.Errorf("Expected the %s dependency to fail the task, got %+v", tt.status, report)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Expected the %s dependency to stop waiting", tt.status)
+			}
+		})
+	}
+}
+
 func TestWorker_SubmitError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/internal/task" && r.Method == "GET" {
@@ -658,7 +762,14 @@ func TestComputeAllOperations(t *testing.T) {
 		{"5", "3", "*", 15},
 		{"6", "3", "/", 2},
 		{"5", "0", "/", 0},
-		{"5", "3", "%", 0},
+		{"5", "3", "%", 2},
+		{"-7", "2", "//", -4},
+		{"0xFF", "0x0F", "&", 15},
+		{"1", "4", "<<", 16},
+		{"5", "3", ">", 1},
+		{"2.5", "2.5", "!=", 0},
+		{"0", "", "not", 1},
+		{"5", "3", "?", 0},
 	}
 
 	for _, tt := range tests {
@@ -673,3 +784,407 @@ func TestComputeAllOperations(t *testing.T) {
 		}
 	}
 }
+
+func TestComputeReport_Overflow(t *testing.T) {
+	for _, op := range []string{"+", "-", "*", "/"} {
+		arg2 := "1e308"
+		if op == "-" {
+			arg2 = "-1e308"
+		} else if op == "/" {
+			arg2 = "1e-10"
+		}
+		report := computeReport(&models.Task{Arg1: "1e308", Arg2: arg2, Operation: op})
+		if report.Error == nil {
+			t.Errorf("Expected overflow error for 1e308 %s %s, got %+v", op, arg2, report)
+		}
+		if _, err := json.Marshal(report); err != nil {
+			t.Errorf("Expected report for 1e308 %s %s to be encodable, got %v", op, arg2, err)
+		}
+	}
+}
+
+func TestComputeValue_Exact(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		expected  string
+	}{
+		{"rat:1/10", "rat:1/5", "+", "rat:3/10"},
+		{"rat:1/3", "rat:1/6", "-", "rat:1/6"},
+		{"rat:2/3", "rat:3/4", "*", "rat:1/2"},
+		{"rat:1", "rat:3", "/", "rat:1/3"},
+		{"rat:2/3", "rat:-2", "^", "rat:9/4"},
+		{"rat:7/2", "", "neg", "rat:-7/2"},
+		{"rat:-5/2", "", "round", "rat:-3"},
+		{"rat:1", "rat:0", "/", "error"},
+		{"rat:2", "", "sqrt", "error"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.arg1, tt.operation, tt.arg2, result, tt.expected)
+		}
+	}
+}
+
+func TestComputeValue_Interval(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		expected  string
+	}{
+		{"3", "0.5", "±", "iv:2.5,3.5"},
+		{"1", "2", "interval", "iv:1,2"},
+		{"iv:1,2", "iv:3,4", "+", "iv:4,6"},
+		{"iv:-1,2", "2", "^", "iv:0,4"},
+		{"iv:0,2", "", "sin", "iv:0,1"},
+		{"iv:1,2", "iv:3,4", "<", "1"},
+		{"iv:1,3", "iv:2,4", "<", "error"},
+		{"1", "iv:-1,1", "/", "error"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.arg1, tt.operation, tt.arg2, result, tt.expected)
+		}
+	}
+}
+
+func TestComputeValue_Complex(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		expected  string
+	}{
+		{"3", "cx:0,4", "+", "cx:3,4"},
+		{"cx:3,4", "cx:1,-2", "*", "cx:11,-2"},
+		{"-4", "", "sqrt", "cx:0,2"},
+		{"cx:3,4", "", "abs", "5"},
+		{"1", "2", "complex", "cx:1,2"},
+		{"cx:0,1", "2", "^", "-1"},
+		{"cx:1,1", "2", "<", "error"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.arg1, tt.operation, tt.arg2, result, tt.expected)
+		}
+	}
+
+	report := valueReport(evaluator.Complex(complex(11, -2)))
+	if report.Result != 11 || report.Value == nil || *report.Value != "cx:11,-2" {
+		t.Errorf("Expected the real part and the complex value in the report, got %+v", report)
+	}
+}
+
+func TestComputeValue_Quantity(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		expected  string
+	}{
+		{"q:3,1,m,m", "q:2,1000,m,km", "+", "q:5,1,m,m"},
+		{"q:6,1,m/s,m/s", "q:0.44704,0.44704,m/s,mph", "to", "q:6,0.44704,m/s,mph"},
+		{"q:10,1,m,m", "q:2,1,s,s", "/", "q:5,1,m/s,m/s"},
+		{"q:10,1000,m,km", "q:5,1,m,m", "/", "2"},
+		{"q:3,1,m,m", "q:2,1,kg,kg", "+", "error"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.arg1, tt.operation, tt.arg2, result, tt.expected)
+		}
+	}
+
+	report := valueReport(evaluator.Quantity{SI: 6, Unit: units.Unit{Name: "mph", Scale: 0.44704, Dim: units.Dimension{1, 0, -1}}})
+	if math.Abs(report.Result-13.421617752326413) > 1e-9 || report.Value == nil || *report.Value != "q:6,0.44704,m/s,mph" {
+		t.Errorf("Expected the display value and the quantity in the report, got %+v", report)
+	}
+}
+
+func TestComputeValue_ExactDependency(t *testing.T) {
+	dependencyValue := "rat:1/3"
+	dependencyResult := 1.0 / 3
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&models.Task{ID: "dep-task", Result: &dependencyResult, Value: &dependencyValue})
+	}))
+	defer server.Close()
+
+	originalURL := serverURL
+	serverURL = server.URL
+	defer func() { serverURL = originalURL }()
+
+	task := &models.Task{Arg1: "$dep-task", Arg2: "rat:2/3", Operation: "+"}
+	if result := computeString(task); result != "rat:1" {
+		t.Errorf("Expected exact dependency to be used, got %s", result)
+	}
+}
+
+func TestSubmitTaskValue(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	originalURL := serverURL
+	serverURL = server.URL
+	defer func() { serverURL = originalURL }()
+
+	value, err := evaluator.ParseValue("rat:1/4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := submitTaskValue("test-task", value); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if payload["result"] != 0.25 || payload["value"] != "rat:1/4" {
+		t.Errorf("Expected approximate result and exact value, got %v", payload)
+	}
+
+	payload = nil
+	if err := submitTaskResult("test-task", 0.5); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := payload["value"]; ok || payload["result"] != 0.5 {
+		t.Errorf("Expected plain float payload, got %v", payload)
+	}
+}
+
+func TestComputeValue_Integer(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		expected  string
+	}{
+		{"9007199254740993", "1", "+", "9007199254740994"},
+		{"9223372036854775807", "1", "-", "9223372036854775806"},
+		{"0b1010", "0b0110", "xor", "12"},
+		{"12", "10", "|", "14"},
+		{"-16", "2", ">>", "-4"},
+		{"1", "63", "<<", "9223372036854775808"},
+		{"-7", "3", "%", "2"},
+		{"7", "2", "/", "3.5"},
+		{"2", "62", "^", "4611686018427387904"},
+		{"9223372036854775807", "1", "+", "9223372036854775808"},
+		{"2.5", "1", "&", "error"},
+		{"1", "64", "<<", "18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.arg1, tt.operation, tt.arg2, result, tt.expected)
+		}
+	}
+}
+
+func TestComputeWithSharedDependency(t *testing.T) {
+	callCount := 0
+	dependencyResult := 5.0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal/task/dep-task" {
+			callCount++
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(&models.Task{ID: "dep-task", Result: &dependencyResult})
+		}
+	}))
+	defer server.Close()
+
+	originalURL := serverURL
+	serverURL = server.URL
+	defer func() { serverURL = originalURL }()
+
+	task := &models.Task{
+		Arg1:      "$dep-task",
+		Arg2:      "$dep-task",
+		Operation: "*",
+	}
+
+	result := compute(task)
+	if result != 25.0 {
+		t.Errorf("Expected 25.0, got %f", result)
+	}
+	if callCount != 1 {
+		t.Errorf("Expected shared dependency to be fetched once, got %d calls", callCount)
+	}
+}
+
+func TestComputeValue_Chunk(t *testing.T) {
+	tests := []struct {
+		arg1      string
+		arg2      string
+		operation string
+		index     string
+		body      string
+		expected  string
+	}{
+		{"1", "100", "sum", "i", "i", "5050"},
+		{"1", "10000", "sum", "i", "i^2", "333383335000"},
+		{"1", "10", "prod", "k", "k", "3628800"},
+		{"5", "4", "sum", "i", "i", "0"},
+		{"5", "4", "prod", "i", "i", "1"},
+		{"1", "4", "sum", "i", "1/i", "2.083333333333333"},
+		{"rat:1", "rat:4", "sum", "i", "1/i", "rat:25/12"},
+		{"1", "3", "sum", "i", "sum(j, 1, i, j)", "10"},
+		{"1", "3", "sum", "i", "if(i % 2 == 0, -i, i)", "2"},
+		{"1", "3", "sum", "i", "i +", "error"},
+		{"1.5", "3", "sum", "i", "i", "error"},
+		{"1", "3", "sum", "i", "unknown", "error"},
+	}
+
+	for _, tt := range tests {
+		task := &models.Task{
+			Arg1:      tt.arg1,
+			Arg2:      tt.arg2,
+			Operation: tt.operation,
+			Index:     tt.index,
+			Body:      tt.body,
+		}
+		if result := computeString(task); result != tt.expected {
+			t.Errorf("computeValue(%s %s..%s: %s) = %s, expected %s", tt.operation, tt.arg1, tt.arg2, tt.body, result, tt.expected)
+		}
+	}
+}
+
+func TestComputeReport_Numeric(t *testing.T) {
+	tests := []struct {
+		task     *models.Task
+		expected float64
+	}{
+		{&models.Task{Arg1: "0", Arg2: "1", Operation: evaluator.Integrate, Index: "x", Body: "3*x^2"}, 1},
+		{&models.Task{Arg1: "0", Arg2: "2", Operation: evaluator.Solve, Index: "t", Body: "t^2 - 2"}, math.Sqrt2},
+	}
+
+	for _, tt := range tests {
+		report := computeReport(tt.task)
+		if math.Abs(report.Result-tt.expected) > 1e-9 {
+			t.Errorf("computeReport(%s) = %v, expected %v", tt.task.Body, report.Result, tt.expected)
+		}
+		if report.ErrorEstimate == nil || report.Iterations == 0 {
+			t.Errorf("Expected error estimate and iterations for %s, got %+v", tt.task.Body, report)
+		}
+		if result, err := computeValue(tt.task); err != nil || result.Float() != report.Result {
+			t.Errorf("Expected computeValue to match report for %s", tt.task.Body)
+		}
+	}
+
+	failed := computeReport(&models.Task{Arg1: "0", Arg2: "1", Operation: evaluator.Solve, Index: "x", Body: "x^2 + 1"})
+	if failed.Error == nil || failed.ErrorEstimate != nil {
+		t.Errorf("Expected an error for failed solve, got %+v", failed)
+	}
+}
+
+func TestSubmitTaskReport(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	originalURL := serverURL
+	serverURL = server.URL
+	defer func() { serverURL = originalURL }()
+
+	estimate := 1e-12
+	if err := submitTaskReport("test-task", &models.TaskResult{Result: 0.5, ErrorEstimate: &estimate, Iterations: 17}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if payload["result"] != 0.5 || payload["error_estimate"] != 1e-12 || payload["iterations"] != 17.0 {
+		t.Errorf("Expected result with error estimate and iterations, got %v", payload)
+	}
+}
+
+func TestComputeValue_NumberTheory(t *testing.T) {
+	tests := []struct {
+		task     *models.Task
+		expected string
+	}{
+		{&models.Task{Arg1: "25", Operation: "factorial"}, "15511210043330985984000000"},
+		{&models.Task{Arg1: "4", Arg2: "13", Arg3: "497", Operation: "modpow"}, "445"},
+		{&models.Task{Arg1: "1022117", Arg2: "902", Arg3: "1010", Operation: evaluator.TrialDivision}, "fac:1009"},
+		{&models.Task{Arg1: "fac:2^2", Arg2: "fac:3", Operation: "*"}, "fac:2^2*3"},
+		{&models.Task{Arg1: "1022117", Arg2: "fac:1009", Operation: evaluator.FactorCall}, "fac:1009*1013"},
+		{&models.Task{Arg1: "-1", Operation: "factorial"}, "error"},
+	}
+
+	for _, tt := range tests {
+		if result := computeString(tt.task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s %s) = %s, expected %s", tt.task.Operation, tt.task.Arg1, tt.task.Arg2, tt.task.Arg3, result, tt.expected)
+		}
+	}
+
+	report := valueReport(evaluator.Factors("2^3*3"))
+	if report.Result != 24 || report.Value == nil || *report.Value != "fac:2^3*3" {
+		t.Errorf("Expected the product and the factorization in the report, got %+v", report)
+	}
+}
+
+func TestComputeValue_Matrix(t *testing.T) {
+	tests := []struct {
+		task     *models.Task
+		expected string
+	}{
+		{&models.Task{Arg1: "mat:1x2:1,2", Arg2: "mat:2x2:1,2,3,4", Operation: evaluator.MatMul}, "mat:1x2:7,10"},
+		{&models.Task{Arg1: "mat:1x2:7,10", Arg2: "mat:2x2:1,0,0,1", Operation: evaluator.MatrixCall}, "mat:3x2:7,10,1,0,0,1"},
+		{&models.Task{Arg1: "vec:1,2", Arg2: "3", Operation: evaluator.VectorCall}, "vec:1,2,3"},
+		{&models.Task{Arg1: "vec:1,2,3", Arg2: "2", Operation: "*"}, "vec:2,4,6"},
+		{&models.Task{Arg1: "mat:2x2:1,2,3,4", Operation: "det"}, "-2"},
+		{&models.Task{Arg1: "mat:2x2:1,2,2,4", Operation: "inv"}, "error"},
+		{&models.Task{Arg1: "vec:1,2,3", Operation: evaluator.MomentsCall}, "mom:3,6,14"},
+		{&models.Task{Arg1: "mom:3,6,14", Arg2: "mom:1,4,16", Operation: "+"}, "mom:4,10,30"},
+		{&models.Task{Arg1: "mom:4,10,30", Operation: "variance"}, "1.25"},
+		{&models.Task{Arg1: "vec:3,1,2", Operation: "median"}, "2"},
+	}
+
+	for _, tt := range tests {
+		if result := computeString(tt.task); result != tt.expected {
+			t.Errorf("computeValue(%s %s %s) = %s, expected %s", tt.task.Operation, tt.task.Arg1, tt.task.Arg2, result, tt.expected)
+		}
+	}
+
+	report := valueReport(evaluator.NewVector([]float64{3, 4}))
+	if report.Result != 5 || report.Value == nil || *report.Value != "vec:3,4" {
+		t.Errorf("Expected the norm and the encoded vector in the report, got %+v", report)
+	}
+}
+
+func computeString(task *models.Task) string {
+	result, err := computeValue(task)
+	if err != nil {
+		return "error"
+	}
+	return result.String()
+}
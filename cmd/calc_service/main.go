@@ -4,6 +4,7 @@ import (
 	"calculator/handlers"
 	"calculator/middleware"
 	"calculator/services"
+	"calculator/units"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,6 +12,12 @@ import (
 )
 
 func main() {
+	if unitsFile := getEnv("UNITS_FILE", ""); unitsFile != "" {
+		if err := units.LoadFile(unitsFile); err != nil {
+			log.Fatalf("Units loading error: %v", err)
+		}
+	}
+
 	dbPath := getEnv("DB_PATH", "./calculator.db")
 	db, err := services.NewDatabaseService(dbPath)
 	if err != nil {
@@ -21,11 +28,17 @@ func main() {
 	jwtSecret := getEnv("JWT_SECRET", "your-secret-key-change-in-production")
 	authService := services.NewAuthService(db, jwtSecret)
 	expressionService := services.NewExpressionService(db)
+	variableService := services.NewVariableService(db)
+	functionService := services.NewFunctionService(db)
+	symbolicService := services.NewSymbolicService(db)
 
 	authHandler := handlers.NewAuthHandler(authService)
 	calculateHandler := handlers.NewCalculateHandler(expressionService)
 	expressionHandler := handlers.NewExpressionHandler(expressionService)
 	taskHandler := handlers.NewTaskHandler(expressionService)
+	variableHandler := handlers.NewVariableHandler(variableService)
+	functionHandler := handlers.NewFunctionHandler(functionService)
+	symbolicHandler := handlers.NewSymbolicHandler(symbolicService)
 
 	authMiddleware := middleware.AuthMiddleware(authService)
 
@@ -46,6 +59,22 @@ func main() {
 	http.Handle("/api/v1/calculate", authMiddleware(http.HandlerFunc(calculateHandler.Calculate)))
 	http.Handle("/api/v1/expressions", authMiddleware(http.HandlerFunc(expressionHandler.GetExpressions)))
 	http.Handle("/api/v1/expressions/", authMiddleware(http.HandlerFunc(expressionHandler.GetExpression)))
+	http.Handle("/api/v1/symbolic", authMiddleware(http.HandlerFunc(symbolicHandler.Transform)))
+	http.Handle("/api/v1/variables", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			variableHandler.GetVariables(w, r)
+		} else {
+			variableHandler.SetVariable(w, r)
+		}
+	})))
+	http.Handle("/api/v1/variables/csv", authMiddleware(http.HandlerFunc(variableHandler.ImportCSV)))
+	http.Handle("/api/v1/functions", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			functionHandler.GetFunctions(w, r)
+		} else {
+			functionHandler.DefineFunction(w, r)
+		}
+	})))
 
 	port := getEnv("PORT", "8080")
 	fmt.Printf("Server started on port %s\n", port)
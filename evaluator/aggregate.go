@@ -0,0 +1,66 @@
+package evaluator
+
+import "fmt"
+
+var aggregates = map[string]string{
+	"sum":  "+",
+	"avg":  "+",
+	"min":  "min",
+	"max":  "max",
+	"prod": "*",
+}
+
+func IsAggregate(name string) bool {
+	_, ok := aggregates[name]
+	return ok
+}
+
+func AggregateOperation(name string) (string, error) {
+	op, ok := aggregates[name]
+	if !ok {
+		return "", fmt.Errorf("неизвестная агрегатная функция %q", name)
+	}
+	return op, nil
+}
+
+func Reduce[T any](items []T, combine func(left, right T) (T, error)) (T, error) {
+	var zero T
+	switch len(items) {
+	case 0:
+		return zero, fmt.Errorf("нечего сворачивать")
+	case 1:
+		return items[0], nil
+	}
+
+	mid := len(items) / 2
+	left, err := Reduce(items[:mid], combine)
+	if err != nil {
+		return zero, err
+	}
+	right, err := Reduce(items[mid:], combine)
+	if err != nil {
+		return zero, err
+	}
+	return combine(left, right)
+}
+
+func Aggregate(name string, args []float64) (float64, error) {
+	op, err := AggregateOperation(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(args) == 0 {
+		return 0, fmt.Errorf("функция %s ожидает хотя бы один аргумент", name)
+	}
+
+	result, err := Reduce(args, func(left, right float64) (float64, error) {
+		return Apply(op, left, right)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if name == "avg" {
+		return Apply("/", result, float64(len(args)))
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReduce_Balanced(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	got, err := Reduce(items, func(left, right string) (string, error) {
+		return fmt.Sprintf("(%s%s)", left, right), nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "((ab)(c(de)))" {
+		t.Errorf("Expected ((ab)(c(de))), got %s", got)
+	}
+
+	if _, err := Reduce([]string{}, func(left, right string) (string, error) { return left + right, nil }); err == nil {
+		t.Error("Expected error for empty input")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []float64
+		expected float64
+	}{
+		{"sum", []float64{1, 2, 3, 4}, 10},
+		{"sum", []float64{7}, 7},
+		{"avg", []float64{1, 2, 3, 4}, 2.5},
+		{"min", []float64{3, -1, 2}, -1},
+		{"max", []float64{3, -1, 2}, 3},
+		{"prod", []float64{2, 3, 4}, 24},
+	}
+
+	for _, tt := range tests {
+		got, err := Aggregate(tt.name, tt.args)
+		if err != nil {
+			t.Errorf("Aggregate(%s, %v) unexpected error: %v", tt.name, tt.args, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Aggregate(%s, %v) = %v, expected %v", tt.name, tt.args, got, tt.expected)
+		}
+	}
+
+	if _, err := Aggregate("sum", nil); err == nil {
+		t.Error("Expected error for sum without arguments")
+	}
+	if _, err := Aggregate("median", []float64{1}); err == nil {
+		t.Error("Expected error for unknown aggregate")
+	}
+}
@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+type BigInt struct {
+	*big.Int
+}
+
+func (b BigInt) Float() float64 {
+	value, _ := new(big.Float).SetInt(b.Int).Float64()
+	if math.IsInf(value, 0) {
+		return math.Copysign(math.MaxFloat64, value)
+	}
+	return value
+}
+
+func (b BigInt) Overflows() bool {
+	value, _ := new(big.Float).SetInt(b.Int).Float64()
+	return math.IsInf(value, 0)
+}
+
+func (b BigInt) String() string {
+	return b.Int.String()
+}
+
+func IntValue(n *big.Int) Value {
+	if n.IsInt64() {
+		return Int(n.Int64())
+	}
+	return BigInt{n}
+}
+
+func parseBigInt(s string) (Value, bool) {
+	digits := strings.TrimLeft(s, "+-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, false
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, false
+	}
+	return IntValue(n), true
+}
+
+func hasBigInt(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(BigInt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toBigInt(op string, value Value) (*big.Int, error) {
+	switch v := value.(type) {
+	case BigInt:
+		return v.Int, nil
+	case Int:
+		return big.NewInt(int64(v)), nil
+	case Factors:
+		return v.product(), nil
+	case Rat:
+		if v.IsInt() {
+			return v.Num(), nil
+		}
+		return nil, fmt.Errorf("операция %s определена только для целых чисел, получено %s", op, v.RatString())
+	case Float:
+		f := float64(v)
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			n, _ := big.NewFloat(f).Int(nil)
+			return n, nil
+		}
+		return nil, fmt.Errorf("операция %s определена только для целых чисел, получено %v", op, f)
+	}
+	return nil, fmt.Errorf("операция %s определена только для целых чисел", op)
+}
+
+func ApplyBig(op string, args ...*big.Int) (Value, error) {
+	arity, ok := operationArity[op]
+	if !ok {
+		if !exactFunction(op) {
+			return nil, fmt.Errorf("неизвестная операция %q", op)
+		}
+		arity = 1
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	a, result := args[0], new(big.Int)
+	switch op {
+	case "neg":
+		return IntValue(result.Neg(a)), nil
+	case "abs":
+		return IntValue(result.Abs(a)), nil
+	case "floor", "ceil", "round":
+		return IntValue(a), nil
+	case "not":
+		return Int(boolInt(a.Sign() == 0)), nil
+	}
+
+	b := args[1]
+	if IsComparison(op) {
+		return Int(compare(op, a.Cmp(b))), nil
+	}
+	switch op {
+	case "+":
+		return IntValue(result.Add(a, b)), nil
+	case "-":
+		return IntValue(result.Sub(a, b)), nil
+	case "*":
+		return IntValue(result.Mul(a, b)), nil
+	case "/":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		quotient := new(big.Rat).SetFrac(a, b)
+		if quotient.IsInt() {
+			return IntValue(quotient.Num()), nil
+		}
+		return Float(Rat{quotient}.Float()), nil
+	case "//", "%":
+		if b.Sign() == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		remainder := new(big.Int)
+		result.QuoRem(a, b, remainder)
+		if remainder.Sign() != 0 && (remainder.Sign() < 0) != (b.Sign() < 0) {
+			result.Sub(result, big.NewInt(1))
+			remainder.Add(remainder, b)
+		}
+		if op == "%" {
+			return IntValue(remainder), nil
+		}
+		return IntValue(result), nil
+	case "^":
+		if b.Sign() >= 0 && b.IsInt64() && int64(a.BitLen())*b.Int64() <= maxExactPowerBits {
+			return IntValue(result.Exp(a, b, nil)), nil
+		}
+		power, err := Apply("^", BigInt{a}.Float(), BigInt{b}.Float())
+		if err != nil {
+			return nil, err
+		}
+		return Float(power), nil
+	case "min":
+		if a.Cmp(b) <= 0 {
+			return IntValue(a), nil
+		}
+		return IntValue(b), nil
+	case "max":
+		if a.Cmp(b) >= 0 {
+			return IntValue(a), nil
+		}
+		return IntValue(b), nil
+	case "&", "|", "xor", "<<", ">>":
+		bits, err := bitwiseRat(op, new(big.Rat).SetInt(a), new(big.Rat).SetInt(b))
+		if err != nil {
+			return nil, err
+		}
+		return IntValue(bits.Num()), nil
+	}
+	return nil, fmt.Errorf("неизвестная операция %q", op)
+}
@@ -0,0 +1,23 @@
+package evaluator
+
+import "fmt"
+
+type Budget struct {
+	limit int64
+	spent int64
+}
+
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+func (b *Budget) spend() error {
+	if b == nil {
+		return nil
+	}
+	b.spent++
+	if b.spent > b.limit {
+		return fmt.Errorf("слишком много вычислений при создании выражения: границы диапазонов и присваивания, от которых они зависят, требуют больше %d шагов", b.limit)
+	}
+	return nil
+}
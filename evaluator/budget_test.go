@@ -0,0 +1,38 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"testing"
+)
+
+func TestEvaluateWithin(t *testing.T) {
+	tests := []struct {
+		expr     string
+		limit    int64
+		expected string
+	}{
+		{"1 + 2", 3, "3"},
+		{"1 + 2", 2, ""},
+		{"sum(i, 1, 10, i)", 13, "55"},
+		{"sum(i, 1, 10, i)", 12, ""},
+		{"sum(i, 1, sum(j, 1, 1000000, 1), i)", 100000, ""},
+		{"integrate(sin(x)*exp(x), 0, 50)", 1000, ""},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		result, err := EvaluateWithin(node, nil, "", NewBudget(tt.limit))
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("Expected %s to exceed %d steps, got %s", tt.expr, tt.limit, result)
+			}
+			continue
+		}
+		if err != nil || result.String() != tt.expected {
+			t.Errorf("EvaluateWithin(%s) = %v, %v; expected %s", tt.expr, result, err, tt.expected)
+		}
+	}
+}
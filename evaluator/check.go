@@ -0,0 +1,206 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"calculator/units"
+	"fmt"
+)
+
+type Type struct {
+	Unit  units.Unit
+	Known bool
+}
+
+var (
+	Scalar  = Type{Unit: units.Unit{Scale: 1}, Known: true}
+	Unknown = Type{}
+)
+
+func unitType(unit units.Unit) Type {
+	if unit.Dimensionless() {
+		return Scalar
+	}
+	return Type{Unit: unit, Known: true}
+}
+
+func (t Type) quantity() bool {
+	return t.Known && !t.Unit.Dimensionless()
+}
+
+func Check(node parser.Node, scope map[string]Type, mode string) (Type, error) {
+	switch n := node.(type) {
+	case *parser.Number:
+		return Scalar, nil
+	case *parser.Ident:
+		if t, ok := scope[n.Name]; ok {
+			return t, nil
+		}
+		value, err := ResolveIdent(n.Name, nil, mode)
+		if err != nil {
+			return Unknown, err
+		}
+		if _, ok := value.(Float); ok {
+			return Scalar, nil
+		}
+		return Unknown, nil
+	case *parser.UnaryOp:
+		operand, err := Check(n.Operand, scope, mode)
+		if err != nil || n.Op != "!" {
+			return operand, err
+		}
+		return Scalar, nil
+	case *parser.BinaryOp:
+		left, err := Check(n.Left, scope, mode)
+		if err != nil {
+			return Unknown, err
+		}
+		right, err := Check(n.Right, scope, mode)
+		if err != nil {
+			return Unknown, err
+		}
+		if IsLogical(n.Op) {
+			return Scalar, nil
+		}
+		return checkOperation(n.Op, left, right, n.Right)
+	case *parser.Call:
+		return checkCall(n, scope, mode)
+	}
+	return Unknown, nil
+}
+
+func checkOperation(op string, left, right Type, exponent parser.Node) (Type, error) {
+	if !left.Known || !right.Known {
+		if op == "^" && right.quantity() {
+			return Unknown, units.NotDimensionless(op, right.Unit)
+		}
+		return Unknown, nil
+	}
+	switch op {
+	case PlusMinus:
+		return Unknown, nil
+	case "*":
+		return unitType(left.Unit.Mul(right.Unit)), nil
+	case "/":
+		return unitType(left.Unit.Div(right.Unit)), nil
+	case "^":
+		if right.quantity() {
+			return Unknown, units.NotDimensionless(op, right.Unit)
+		}
+		if !left.quantity() {
+			return Scalar, nil
+		}
+		if p, ok := constantExponent(exponent); ok {
+			unit, err := left.Unit.Pow(p)
+			if err != nil {
+				return Unknown, err
+			}
+			return unitType(unit), nil
+		}
+		return Unknown, nil
+	case "+", "-", "min", "max":
+		if left.Unit.Dim != right.Unit.Dim {
+			return Unknown, units.Mismatch(op, left.Unit, right.Unit)
+		}
+		if left.Unit.Dimensionless() {
+			return right, nil
+		}
+		return left, nil
+	}
+	if IsComparison(op) {
+		if left.Unit.Dim != right.Unit.Dim {
+			return Unknown, units.Mismatch(op, left.Unit, right.Unit)
+		}
+		return Scalar, nil
+	}
+	if left.quantity() || right.quantity() {
+		return Unknown, nil
+	}
+	return Scalar, nil
+}
+
+func constantExponent(node parser.Node) (float64, bool) {
+	switch n := node.(type) {
+	case *parser.Number:
+		return n.Value, true
+	case *parser.UnaryOp:
+		if p, ok := constantExponent(n.Operand); ok && n.Op == "-" {
+			return -p, true
+		}
+	}
+	return 0, false
+}
+
+func checkCall(n *parser.Call, scope map[string]Type, mode string) (Type, error) {
+	if (n.Name == UnitCall || n.Name == ConvertCall) && len(n.Args) == 2 {
+		return checkQuantity(n, scope, mode)
+	}
+	if _, ok := RangeIndex(n); ok || IsNumeric(n.Name) {
+		return Unknown, nil
+	}
+	if mode == ModeExact && IsFunction(n.Name) && !exactFunction(n.Name) {
+		return Unknown, fmt.Errorf("функция %s недоступна в точном режиме", n.Name)
+	}
+
+	args := make([]Type, len(n.Args))
+	for i, arg := range n.Args {
+		t, err := Check(arg, scope, mode)
+		if err != nil {
+			return Unknown, err
+		}
+		args[i] = t
+	}
+
+	switch {
+	case n.Name == Conditional:
+		if len(args) == 3 && args[1] == args[2] {
+			return args[1], nil
+		}
+	case IsFunction(n.Name) && len(args) == 1 && args[0].Known:
+		switch n.Name {
+		case "abs", "floor", "ceil", "round":
+			return args[0], nil
+		case "sqrt":
+			if !args[0].quantity() {
+				return Scalar, nil
+			}
+			unit, err := args[0].Unit.Pow(0.5)
+			if err != nil {
+				return Unknown, err
+			}
+			return unitType(unit), nil
+		}
+		if args[0].quantity() {
+			return Unknown, units.NotDimensionless(n.Name, args[0].Unit)
+		}
+		return Scalar, nil
+	}
+	return Unknown, nil
+}
+
+func checkQuantity(n *parser.Call, scope map[string]Type, mode string) (Type, error) {
+	unit, ok := n.Args[1].(*parser.Unit)
+	if !ok {
+		return Unknown, fmt.Errorf("функция %s ожидает единицу измерения вторым аргументом", n.Name)
+	}
+	value, err := Check(n.Args[0], scope, mode)
+	if err != nil {
+		return Unknown, err
+	}
+	target, err := units.Parse(unit.Name)
+	if err != nil {
+		return Unknown, err
+	}
+	if mode == ModeExact {
+		return Unknown, fmt.Errorf("единицы измерения недоступны в точном режиме")
+	}
+	if !value.Known {
+		return Unknown, nil
+	}
+	if n.Name == UnitCall {
+		return unitType(value.Unit.Mul(target)), nil
+	}
+	if value.Unit.Dim != target.Dim {
+		return Unknown, units.Mismatch(n.Name, value.Unit, target)
+	}
+	return unitType(target), nil
+}
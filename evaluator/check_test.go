@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"calculator/units"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		expr      string
+		mode      string
+		dimension bool
+		valid     bool
+	}{
+		{"3 m + 2 km", "", false, true},
+		{"(6 m) / (2 s) to km/h", "", false, true},
+		{"(3 m)^2 + 1 m^2", "", false, true},
+		{"sqrt(4 m^2) + 1 m", "", false, true},
+		{"x * 2 m - 1 m", "", false, true},
+		{"if(x > 0, 1 m, 2 m) + 3 m", "", false, true},
+		{"sum(i, 1, 100000000, i) + 1 m", "", false, true},
+		{"3 m + 2 kg", "", true, false},
+		{"3 m > 2", "", true, false},
+		{"2 ^ (1 m)", "", true, false},
+		{"sin(1 m)", "", true, false},
+		{"(3 m)^2 to km", "", true, false},
+		{"y = 2 s; y + 1 m", "", true, false},
+		{"sqrt(2)", ModeExact, false, false},
+		{"abs(-2) + 1/3", ModeExact, false, true},
+		{"2 m", ModeExact, false, false},
+		{"pi", ModeExact, false, false},
+		{"unknown + 1", "", false, false},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.ParseProgram(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		scope := map[string]Type{"x": Scalar}
+		for _, stmt := range program.Statements {
+			var checked Type
+			if checked, err = Check(stmt.Value, scope, tt.mode); err != nil {
+				break
+			}
+			if stmt.IsAssignment() {
+				scope[stmt.Name] = checked
+			}
+		}
+		if tt.valid && err != nil {
+			t.Errorf("Check(%s) failed: %v", tt.expr, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Expected error for %s", tt.expr)
+		}
+		var dimensionErr *units.DimensionError
+		if tt.dimension && !errors.As(err, &dimensionErr) {
+			t.Errorf("Expected dimension error for %s, got %v", tt.expr, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+const (
+	ImaginaryUnit = "i"
+	ComplexCall   = parser.ComplexCall
+)
+
+const complexPrefix = "cx:"
+
+type Complex complex128
+
+func (c Complex) Float() float64 {
+	return real(c)
+}
+
+func (c Complex) String() string {
+	return complexPrefix + FormatNumber(real(c)) + "," + FormatNumber(imag(c))
+}
+
+func (c Complex) Imag() float64 {
+	return imag(c)
+}
+
+var complexFunctions = map[string]func(complex128) complex128{
+	"sqrt":  cmplx.Sqrt,
+	"abs":   func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) },
+	"sin":   cmplx.Sin,
+	"cos":   cmplx.Cos,
+	"tan":   cmplx.Tan,
+	"ln":    cmplx.Log,
+	"log10": cmplx.Log10,
+	"exp":   cmplx.Exp,
+	"re":    func(z complex128) complex128 { return complex(real(z), 0) },
+	"im":    func(z complex128) complex128 { return complex(imag(z), 0) },
+	"conj":  cmplx.Conj,
+}
+
+func parseComplex(s string) (Value, error) {
+	parts := strings.Split(strings.TrimPrefix(s, complexPrefix), ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("некорректное комплексное число %q", s)
+	}
+	re, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное комплексное число %q", s)
+	}
+	im, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное комплексное число %q", s)
+	}
+	return Complex(complex(re, im)), nil
+}
+
+func hasComplex(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Complex); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func promotesToComplex(op string, args []float64) bool {
+	switch op {
+	case "sqrt", "ln", "log10":
+		return len(args) == 1 && args[0] < 0
+	case "^":
+		return len(args) == 2 && args[0] < 0 && args[1] != math.Trunc(args[1]) && !math.IsInf(args[1], 0)
+	}
+	return false
+}
+
+func ApplyComplex(op string, args ...Value) (Value, error) {
+	zs := make([]complex128, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case Complex:
+			zs[i] = complex128(v)
+		case Rat:
+			return nil, fmt.Errorf("комплексные числа недоступны в точном режиме")
+		case Interval:
+			return nil, fmt.Errorf("интервалы не поддерживают комплексные числа")
+		default:
+			zs[i] = complex(arg.Float(), 0)
+		}
+	}
+
+	if op == ComplexCall {
+		if len(zs) != 2 {
+			return nil, fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", op, len(zs))
+		}
+		if imag(zs[0]) != 0 || imag(zs[1]) != 0 {
+			return nil, fmt.Errorf("части комплексного числа должны быть действительными")
+		}
+		return complexValue(op, complex(real(zs[0]), real(zs[1])))
+	}
+
+	if fn, ok := complexFunctions[op]; ok {
+		if len(zs) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", op, len(zs))
+		}
+		if op == "ln" || op == "log10" {
+			if zs[0] == 0 {
+				return nil, fmt.Errorf("функция %s не определена для аргумента 0", op)
+			}
+		}
+		return complexValue(op, fn(zs[0]))
+	}
+	if _, ok := functions[op]; ok {
+		return nil, fmt.Errorf("функция %s не определена для комплексных чисел", op)
+	}
+	if arity, ok := operationArity[op]; !ok {
+		return nil, fmt.Errorf("неизвестная операция %q", op)
+	} else if len(zs) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(zs))
+	}
+
+	switch op {
+	case "+":
+		return complexValue(op, zs[0]+zs[1])
+	case "-":
+		return complexValue(op, zs[0]-zs[1])
+	case "*":
+		return complexValue(op, zs[0]*zs[1])
+	case "/":
+		if zs[1] == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		return complexValue(op, zs[0]/zs[1])
+	case "^":
+		if zs[0] == 0 && (real(zs[1]) < 0 || imag(zs[1]) != 0) {
+			return nil, fmt.Errorf("возведение 0 в степень %s не определено", formatComplex(zs[1]))
+		}
+		return complexValue(op, complexPower(zs[0], zs[1]))
+	case "neg":
+		return complexValue(op, -zs[0])
+	case "not":
+		return Int(boolInt(zs[0] == 0)), nil
+	case "==":
+		return Int(boolInt(zs[0] == zs[1])), nil
+	case "!=":
+		return Int(boolInt(zs[0] != zs[1])), nil
+	}
+	return nil, fmt.Errorf("операция %q не определена для комплексных чисел", op)
+}
+
+func complexPower(z, p complex128) complex128 {
+	n := real(p)
+	switch {
+	case imag(p) != 0:
+		return cmplx.Pow(z, p)
+	case n == 0.5:
+		return cmplx.Sqrt(z)
+	case n != math.Trunc(n) || math.Abs(n) > 1<<32:
+		return cmplx.Pow(z, p)
+	}
+	result, base, k := complex128(1), z, int64(math.Abs(n))
+	for ; k > 0; k >>= 1 {
+		if k&1 == 1 {
+			result *= base
+		}
+		base *= base
+	}
+	if n < 0 {
+		return 1 / result
+	}
+	return result
+}
+
+func complexValue(op string, z complex128) (Value, error) {
+	if cmplx.IsNaN(z) || cmplx.IsInf(z) {
+		return nil, fmt.Errorf("операция %q над комплексными числами не определена", op)
+	}
+	if imag(z) == 0 {
+		return Float(real(z)), nil
+	}
+	return Complex(z), nil
+}
+
+func formatComplex(z complex128) string {
+	re, im := real(z), imag(z)
+	if im == 0 {
+		return FormatNumber(re)
+	}
+	imaginary := FormatNumber(math.Abs(im)) + ImaginaryUnit
+	if math.Abs(im) == 1 {
+		imaginary = ImaginaryUnit
+	}
+	if re == 0 {
+		if im < 0 {
+			return "-" + imaginary
+		}
+		return imaginary
+	}
+	if im < 0 {
+		return FormatNumber(re) + " - " + imaginary
+	}
+	return FormatNumber(re) + " + " + imaginary
+}
@@ -0,0 +1,106 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"math"
+	"testing"
+)
+
+func TestEvaluate_Complex(t *testing.T) {
+	tests := []struct {
+		expr string
+		re   float64
+		im   float64
+	}{
+		{"(3+4i)*(1-2i)", 11, -2},
+		{"sqrt(-4)", 0, 2},
+		{"x^0.5", 0, 3},
+		{"i^2", -1, 0},
+		{"(2i)^-2", -0.25, 0},
+		{"2 + i", 2, 1},
+		{"ln(-1)", 0, math.Pi},
+		{"(1+2i)/(3-4i)", -0.2, 0.4},
+		{"abs(3+4i)", 5, 0},
+		{"re(3+4i) + im(3+4i)", 7, 0},
+		{"conj(1+2i)", 1, -2},
+		{"complex(1, 2)", 1, 2},
+		{"sum(k, 1, 3, k*i)", 0, 6},
+		{"i = 2; i*3", 6, 0},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.ParseProgram(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scope := map[string]Value{"x": Float(-9)}
+		var result Value
+		for _, stmt := range program.Statements {
+			if result, err = Evaluate(stmt.Value, scope, ""); err != nil {
+				t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+			}
+			if stmt.IsAssignment() {
+				scope[stmt.Name] = result
+			}
+		}
+		var im float64
+		if c, ok := result.(Complex); ok {
+			im = c.Imag()
+		}
+		if math.Abs(result.Float()-tt.re) > 1e-12 || math.Abs(im-tt.im) > 1e-12 {
+			t.Errorf("Evaluate(%s) = %v, expected %v%+vi", tt.expr, result, tt.re, tt.im)
+		}
+	}
+}
+
+func TestEvaluate_ComplexErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		mode string
+	}{
+		{"(1+i) < 2", ""},
+		{"floor(1+i)", ""},
+		{"(1+i) % 2", ""},
+		{"1/(0i)", ""},
+		{"0^(-1+i)", ""},
+		{"[1, 2]*i", ""},
+		{"integrate(x*i, 0, 1)", ""},
+		{"sum(k, 1, i, k)", ""},
+		{"2i", ModeExact},
+		{"i", ModeExact},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result, err := Evaluate(node, nil, tt.mode); err == nil {
+			t.Errorf("Expected error for %s, got %v", tt.expr, result)
+		}
+	}
+}
+
+func TestComplex(t *testing.T) {
+	c := Complex(complex(1.5, -2))
+	parsed, err := ParseValue(c.String())
+	if err != nil {
+		t.Fatalf("ParseValue(%s) failed: %v", c, err)
+	}
+	if parsed != c || c.String() != "cx:1.5,-2" {
+		t.Errorf("Expected %v, got %v", c, parsed)
+	}
+
+	for _, input := range []string{"cx:", "cx:1", "cx:a,1"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+
+	if !Truthy(Complex(1i)) || Truthy(Complex(0)) {
+		t.Error("Expected complex truthiness to compare with zero")
+	}
+	if result, err := ApplyValue("*", Complex(1i), Complex(-1i)); err != nil || result != Float(1) {
+		t.Errorf("Expected real product to become a float, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,20 @@
+package evaluator
+
+import "math"
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+func Constant(name string) (float64, bool) {
+	value, ok := constants[name]
+	return value, ok
+}
+
+func IsReserved(name string) bool {
+	_, isConstant := constants[name]
+	return isConstant || IsFunction(name) || IsAggregate(name) || IsNumeric(name) || IsNumberTheory(name) || IsMatrixOperation(name) || IsStatistic(name) || name == Conditional || name == IntervalCall || name == ComplexCall || name == UnitCall || name == ConvertCall
+}
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestConstant(t *testing.T) {
+	tests := map[string]float64{
+		"pi":  math.Pi,
+		"e":   math.E,
+		"tau": 2 * math.Pi,
+		"phi": math.Phi,
+	}
+	for name, expected := range tests {
+		got, ok := Constant(name)
+		if !ok || got != expected {
+			t.Errorf("Constant(%q) = %v, %v, expected %v", name, got, ok, expected)
+		}
+	}
+
+	if _, ok := Constant("x"); ok {
+		t.Error("Expected x not to be a constant")
+	}
+}
+
+func TestFormatNumber_RoundTrip(t *testing.T) {
+	values := []float64{0, -2, 0.1, 1.0 / 3, 6.02e23, 1.5e-300, math.Pi, -math.MaxFloat64}
+	for _, value := range values {
+		formatted := FormatNumber(value)
+		parsed, err := strconv.ParseFloat(formatted, 64)
+		if err != nil || parsed != value {
+			t.Errorf("FormatNumber(%v) = %q does not round-trip (got %v, %v)", value, formatted, parsed, err)
+		}
+	}
+
+	if got := FormatNumber(6.02e23); got != "6.02e+23" {
+		t.Errorf("Expected 6.02e+23, got %s", got)
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	for _, name := range []string{"pi", "e", "sqrt", "sum", "max"} {
+		if !IsReserved(name) {
+			t.Errorf("Expected %q to be reserved", name)
+		}
+	}
+	for _, name := range []string{"x", "rate", "pi2"} {
+		if IsReserved(name) {
+			t.Errorf("Expected %q not to be reserved", name)
+		}
+	}
+}
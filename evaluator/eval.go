@@ -0,0 +1,136 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+)
+
+func Evaluate(node parser.Node, scope map[string]Value, mode string) (Value, error) {
+	return evaluate(node, scope, mode, nil)
+}
+
+func EvaluateWithin(node parser.Node, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	return evaluate(node, scope, mode, budget)
+}
+
+func evaluate(node parser.Node, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	if err := budget.spend(); err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case *parser.Number:
+		return Literal(n.Literal, mode)
+	case *parser.Ident:
+		return ResolveIdent(n.Name, scope, mode)
+	case *parser.UnaryOp:
+		operand, err := evaluate(n.Operand, scope, mode, budget)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "+" {
+			return operand, nil
+		}
+		return ApplyValue(UnaryOperation(n.Op), operand)
+	case *parser.BinaryOp:
+		if IsLogical(n.Op) {
+			return evaluateLogical(n, scope, mode, budget)
+		}
+		left, err := evaluate(n.Left, scope, mode, budget)
+		if err != nil {
+			return nil, err
+		}
+		right, err := evaluate(n.Right, scope, mode, budget)
+		if err != nil {
+			return nil, err
+		}
+		return ApplyValue(n.Op, left, right)
+	case *parser.Call:
+		if n.Name == Conditional {
+			return evaluateConditional(n, scope, mode, budget)
+		}
+		if _, ok := RangeIndex(n); ok {
+			return evaluateRange(n, scope, mode, budget)
+		}
+		if IsNumeric(n.Name) {
+			return evaluateNumeric(n, scope, mode, budget)
+		}
+		if (n.Name == UnitCall || n.Name == ConvertCall) && len(n.Args) == 2 {
+			return evaluateQuantity(n, scope, mode, budget)
+		}
+		if !IsFunction(n.Name) && !IsAggregate(n.Name) && !IsNumberTheory(n.Name) && !IsMatrixOperation(n.Name) && !IsStatistic(n.Name) && n.Name != IntervalCall && n.Name != ComplexCall {
+			return nil, fmt.Errorf("неизвестная функция %q", n.Name)
+		}
+		args := make([]Value, len(n.Args))
+		for i, arg := range n.Args {
+			value, err := evaluate(arg, scope, mode, budget)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		if IsAggregate(n.Name) {
+			return AggregateValue(n.Name, args)
+		}
+		return ApplyValue(n.Name, args...)
+	}
+	return nil, fmt.Errorf("неизвестный узел выражения %T", node)
+}
+
+func evaluateLogical(n *parser.BinaryOp, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	left, err := evaluate(n.Left, scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	if Truthy(left) == (n.Op == "||") {
+		return Boolean(n.Op == "||", mode), nil
+	}
+	right, err := evaluate(n.Right, scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	return Boolean(Truthy(right), mode), nil
+}
+
+func evaluateConditional(n *parser.Call, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	if len(n.Args) != 3 {
+		return nil, fmt.Errorf("функция if ожидает 3 аргумента, получено %d", len(n.Args))
+	}
+	cond, err := evaluate(n.Args[0], scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	if Truthy(cond) {
+		return evaluate(n.Args[1], scope, mode, budget)
+	}
+	return evaluate(n.Args[2], scope, mode, budget)
+}
+
+func UnaryOperation(op string) string {
+	if op == "!" {
+		return "not"
+	}
+	return "neg"
+}
+
+func IsLogical(op string) bool {
+	return op == "&&" || op == "||"
+}
+
+func ResolveIdent(name string, scope map[string]Value, mode string) (Value, error) {
+	if value, ok := scope[name]; ok {
+		return value, nil
+	}
+	if value, ok := Constant(name); ok {
+		if mode == ModeExact {
+			return nil, fmt.Errorf("константа %s недоступна в точном режиме", name)
+		}
+		return Float(value), nil
+	}
+	if name == ImaginaryUnit {
+		if mode == ModeExact {
+			return nil, fmt.Errorf("комплексные числа недоступны в точном режиме")
+		}
+		return Complex(1i), nil
+	}
+	return nil, fmt.Errorf("неизвестная переменная %q", name)
+}
@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	maxExactPowerBits = 1 << 22
+	decimalDigits     = 30
+)
+
+func ApplyRat(op string, args ...*big.Rat) (*big.Rat, error) {
+	arity, ok := operationArity[op]
+	if !ok {
+		arity = 1
+		if !exactFunction(op) {
+			if IsFunction(op) {
+				return nil, fmt.Errorf("функция %s недоступна в точном режиме", op)
+			}
+			return nil, fmt.Errorf("неизвестная операция %q", op)
+		}
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	result := new(big.Rat)
+	if IsComparison(op) {
+		return result.SetInt64(compare(op, args[0].Cmp(args[1]))), nil
+	}
+	switch op {
+	case "+":
+		return result.Add(args[0], args[1]), nil
+	case "-":
+		return result.Sub(args[0], args[1]), nil
+	case "*":
+		return result.Mul(args[0], args[1]), nil
+	case "/":
+		if args[1].Sign() == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		return result.Quo(args[0], args[1]), nil
+	case "^":
+		return powRat(args[0], args[1])
+	case "//", "%":
+		if args[1].Sign() == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		quotient := floorRat(new(big.Rat).Quo(args[0], args[1]))
+		if op == "//" {
+			return result.SetInt(quotient), nil
+		}
+		return result.Sub(args[0], new(big.Rat).Mul(args[1], new(big.Rat).SetInt(quotient))), nil
+	case "&", "|", "xor", "<<", ">>":
+		return bitwiseRat(op, args[0], args[1])
+	case "min":
+		if args[0].Cmp(args[1]) <= 0 {
+			return result.Set(args[0]), nil
+		}
+		return result.Set(args[1]), nil
+	case "max":
+		if args[0].Cmp(args[1]) >= 0 {
+			return result.Set(args[0]), nil
+		}
+		return result.Set(args[1]), nil
+	case "neg":
+		return result.Neg(args[0]), nil
+	case "not":
+		return result.SetInt64(boolInt(args[0].Sign() == 0)), nil
+	case "abs":
+		return result.Abs(args[0]), nil
+	case "floor":
+		return result.SetInt(floorRat(args[0])), nil
+	case "ceil":
+		ceiling := floorRat(new(big.Rat).Neg(args[0]))
+		return result.SetInt(ceiling.Neg(ceiling)), nil
+	case "round":
+		half := new(big.Rat).Add(new(big.Rat).Abs(args[0]), big.NewRat(1, 2))
+		rounded := floorRat(half)
+		if args[0].Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+		return result.SetInt(rounded), nil
+	}
+	return nil, fmt.Errorf("неизвестная операция %q", op)
+}
+
+func DecimalString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	decimal := strings.TrimRight(r.FloatString(decimalDigits), "0")
+	decimal = strings.TrimSuffix(decimal, ".")
+	if decimal == "-0" {
+		return "0"
+	}
+	return decimal
+}
+
+func bitwiseRat(op string, left, right *big.Rat) (*big.Rat, error) {
+	if !left.IsInt() || !right.IsInt() {
+		return nil, fmt.Errorf("операция %s определена только для целых чисел", op)
+	}
+	a, b := left.Num(), right.Num()
+	result := new(big.Int)
+	switch op {
+	case "&":
+		result.And(a, b)
+	case "|":
+		result.Or(a, b)
+	case "xor":
+		result.Xor(a, b)
+	default:
+		if b.Sign() < 0 || !b.IsInt64() || (op == "<<" && int64(a.BitLen())+b.Int64() > maxExactPowerBits) {
+			return nil, fmt.Errorf("недопустимая величина сдвига %s", b.String())
+		}
+		if op == "<<" {
+			result.Lsh(a, uint(b.Int64()))
+		} else {
+			result.Rsh(a, uint(b.Int64()))
+		}
+	}
+	return new(big.Rat).SetInt(result), nil
+}
+
+func exactFunction(name string) bool {
+	switch name {
+	case "abs", "floor", "ceil", "round":
+		return true
+	}
+	return false
+}
+
+func floorRat(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+func powRat(base, exponent *big.Rat) (*big.Rat, error) {
+	if !exponent.IsInt() {
+		return nil, fmt.Errorf("в точном режиме показатель степени должен быть целым, получено %s", exponent.RatString())
+	}
+	n := exponent.Num()
+	if !n.IsInt64() || int64(base.Num().BitLen()+base.Denom().BitLen())*absInt64(n.Int64()) > maxExactPowerBits {
+		return nil, fmt.Errorf("результат возведения в степень %s слишком велик для точного режима", n.String())
+	}
+
+	k := new(big.Int).Abs(n)
+	num := new(big.Int).Exp(base.Num(), k, nil)
+	den := new(big.Int).Exp(base.Denom(), k, nil)
+	if n.Sign() < 0 {
+		if num.Sign() == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
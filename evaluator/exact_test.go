@@ -0,0 +1,116 @@
+package evaluator
+
+import (
+	"math/big"
+	"testing"
+)
+
+func rat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("invalid rational " + s)
+	}
+	return r
+}
+
+func TestApplyRat(t *testing.T) {
+	tests := []struct {
+		op       string
+		args     []string
+		expected string
+	}{
+		{"+", []string{"1/10", "2/10"}, "3/10"},
+		{"-", []string{"1/2", "1/3"}, "1/6"},
+		{"*", []string{"2/3", "3/4"}, "1/2"},
+		{"/", []string{"1", "3"}, "1/3"},
+		{"^", []string{"2/3", "3"}, "8/27"},
+		{"^", []string{"2", "-2"}, "1/4"},
+		{"^", []string{"5", "0"}, "1"},
+		{"min", []string{"1/3", "1/4"}, "1/4"},
+		{"max", []string{"1/3", "1/4"}, "1/3"},
+		{"neg", []string{"1/3"}, "-1/3"},
+		{"abs", []string{"-1/3"}, "1/3"},
+		{"floor", []string{"-7/2"}, "-4"},
+		{"ceil", []string{"-7/2"}, "-3"},
+		{"ceil", []string{"7/2"}, "4"},
+		{"round", []string{"5/2"}, "3"},
+		{"round", []string{"-5/2"}, "-3"},
+		{"round", []string{"7/3"}, "2"},
+		{"//", []string{"7/2", "1/3"}, "10"},
+		{"//", []string{"-7", "2"}, "-4"},
+		{"%", []string{"7/2", "1/3"}, "1/6"},
+		{"%", []string{"-7", "3"}, "2"},
+		{"&", []string{"12", "10"}, "8"},
+		{"xor", []string{"-1", "5"}, "-6"},
+		{"<<", []string{"1", "100"}, "1267650600228229401496703205376"},
+		{">>", []string{"-16", "2"}, "-4"},
+		{"<", []string{"1/3", "1/2"}, "1"},
+		{"==", []string{"2/4", "1/2"}, "1"},
+		{">=", []string{"1/3", "1/2"}, "0"},
+		{"not", []string{"1/1000"}, "0"},
+		{"not", []string{"0"}, "1"},
+	}
+
+	for _, tt := range tests {
+		args := make([]*big.Rat, len(tt.args))
+		for i, arg := range tt.args {
+			args[i] = rat(arg)
+		}
+		got, err := ApplyRat(tt.op, args...)
+		if err != nil {
+			t.Errorf("ApplyRat(%s, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got.RatString() != tt.expected {
+			t.Errorf("ApplyRat(%s, %v) = %s, want %s", tt.op, tt.args, got.RatString(), tt.expected)
+		}
+	}
+}
+
+func TestApplyRat_Errors(t *testing.T) {
+	tests := []struct {
+		op   string
+		args []string
+	}{
+		{"/", []string{"1", "0"}},
+		{"^", []string{"2", "1/2"}},
+		{"^", []string{"0", "-1"}},
+		{"^", []string{"10", "100000000"}},
+		{"sqrt", []string{"4"}},
+		{"sin", []string{"0"}},
+		{"?", []string{"1", "2"}},
+		{"+", []string{"1"}},
+		{"%", []string{"1", "0"}},
+		{"&", []string{"1/2", "1"}},
+		{"<<", []string{"1", "-1"}},
+	}
+
+	for _, tt := range tests {
+		args := make([]*big.Rat, len(tt.args))
+		for i, arg := range tt.args {
+			args[i] = rat(arg)
+		}
+		if _, err := ApplyRat(tt.op, args...); err == nil {
+			t.Errorf("ApplyRat(%s, %v) expected error", tt.op, tt.args)
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3/10", "0.3"},
+		{"1/3", "0.333333333333333333333333333333"},
+		{"-5/4", "-1.25"},
+		{"42", "42"},
+		{"1/1000000000000000000000000000000000", "0"},
+	}
+
+	for _, tt := range tests {
+		if got := DecimalString(rat(tt.input)); got != tt.expected {
+			t.Errorf("DecimalString(%s) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+}
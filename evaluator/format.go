@@ -0,0 +1,7 @@
+package evaluator
+
+import "strconv"
+
+func FormatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,229 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type Int int64
+
+var integerOperations = map[string]bool{
+	"&":   true,
+	"|":   true,
+	"xor": true,
+	"<<":  true,
+	">>":  true,
+}
+
+func (i Int) Float() float64 {
+	return float64(i)
+}
+
+func (i Int) String() string {
+	return strconv.FormatInt(int64(i), 10)
+}
+
+func IsIntegerOperation(op string) bool {
+	return integerOperations[op]
+}
+
+func ApplyInt(op string, args ...int64) (Value, error) {
+	arity, ok := operationArity[op]
+	if !ok {
+		if !exactFunction(op) {
+			return nil, fmt.Errorf("неизвестная операция %q", op)
+		}
+		arity = 1
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	a := args[0]
+	switch op {
+	case "neg":
+		if a == math.MinInt64 {
+			return ApplyBig(op, big.NewInt(a))
+		}
+		return Int(-a), nil
+	case "abs":
+		if a == math.MinInt64 {
+			return ApplyBig(op, big.NewInt(a))
+		}
+		if a < 0 {
+			return Int(-a), nil
+		}
+		return Int(a), nil
+	case "floor", "ceil", "round":
+		return Int(a), nil
+	case "not":
+		return Int(boolInt(a == 0)), nil
+	}
+
+	b := args[1]
+	if IsComparison(op) {
+		return Int(compare(op, compareInt(a, b))), nil
+	}
+	switch op {
+	case "+":
+		if r := a + b; (a >= 0) != (b >= 0) || (r >= 0) == (a >= 0) {
+			return Int(r), nil
+		}
+		return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+	case "-":
+		if r := a - b; (a >= 0) == (b >= 0) || (r >= 0) == (a >= 0) {
+			return Int(r), nil
+		}
+		return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+	case "*":
+		if r, ok := mulInt(a, b); ok {
+			return Int(r), nil
+		}
+		return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+		}
+		if a%b == 0 {
+			return Int(a / b), nil
+		}
+		return Float(float64(a) / float64(b)), nil
+	case "//":
+		if b == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+		}
+		q := a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			q--
+		}
+		return Int(q), nil
+	case "%":
+		if b == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		if b == -1 {
+			return Int(0), nil
+		}
+		r := a % b
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return Int(r), nil
+	case "^":
+		if r, ok := powInt(a, b); b >= 0 && ok {
+			return Int(r), nil
+		}
+		if b >= 0 {
+			return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+		}
+		result, err := Apply("^", float64(a), float64(b))
+		if err != nil {
+			return nil, err
+		}
+		return Float(result), nil
+	case "min":
+		if a <= b {
+			return Int(a), nil
+		}
+		return Int(b), nil
+	case "max":
+		if a >= b {
+			return Int(a), nil
+		}
+		return Int(b), nil
+	case "&":
+		return Int(a & b), nil
+	case "|":
+		return Int(a | b), nil
+	case "xor":
+		return Int(a ^ b), nil
+	case "<<", ">>":
+		if b < 0 {
+			return nil, fmt.Errorf("недопустимая величина сдвига %d: ожидается неотрицательное число", b)
+		}
+		if op == ">>" {
+			if b >= 64 {
+				b = 63
+			}
+			return Int(a >> uint(b)), nil
+		}
+		if b < 64 && (a<<uint(b))>>uint(b) == a {
+			return Int(a << uint(b)), nil
+		}
+		return ApplyBig(op, big.NewInt(a), big.NewInt(b))
+	}
+	return nil, fmt.Errorf("неизвестная операция %q", op)
+}
+
+func ParseInt(s string) (int64, bool, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXbBoO", rune(digits[1])) {
+		value, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return 0, true, fmt.Errorf("число %s не помещается в 64 бита", s)
+		}
+		return value, true, nil
+	}
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
+
+func toInt64(op string, value Value) (int64, error) {
+	switch v := value.(type) {
+	case Int:
+		return int64(v), nil
+	case Float:
+		f := float64(v)
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("операция %s определена только для целых чисел, получено %v", op, f)
+	}
+	return 0, fmt.Errorf("операция %s определена только для целых чисел", op)
+}
+
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
+}
+
+func powInt(base, exponent int64) (int64, bool) {
+	result := int64(1)
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			var ok bool
+			if result, ok = mulInt(result, base); !ok {
+				return 0, false
+			}
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			var ok bool
+			if base, ok = mulInt(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
@@ -0,0 +1,156 @@
+package evaluator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApplyInt(t *testing.T) {
+	tests := []struct {
+		op       string
+		args     []int64
+		expected string
+	}{
+		{"+", []int64{2, 3}, "5"},
+		{"-", []int64{2, 3}, "-1"},
+		{"*", []int64{1 << 40, 1 << 20}, "1152921504606846976"},
+		{"/", []int64{6, 3}, "2"},
+		{"/", []int64{7, 2}, "3.5"},
+		{"//", []int64{7, 2}, "3"},
+		{"//", []int64{-7, 2}, "-4"},
+		{"//", []int64{7, -2}, "-4"},
+		{"%", []int64{7, 3}, "1"},
+		{"%", []int64{-7, 3}, "2"},
+		{"%", []int64{7, -3}, "-2"},
+		{"%", []int64{math.MinInt64, -1}, "0"},
+		{"^", []int64{3, 39}, "4052555153018976267"},
+		{"^", []int64{2, -1}, "0.5"},
+		{"&", []int64{0xFF, 0x0F}, "15"},
+		{"|", []int64{0b1010, 0b0101}, "15"},
+		{"xor", []int64{0xFF, 0x0F}, "240"},
+		{"<<", []int64{1, 62}, "4611686018427387904"},
+		{">>", []int64{-16, 2}, "-4"},
+		{"min", []int64{2, -3}, "-3"},
+		{"max", []int64{2, -3}, "2"},
+		{"neg", []int64{5}, "-5"},
+		{"abs", []int64{-5}, "5"},
+		{"round", []int64{5}, "5"},
+		{"<", []int64{math.MaxInt64 - 1, math.MaxInt64}, "1"},
+		{"==", []int64{-3, -3}, "1"},
+		{">", []int64{-3, 3}, "0"},
+		{"not", []int64{0}, "1"},
+		{"not", []int64{7}, "0"},
+		{"+", []int64{math.MaxInt64, 1}, "9223372036854775808"},
+		{"-", []int64{math.MinInt64, 1}, "-9223372036854775809"},
+		{"*", []int64{math.MaxInt64, 2}, "18446744073709551614"},
+		{"neg", []int64{math.MinInt64}, "9223372036854775808"},
+		{"abs", []int64{math.MinInt64}, "9223372036854775808"},
+		{"/", []int64{math.MinInt64, -1}, "9223372036854775808"},
+		{"//", []int64{math.MinInt64, -1}, "9223372036854775808"},
+		{"^", []int64{2, 64}, "18446744073709551616"},
+		{"<<", []int64{1, 63}, "9223372036854775808"},
+		{"<<", []int64{3, 62}, "13835058055282163712"},
+		{"<<", []int64{-1, 63}, "-9223372036854775808"},
+		{"<<", []int64{1, 64}, "18446744073709551616"},
+		{">>", []int64{-1, 100}, "-1"},
+		{">>", []int64{1, 64}, "0"},
+	}
+
+	for _, tt := range tests {
+		got, err := ApplyInt(tt.op, tt.args...)
+		if err != nil {
+			t.Errorf("ApplyInt(%q, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got.String() != tt.expected {
+			t.Errorf("ApplyInt(%q, %v) = %s, expected %s", tt.op, tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestApplyInt_Errors(t *testing.T) {
+	tests := []struct {
+		op   string
+		args []int64
+	}{
+		{"/", []int64{1, 0}},
+		{"//", []int64{1, 0}},
+		{"%", []int64{1, 0}},
+		{"<<", []int64{1, -1}},
+		{">>", []int64{1, -1}},
+		{"sqrt", []int64{4}},
+		{"&", []int64{1}},
+		{"?", []int64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		if _, err := ApplyInt(tt.op, tt.args...); err == nil {
+			t.Errorf("ApplyInt(%q, %v) expected error", tt.op, tt.args)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		ok       bool
+	}{
+		{"42", 42, true},
+		{"-42", -42, true},
+		{"0xFF", 255, true},
+		{"0b1010", 10, true},
+		{"0o17", 15, true},
+		{"-0x10", -16, true},
+		{"9223372036854775807", math.MaxInt64, true},
+		{"9223372036854775808", 0, false},
+		{"2.5", 0, false},
+		{"1e3", 0, false},
+		{"rat:1", 0, false},
+	}
+
+	for _, tt := range tests {
+		value, ok, err := ParseInt(tt.input)
+		if err != nil {
+			t.Errorf("ParseInt(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if ok != tt.ok || value != tt.expected {
+			t.Errorf("ParseInt(%q) = %d, %v; expected %d, %v", tt.input, value, ok, tt.expected, tt.ok)
+		}
+	}
+
+	if _, _, err := ParseInt("0xFFFFFFFFFFFFFFFFF"); err == nil {
+		t.Error("Expected overflow error for a 68-bit hex literal")
+	}
+}
+
+func TestApplyValue_Integer(t *testing.T) {
+	tests := []struct {
+		op       string
+		args     []Value
+		expected string
+	}{
+		{"+", []Value{Int(math.MaxInt64 - 1), Int(1)}, "9223372036854775807"},
+		{"&", []Value{Int(0xFF), Float(15)}, "15"},
+		{"//", []Value{Int(-7), Int(2)}, "-4"},
+		{"//", []Value{Float(7.5), Int(2)}, "3"},
+		{"*", []Value{Int(3), Float(0.5)}, "1.5"},
+		{"sqrt", []Value{Int(16)}, "4"},
+	}
+
+	for _, tt := range tests {
+		got, err := ApplyValue(tt.op, tt.args...)
+		if err != nil {
+			t.Errorf("ApplyValue(%q, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got.String() != tt.expected {
+			t.Errorf("ApplyValue(%q, %v) = %s, expected %s", tt.op, tt.args, got, tt.expected)
+		}
+	}
+
+	if _, err := ApplyValue("|", Float(2.5), Int(1)); err == nil {
+		t.Error("Expected bitwise operation on a fractional value to fail")
+	}
+}
@@ -0,0 +1,314 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	PlusMinus    = "±"
+	IntervalCall = parser.IntervalCall
+)
+
+const intervalPrefix = "iv:"
+
+type Interval struct {
+	Lo float64
+	Hi float64
+}
+
+func (iv Interval) Float() float64 {
+	return iv.Lo + (iv.Hi-iv.Lo)/2
+}
+
+func (iv Interval) String() string {
+	return intervalPrefix + FormatNumber(iv.Lo) + "," + FormatNumber(iv.Hi)
+}
+
+func (iv Interval) Radius() float64 {
+	return (iv.Hi - iv.Lo) / 2
+}
+
+func (iv Interval) degenerate() bool {
+	return iv.Lo == iv.Hi
+}
+
+func (iv Interval) contains(x float64) bool {
+	return iv.Lo <= x && x <= iv.Hi
+}
+
+func IsIntervalOperation(op string) bool {
+	return op == PlusMinus || op == IntervalCall
+}
+
+func parseInterval(s string) (Value, error) {
+	parts := strings.Split(strings.TrimPrefix(s, intervalPrefix), ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("некорректный интервал %q", s)
+	}
+	lo, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный интервал %q", s)
+	}
+	hi, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || lo > hi {
+		return nil, fmt.Errorf("некорректный интервал %q", s)
+	}
+	return Interval{Lo: lo, Hi: hi}, nil
+}
+
+func toInterval(value Value) Interval {
+	if b, ok := value.(Interval); ok {
+		return b
+	}
+	f := value.Float()
+	return Interval{Lo: f, Hi: f}
+}
+
+func hasInterval(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Interval); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyInterval(op string, args ...Value) (Value, error) {
+	xs := make([]Interval, len(args))
+	for i, arg := range args {
+		xs[i] = toInterval(arg)
+	}
+
+	switch op {
+	case PlusMinus:
+		if len(xs) != 2 {
+			return nil, fmt.Errorf("операция %q ожидает 2 аргумент(а), получено %d", op, len(xs))
+		}
+		if xs[1].Lo < 0 {
+			return nil, fmt.Errorf("погрешность не может быть отрицательной: %s", FormatNumber(xs[1].Lo))
+		}
+		return intervalValue(checkedInterval(op, xs[0].Lo-xs[1].Hi, xs[0].Hi+xs[1].Hi))
+	case IntervalCall:
+		if len(xs) != 2 {
+			return nil, fmt.Errorf("интервал задаётся двумя границами, получено %d", len(xs))
+		}
+		if xs[0].Lo > xs[1].Hi {
+			return nil, fmt.Errorf("нижняя граница интервала %s больше верхней %s", FormatNumber(xs[0].Lo), FormatNumber(xs[1].Hi))
+		}
+		return intervalValue(checkedInterval(op, xs[0].Lo, xs[1].Hi))
+	}
+
+	if _, ok := functions[op]; ok {
+		if len(xs) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", op, len(xs))
+		}
+		return intervalFunction(op, xs[0])
+	}
+	if arity, ok := operationArity[op]; !ok {
+		return nil, fmt.Errorf("неизвестная операция %q", op)
+	} else if len(xs) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(xs))
+	}
+
+	if IsComparison(op) {
+		return compareIntervals(op, xs[0], xs[1])
+	}
+
+	switch op {
+	case "+":
+		return intervalValue(checkedInterval(op, xs[0].Lo+xs[1].Lo, xs[0].Hi+xs[1].Hi))
+	case "-":
+		return intervalValue(checkedInterval(op, xs[0].Lo-xs[1].Hi, xs[0].Hi-xs[1].Lo))
+	case "*":
+		return intervalValue(multiplyIntervals(xs[0], xs[1]))
+	case "/":
+		return intervalValue(divideIntervals(xs[0], xs[1]))
+	case "//":
+		q, err := divideIntervals(xs[0], xs[1])
+		if err != nil {
+			return nil, err
+		}
+		return Interval{Lo: math.Floor(q.Lo), Hi: math.Floor(q.Hi)}, nil
+	case "^":
+		return powerInterval(xs[0], xs[1])
+	case "min":
+		return Interval{Lo: math.Min(xs[0].Lo, xs[1].Lo), Hi: math.Min(xs[0].Hi, xs[1].Hi)}, nil
+	case "max":
+		return Interval{Lo: math.Max(xs[0].Lo, xs[1].Lo), Hi: math.Max(xs[0].Hi, xs[1].Hi)}, nil
+	case "neg":
+		return Interval{Lo: -xs[0].Hi, Hi: -xs[0].Lo}, nil
+	case "not":
+		if Truthy(xs[0]) {
+			return Int(0), nil
+		}
+		return Int(1), nil
+	}
+
+	floats := make([]float64, len(xs))
+	for i, x := range xs {
+		if !x.degenerate() {
+			return nil, fmt.Errorf("операция %q не поддерживается для интервалов", op)
+		}
+		floats[i] = x.Lo
+	}
+	result, err := Apply(op, floats...)
+	if err != nil {
+		return nil, err
+	}
+	return Interval{Lo: result, Hi: result}, nil
+}
+
+func compareIntervals(op string, a, b Interval) (Value, error) {
+	var always, never bool
+	switch op {
+	case "<":
+		always, never = a.Hi < b.Lo, a.Lo >= b.Hi
+	case "<=":
+		always, never = a.Hi <= b.Lo, a.Lo > b.Hi
+	case ">":
+		always, never = a.Lo > b.Hi, a.Hi <= b.Lo
+	case ">=":
+		always, never = a.Lo >= b.Hi, a.Hi < b.Lo
+	case "==", "!=":
+		always = a.degenerate() && b.degenerate() && a.Lo == b.Lo
+		never = a.Hi < b.Lo || b.Hi < a.Lo
+		if op == "!=" {
+			always, never = never, always
+		}
+	}
+	switch {
+	case always:
+		return Int(1), nil
+	case never:
+		return Int(0), nil
+	}
+	return nil, fmt.Errorf("сравнение интервалов %s %s %s неоднозначно", formatInterval(a), op, formatInterval(b))
+}
+
+func multiplyIntervals(a, b Interval) (Interval, error) {
+	products := []float64{a.Lo * b.Lo, a.Lo * b.Hi, a.Hi * b.Lo, a.Hi * b.Hi}
+	return hull("*", products)
+}
+
+func divideIntervals(a, b Interval) (Interval, error) {
+	if b.Lo == 0 && b.Hi == 0 {
+		return Interval{}, fmt.Errorf("деление на ноль")
+	}
+	if b.contains(0) {
+		return Interval{}, fmt.Errorf("деление на интервал %s, содержащий ноль", formatInterval(b))
+	}
+	return multiplyIntervals(a, Interval{Lo: 1 / b.Hi, Hi: 1 / b.Lo})
+}
+
+func powerInterval(a, b Interval) (Value, error) {
+	if !b.degenerate() {
+		if a.Lo <= 0 {
+			return nil, fmt.Errorf("возведение интервала %s в интервальную степень определено только для положительных чисел", formatInterval(a))
+		}
+		return intervalValue(hull("^", []float64{
+			math.Pow(a.Lo, b.Lo), math.Pow(a.Lo, b.Hi), math.Pow(a.Hi, b.Lo), math.Pow(a.Hi, b.Hi),
+		}))
+	}
+
+	p := b.Lo
+	if p != math.Trunc(p) {
+		if a.Lo < 0 || (p < 0 && a.Lo == 0) {
+			return nil, fmt.Errorf("возведение интервала %s в степень %s не определено", formatInterval(a), FormatNumber(p))
+		}
+		return intervalValue(hull("^", []float64{math.Pow(a.Lo, p), math.Pow(a.Hi, p)}))
+	}
+	if p < 0 {
+		denominator, err := powerInterval(a, Interval{Lo: -p, Hi: -p})
+		if err != nil {
+			return nil, err
+		}
+		return intervalValue(divideIntervals(Interval{Lo: 1, Hi: 1}, denominator.(Interval)))
+	}
+
+	lo, hi := math.Pow(a.Lo, p), math.Pow(a.Hi, p)
+	if math.Mod(p, 2) == 0 && a.contains(0) {
+		return intervalValue(checkedInterval("^", 0, math.Max(lo, hi)))
+	}
+	return intervalValue(hull("^", []float64{lo, hi}))
+}
+
+func intervalFunction(name string, x Interval) (Value, error) {
+	switch name {
+	case "abs":
+		if x.contains(0) {
+			return Interval{Lo: 0, Hi: math.Max(-x.Lo, x.Hi)}, nil
+		}
+		return intervalValue(hull(name, []float64{math.Abs(x.Lo), math.Abs(x.Hi)}))
+	case "sin":
+		return periodicInterval(name, math.Sin, x, math.Pi/2, -math.Pi/2)
+	case "cos":
+		return periodicInterval(name, math.Cos, x, 0, math.Pi)
+	case "tan":
+		if x.Hi-x.Lo >= math.Pi || reaches(x, math.Pi/2, math.Pi) {
+			return nil, fmt.Errorf("функция tan не определена на интервале %s", formatInterval(x))
+		}
+	}
+
+	lo, err := Apply(name, x.Lo)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := Apply(name, x.Hi)
+	if err != nil {
+		return nil, err
+	}
+	return Interval{Lo: lo, Hi: hi}, nil
+}
+
+func periodicInterval(name string, f func(float64) float64, x Interval, peak, trough float64) (Value, error) {
+	if x.Hi-x.Lo >= 2*math.Pi {
+		return Interval{Lo: -1, Hi: 1}, nil
+	}
+	b, err := hull(name, []float64{f(x.Lo), f(x.Hi)})
+	if err != nil {
+		return nil, err
+	}
+	if reaches(x, peak, 2*math.Pi) {
+		b.Hi = 1
+	}
+	if reaches(x, trough, 2*math.Pi) {
+		b.Lo = -1
+	}
+	return b, nil
+}
+
+func reaches(x Interval, point, period float64) bool {
+	k := math.Ceil((x.Lo - point) / period)
+	return point+k*period <= x.Hi
+}
+
+func hull(op string, values []float64) (Interval, error) {
+	b := Interval{Lo: values[0], Hi: values[0]}
+	for _, v := range values[1:] {
+		b.Lo = math.Min(b.Lo, v)
+		b.Hi = math.Max(b.Hi, v)
+	}
+	return checkedInterval(op, b.Lo, b.Hi)
+}
+
+func checkedInterval(op string, lo, hi float64) (Interval, error) {
+	if math.IsNaN(lo) || math.IsNaN(hi) || math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return Interval{}, fmt.Errorf("операция %q над интервалами не определена", op)
+	}
+	return Interval{Lo: lo, Hi: hi}, nil
+}
+
+func intervalValue(iv Interval, err error) (Value, error) {
+	if err != nil {
+		return nil, err
+	}
+	return iv, nil
+}
+
+func formatInterval(iv Interval) string {
+	return "[" + FormatNumber(iv.Lo) + ", " + FormatNumber(iv.Hi) + "]"
+}
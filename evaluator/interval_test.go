@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"math"
+	"testing"
+)
+
+func TestEvaluate_Interval(t *testing.T) {
+	tests := []struct {
+		expr string
+		lo   float64
+		hi   float64
+	}{
+		{"3.2±0.1", 3.1, 3.3},
+		{"[1; 2] + [3; 4]", 4, 6},
+		{"[1; 2] - [3; 4]", -3, -1},
+		{"[-1; 2]*[3; 4]", -4, 8},
+		{"[1; 2]/[4; 8]", 0.125, 0.5},
+		{"[-1; 2]^2", 0, 4},
+		{"[1; 2]^-1", 0.5, 1},
+		{"[1; 4]^0.5", 1, 2},
+		{"sqrt([4; 9])", 2, 3},
+		{"abs([-3; 2])", 0, 3},
+		{"sin([0; 2])", 0, 1},
+		{"cos([-1; 1])", math.Cos(1), 1},
+		{"max([1; 3], [2; 2])", 2, 3},
+		{"-[1; 2]", -2, -1},
+		{"[1; 2]±0.5", 0.5, 2.5},
+		{"sum(i, 1, 3, i*[1; 2])", 6, 12},
+		{"x^2 - x", -1, 1},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err := Evaluate(node, map[string]Value{"x": Interval{Lo: 0, Hi: 1}}, "")
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+		}
+		iv, ok := result.(Interval)
+		if !ok {
+			t.Fatalf("Evaluate(%s) = %v, expected an interval", tt.expr, result)
+		}
+		if math.Abs(iv.Lo-tt.lo) > 1e-12 || math.Abs(iv.Hi-tt.hi) > 1e-12 {
+			t.Errorf("Evaluate(%s) = [%v, %v], expected [%v, %v]", tt.expr, iv.Lo, iv.Hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestEvaluate_IntervalComparison(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected Value
+	}{
+		{"[1; 2] < [3; 4]", Int(1)},
+		{"[1; 2] >= [3; 4]", Int(0)},
+		{"[1; 2] == [3; 4]", Int(0)},
+		{"[1; 2] != [3; 4]", Int(1)},
+		{"if([1; 2] > 0, 1, 2)", Int(1)},
+		{"![1; 2]", Int(0)},
+		{"![-1; 1]", Int(0)},
+		{"![0; 0]", Int(1)},
+		{"[-1; 1] && 1", Int(1)},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err := Evaluate(node, nil, "")
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+		}
+		if result != tt.expected {
+			t.Errorf("Evaluate(%s) = %v, expected %v", tt.expr, result, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_IntervalErrors(t *testing.T) {
+	tests := []string{
+		"[1; 3] < [2; 4]",
+		"[1; 2] == [1; 2]",
+		"1/[-1; 1]",
+		"tan([1; 2])",
+		"[2; 1]",
+		"1±-1",
+		"sqrt([-1; 1])",
+		"[-1; 2]^0.5",
+		"[1; 2] % 2",
+		"integrate(x, 0, [1; 2])",
+	}
+
+	for _, expr := range tests {
+		node, err := parser.Parse(expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result, err := Evaluate(node, nil, ""); err == nil || result != nil {
+			t.Errorf("Expected error for %s, got %v", expr, result)
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	iv := Interval{Lo: 3.1, Hi: 3.5}
+	if math.Abs(iv.Float()-3.3) > 1e-12 || math.Abs(iv.Radius()-0.2) > 1e-12 {
+		t.Errorf("Expected midpoint 3.3 and radius 0.2, got %v and %v", iv.Float(), iv.Radius())
+	}
+
+	parsed, err := ParseValue(iv.String())
+	if err != nil {
+		t.Fatalf("ParseValue(%s) failed: %v", iv, err)
+	}
+	if parsed != iv {
+		t.Errorf("Expected %v, got %v", iv, parsed)
+	}
+
+	for _, input := range []string{"iv:", "iv:1", "iv:2,1", "iv:a,b"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package evaluator
+
+import "math/big"
+
+const Conditional = "if"
+
+var comparisons = map[string]func(cmp int) bool{
+	"<":  func(cmp int) bool { return cmp < 0 },
+	"<=": func(cmp int) bool { return cmp <= 0 },
+	"==": func(cmp int) bool { return cmp == 0 },
+	"!=": func(cmp int) bool { return cmp != 0 },
+	">=": func(cmp int) bool { return cmp >= 0 },
+	">":  func(cmp int) bool { return cmp > 0 },
+}
+
+func IsComparison(op string) bool {
+	_, ok := comparisons[op]
+	return ok
+}
+
+func Truthy(value Value) bool {
+	switch v := value.(type) {
+	case Rat:
+		return v.Sign() != 0
+	case Int:
+		return v != 0
+	case Interval:
+		return v.Lo != 0 || v.Hi != 0
+	case Complex:
+		return v != 0
+	}
+	return value.Float() != 0
+}
+
+func Boolean(b bool, mode string) Value {
+	if mode == ModeExact {
+		return Rat{big.NewRat(boolInt(b), 1)}
+	}
+	return Int(boolInt(b))
+}
+
+func compare(op string, cmp int) int64 {
+	return boolInt(comparisons[op](cmp))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestTruthy(t *testing.T) {
+	tests := []struct {
+		value    Value
+		expected bool
+	}{
+		{Int(0), false},
+		{Int(-1), true},
+		{Float(0), false},
+		{Float(0.5), true},
+		{Rat{big.NewRat(0, 1)}, false},
+		{Rat{big.NewRat(1, 10)}, true},
+		{Rat{new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), 2000))}, true},
+	}
+
+	for _, tt := range tests {
+		if got := Truthy(tt.value); got != tt.expected {
+			t.Errorf("Truthy(%s) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestBoolean(t *testing.T) {
+	if got := Boolean(true, "").String(); got != "1" {
+		t.Errorf("Expected 1, got %s", got)
+	}
+	if got := Boolean(false, "").String(); got != "0" {
+		t.Errorf("Expected 0, got %s", got)
+	}
+	if got := Boolean(true, ModeExact).String(); got != "rat:1" {
+		t.Errorf("Expected rat:1, got %s", got)
+	}
+}
+
+func TestApplyValue_Comparison(t *testing.T) {
+	third, _ := Literal("1/3", ModeExact)
+	tests := []struct {
+		op       string
+		args     []Value
+		expected string
+	}{
+		{"<", []Value{Int(math.MaxInt64), Float(1e300)}, "1"},
+		{"==", []Value{Int(9007199254740993), Int(9007199254740992)}, "0"},
+		{"!=", []Value{Float(0.1), Float(0.2)}, "1"},
+		{">", []Value{third, Float(0.3)}, "rat:1"},
+		{"not", []Value{third}, "rat:0"},
+	}
+
+	for _, tt := range tests {
+		got, err := ApplyValue(tt.op, tt.args...)
+		if err != nil {
+			t.Errorf("ApplyValue(%q, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got.String() != tt.expected {
+			t.Errorf("ApplyValue(%q, %v) = %s, expected %s", tt.op, tt.args, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	VectorCall = parser.VectorCall
+	MatrixCall = parser.MatrixCall
+	MatMul     = "matmul"
+)
+
+const (
+	vectorPrefix       = "vec:"
+	matrixPrefix       = "mat:"
+	singularTolerance  = 1e-12
+	maxMatrixDimension = 1000
+)
+
+var matrixArity = map[string]int{
+	MatMul:      2,
+	"dot":       2,
+	"cross":     2,
+	"det":       1,
+	"inv":       1,
+	"transpose": 1,
+	"norm":      1,
+}
+
+type Matrix struct {
+	Rows   int
+	Cols   int
+	Data   []float64
+	Vector bool
+}
+
+func (m Matrix) Float() float64 {
+	var sum float64
+	for _, x := range m.Data {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+func (m Matrix) String() string {
+	elements := make([]string, len(m.Data))
+	for i, x := range m.Data {
+		elements[i] = FormatNumber(x)
+	}
+	if m.Vector {
+		return vectorPrefix + strings.Join(elements, ",")
+	}
+	return fmt.Sprintf("%s%dx%d:%s", matrixPrefix, m.Rows, m.Cols, strings.Join(elements, ","))
+}
+
+func (m Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+func (m Matrix) Row(i int) []float64 {
+	return m.Data[i*m.Cols : (i+1)*m.Cols]
+}
+
+func (m Matrix) Grid() [][]float64 {
+	grid := make([][]float64, m.Rows)
+	for i := range grid {
+		grid[i] = append([]float64(nil), m.Row(i)...)
+	}
+	return grid
+}
+
+func (m Matrix) RowBlock(from, to int) Matrix {
+	return Matrix{Rows: to - from, Cols: m.Cols, Data: append([]float64(nil), m.Data[from*m.Cols:to*m.Cols]...)}
+}
+
+func (m Matrix) shape() string {
+	if m.Vector {
+		return fmt.Sprintf("вектор длины %d", m.Cols)
+	}
+	return fmt.Sprintf("матрица %dx%d", m.Rows, m.Cols)
+}
+
+func (m Matrix) sameShape(other Matrix) bool {
+	return m.Rows == other.Rows && m.Cols == other.Cols && m.Vector == other.Vector
+}
+
+func (m Matrix) square() bool {
+	return !m.Vector && m.Rows == m.Cols
+}
+
+func NewVector(elements []float64) Matrix {
+	return Matrix{Rows: 1, Cols: len(elements), Data: elements, Vector: true}
+}
+
+func IsMatrixOperation(name string) bool {
+	_, ok := matrixArity[name]
+	return ok || name == VectorCall || name == MatrixCall
+}
+
+func MatrixArity(name string) int {
+	return matrixArity[name]
+}
+
+func parseVector(s string) (Value, error) {
+	elements, err := parseElements(strings.TrimPrefix(s, vectorPrefix))
+	if err != nil || len(elements) == 0 {
+		return nil, fmt.Errorf("некорректный вектор %q", s)
+	}
+	return NewVector(elements), nil
+}
+
+func parseMatrix(s string) (Value, error) {
+	shape, data, ok := strings.Cut(strings.TrimPrefix(s, matrixPrefix), ":")
+	if !ok {
+		return nil, fmt.Errorf("некорректная матрица %q", s)
+	}
+	rows, cols, ok := strings.Cut(shape, "x")
+	if !ok {
+		return nil, fmt.Errorf("некорректная матрица %q", s)
+	}
+	r, err := strconv.Atoi(rows)
+	if err != nil || r < 1 {
+		return nil, fmt.Errorf("некорректная матрица %q", s)
+	}
+	c, err := strconv.Atoi(cols)
+	if err != nil || c < 1 {
+		return nil, fmt.Errorf("некорректная матрица %q", s)
+	}
+	elements, err := parseElements(data)
+	if err != nil || len(elements) != r*c {
+		return nil, fmt.Errorf("некорректная матрица %q", s)
+	}
+	return Matrix{Rows: r, Cols: c, Data: elements}, nil
+}
+
+func parseElements(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	elements := make([]float64, len(parts))
+	for i, part := range parts {
+		x, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = x
+	}
+	return elements, nil
+}
+
+func hasMatrix(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Matrix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyMatrix(op string, args ...Value) (Value, error) {
+	for _, arg := range args {
+		switch arg.(type) {
+		case Rat:
+			return nil, fmt.Errorf("векторы и матрицы недоступны в точном режиме")
+		case Interval, Complex, Quantity:
+			return nil, fmt.Errorf("векторы и матрицы поддерживают только действительные числа")
+		}
+	}
+
+	switch op {
+	case VectorCall:
+		return concatVectors(args)
+	case MatrixCall:
+		return stackRows(args)
+	}
+	if arity, ok := matrixArity[op]; ok {
+		if len(args) != arity {
+			return nil, fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", op, arity, len(args))
+		}
+		return matrixFunction(op, args)
+	}
+	return elementwise(op, args)
+}
+
+func concatVectors(args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("функция %s ожидает хотя бы один аргумент", VectorCall)
+	}
+	var elements []float64
+	for _, arg := range args {
+		m, ok := arg.(Matrix)
+		switch {
+		case !ok:
+			elements = append(elements, arg.Float())
+		case m.Vector:
+			elements = append(elements, m.Data...)
+		default:
+			return nil, fmt.Errorf("функция %s не принимает матрицы", VectorCall)
+		}
+	}
+	if len(elements) > maxMatrixDimension*maxMatrixDimension {
+		return nil, fmt.Errorf("вектор слишком велик: допускается не больше %d элементов", maxMatrixDimension*maxMatrixDimension)
+	}
+	return NewVector(elements), nil
+}
+
+func stackRows(args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("функция %s ожидает хотя бы один аргумент", MatrixCall)
+	}
+	result := Matrix{}
+	for _, arg := range args {
+		block, ok := arg.(Matrix)
+		if !ok {
+			block = NewVector([]float64{arg.Float()})
+		}
+		if result.Rows > 0 && block.Cols != result.Cols {
+			return nil, fmt.Errorf("строки матрицы имеют разную длину: %d и %d", result.Cols, block.Cols)
+		}
+		result.Rows += block.Rows
+		result.Cols = block.Cols
+		result.Data = append(result.Data, block.Data...)
+	}
+	if result.Rows > maxMatrixDimension || result.Cols > maxMatrixDimension {
+		return nil, fmt.Errorf("матрица слишком велика: допускается не больше %d строк и столбцов", maxMatrixDimension)
+	}
+	return result, nil
+}
+
+func elementwise(op string, args []Value) (Value, error) {
+	switch op {
+	case "==", "!=":
+		left, leftOk := args[0].(Matrix)
+		right, rightOk := args[1].(Matrix)
+		equal := leftOk && rightOk && left.sameShape(right)
+		for i := 0; equal && i < len(left.Data); i++ {
+			equal = left.Data[i] == right.Data[i]
+		}
+		return Int(boolInt(equal == (op == "=="))), nil
+	case "not":
+		return Int(boolInt(args[0].Float() == 0)), nil
+	}
+
+	var shape *Matrix
+	for _, arg := range args {
+		m, ok := arg.(Matrix)
+		if !ok {
+			continue
+		}
+		if shape == nil {
+			shape = &m
+		} else if !m.sameShape(*shape) {
+			return nil, fmt.Errorf("размеры не совпадают: %s и %s", shape.shape(), m.shape())
+		}
+	}
+
+	if IsComparison(op) {
+		return nil, fmt.Errorf("сравнение %s не определено для векторов и матриц", op)
+	}
+	if _, ok := functions[op]; !ok {
+		if _, ok := operationArity[op]; !ok {
+			return nil, fmt.Errorf("операция %q не определена для векторов и матриц", op)
+		}
+	}
+
+	result := Matrix{Rows: shape.Rows, Cols: shape.Cols, Data: make([]float64, len(shape.Data)), Vector: shape.Vector}
+	xs := make([]float64, len(args))
+	for k := range result.Data {
+		for i, arg := range args {
+			if m, ok := arg.(Matrix); ok {
+				xs[i] = m.Data[k]
+			} else {
+				xs[i] = arg.Float()
+			}
+		}
+		x, err := Apply(op, xs...)
+		if err != nil {
+			return nil, err
+		}
+		result.Data[k] = x
+	}
+	return result, nil
+}
+
+func matrixFunction(op string, args []Value) (Value, error) {
+	ms := make([]Matrix, len(args))
+	for i, arg := range args {
+		m, ok := arg.(Matrix)
+		if !ok {
+			return nil, fmt.Errorf("функция %s ожидает векторы или матрицы, получено число %s", op, FormatNumber(arg.Float()))
+		}
+		ms[i] = m
+	}
+	a := ms[0]
+
+	switch op {
+	case "norm":
+		return Float(a.Float()), nil
+	case "transpose":
+		if a.Vector {
+			return Matrix{Rows: a.Cols, Cols: 1, Data: append([]float64(nil), a.Data...)}, nil
+		}
+		result := Matrix{Rows: a.Cols, Cols: a.Rows, Data: make([]float64, len(a.Data))}
+		for i := 0; i < a.Rows; i++ {
+			for j := 0; j < a.Cols; j++ {
+				result.Data[j*a.Rows+i] = a.At(i, j)
+			}
+		}
+		return result, nil
+	case "det":
+		if !a.square() {
+			return nil, fmt.Errorf("определитель определён только для квадратных матриц, получено: %s", a.shape())
+		}
+		return Float(determinant(a)), nil
+	case "inv":
+		if !a.square() {
+			return nil, fmt.Errorf("обратная матрица определена только для квадратных матриц, получено: %s", a.shape())
+		}
+		return inverse(a)
+	}
+
+	b := ms[1]
+	switch op {
+	case "dot":
+		if !a.Vector || !b.sameShape(a) {
+			return nil, fmt.Errorf("функция dot ожидает векторы одинаковой длины, получено: %s и %s", a.shape(), b.shape())
+		}
+		return Float(dot(a.Data, b.Data)), nil
+	case "cross":
+		if !a.Vector || !b.Vector || a.Cols != 3 || b.Cols != 3 {
+			return nil, fmt.Errorf("функция cross ожидает трёхмерные векторы, получено: %s и %s", a.shape(), b.shape())
+		}
+		x, y := a.Data, b.Data
+		return NewVector([]float64{x[1]*y[2] - x[2]*y[1], x[2]*y[0] - x[0]*y[2], x[0]*y[1] - x[1]*y[0]}), nil
+	case MatMul:
+		return matmul(a, b)
+	}
+	return nil, fmt.Errorf("неизвестная функция %q", op)
+}
+
+func matmul(a, b Matrix) (Value, error) {
+	switch {
+	case a.Vector && b.Vector:
+		if a.Cols != b.Cols {
+			return nil, fmt.Errorf("размеры не согласованы для умножения: %s и %s", a.shape(), b.shape())
+		}
+		return Float(dot(a.Data, b.Data)), nil
+	case b.Vector:
+		if a.Cols != b.Cols {
+			return nil, fmt.Errorf("размеры не согласованы для умножения: %s и %s", a.shape(), b.shape())
+		}
+		result := make([]float64, a.Rows)
+		for i := range result {
+			result[i] = dot(a.Row(i), b.Data)
+		}
+		return NewVector(result), nil
+	}
+	if a.Cols != b.Rows {
+		return nil, fmt.Errorf("размеры не согласованы для умножения: %s и %s", a.shape(), b.shape())
+	}
+	result := Matrix{Rows: a.Rows, Cols: b.Cols, Data: make([]float64, a.Rows*b.Cols), Vector: a.Vector}
+	for i := 0; i < a.Rows; i++ {
+		for k := 0; k < a.Cols; k++ {
+			x := a.At(i, k)
+			for j := 0; j < b.Cols; j++ {
+				result.Data[i*b.Cols+j] += x * b.At(k, j)
+			}
+		}
+	}
+	return result, nil
+}
+
+func dot(x, y []float64) float64 {
+	var sum float64
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+func pivotRow(m Matrix, col int) int {
+	pivot := col
+	for i := col + 1; i < m.Rows; i++ {
+		if math.Abs(m.At(i, col)) > math.Abs(m.At(pivot, col)) {
+			pivot = i
+		}
+	}
+	return pivot
+}
+
+func swapRows(m Matrix, i, j int) {
+	if i == j {
+		return
+	}
+	a, b := m.Row(i), m.Row(j)
+	for k := range a {
+		a[k], b[k] = b[k], a[k]
+	}
+}
+
+func determinant(a Matrix) float64 {
+	lu := Matrix{Rows: a.Rows, Cols: a.Cols, Data: append([]float64(nil), a.Data...)}
+	det := 1.0
+	for col := 0; col < lu.Cols; col++ {
+		pivot := pivotRow(lu, col)
+		if lu.At(pivot, col) == 0 {
+			return 0
+		}
+		if pivot != col {
+			swapRows(lu, pivot, col)
+			det = -det
+		}
+		det *= lu.At(col, col)
+		for i := col + 1; i < lu.Rows; i++ {
+			factor := lu.At(i, col) / lu.At(col, col)
+			row, top := lu.Row(i), lu.Row(col)
+			for j := col; j < lu.Cols; j++ {
+				row[j] -= factor * top[j]
+			}
+		}
+	}
+	return det
+}
+
+func inverse(a Matrix) (Value, error) {
+	n := a.Rows
+	work := Matrix{Rows: n, Cols: n, Data: append([]float64(nil), a.Data...)}
+	result := Matrix{Rows: n, Cols: n, Data: make([]float64, n*n)}
+	scale := 0.0
+	for i := 0; i < n; i++ {
+		result.Data[i*n+i] = 1
+	}
+	for _, x := range a.Data {
+		scale = math.Max(scale, math.Abs(x))
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := pivotRow(work, col)
+		if math.Abs(work.At(pivot, col)) <= singularTolerance*scale {
+			return nil, fmt.Errorf("матрица вырождена")
+		}
+		swapRows(work, pivot, col)
+		swapRows(result, pivot, col)
+
+		p := work.At(col, col)
+		for j := 0; j < n; j++ {
+			work.Data[col*n+j] /= p
+			result.Data[col*n+j] /= p
+		}
+		for i := 0; i < n; i++ {
+			if i == col {
+				continue
+			}
+			factor := work.At(i, col)
+			for j := 0; j < n; j++ {
+				work.Data[i*n+j] -= factor * work.At(col, j)
+				result.Data[i*n+j] -= factor * result.At(col, j)
+			}
+		}
+	}
+	return result, nil
+}
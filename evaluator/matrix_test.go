@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"testing"
+)
+
+func TestEvaluate_Matrix(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"[1, 2, 3]", "vec:1,2,3"},
+		{"[x]", "vec:2"},
+		{"vector(1, 2)", "vec:1,2"},
+		{"[[1, 2], [3, 4]]", "mat:2x2:1,2,3,4"},
+		{"[[1, 2, 3]]", "mat:1x3:1,2,3"},
+		{"[1, 2, 3] + [4, 5, 6]", "vec:5,7,9"},
+		{"[1, 2, 3]*2 - 1", "vec:1,3,5"},
+		{"[[1, 2], [3, 4]]*[[2, 0], [1, 2]]", "mat:2x2:2,0,3,8"},
+		{"1/[1, 2, 4]", "vec:1,0.5,0.25"},
+		{"-[1, -2, 3]^2", "vec:-1,-4,-9"},
+		{"sqrt([4, 9, 16])", "vec:2,3,4"},
+		{"[7, 8, 9] % 4", "vec:3,0,1"},
+		{"dot([1, 2, 3], [4, 5, 6])", "32"},
+		{"cross([1, 0, 0], [0, 1, 0])", "vec:0,0,1"},
+		{"det([[1, 2], [3, 4]])", "-2"},
+		{"det([[0, 1, 2], [1, 0, 3], [4, -3, 8]])", "-2"},
+		{"det([[1, 2], [2, 4]])", "0"},
+		{"inv([[2, 0], [0, 4]])", "mat:2x2:0.5,0,0,0.25"},
+		{"inv([[0, 1], [2, 0]])", "mat:2x2:0,0.5,1,0"},
+		{"transpose([[1, 2, 3], [4, 5, 6]])", "mat:3x2:1,4,2,5,3,6"},
+		{"transpose([1, 2])", "mat:2x1:1,2"},
+		{"matmul([[1, 2], [3, 4]], [[5, 6], [7, 8]])", "mat:2x2:19,22,43,50"},
+		{"matmul([[1, 2], [3, 4]], [1, 1])", "vec:3,7"},
+		{"matmul([1, 1], [[1, 2], [3, 4]])", "vec:4,6"},
+		{"matmul([1, 2, 3], [1, 2, 3])", "14"},
+		{"matmul(transpose([1, 2]), [[3, 4]])", "mat:2x2:3,4,6,8"},
+		{"norm([3, 4])", "5"},
+		{"[1, 2, 3] == [1, 2, 3]", "1"},
+		{"vector(1, 2) == [[1, 2]]", "0"},
+		{"[1, 2, 3] != [1, 2, 4]", "1"},
+		{"v = [1, 2, 3]; w = [v, 4]; w*x", "vec:2,4,6,8"},
+		{"v = vector(1, 2); [v, v]", "vec:1,2,1,2"},
+		{"v = vector(1, 2); matrix(v, v)", "mat:2x2:1,2,1,2"},
+		{"dot([1, 2], [3, 4])", "11"},
+		{"[1, 2] - [3, 4]", "vec:-2,-2"},
+		{"[3, 1]", "vec:3,1"},
+		{"[1, 2]*[3, 4] + 1", "vec:4,9"},
+		{"sqrt([4, 9])", "vec:2,3"},
+		{"[x, 1]/[2, 4] == [1, 0.25]", "1"},
+		{"cross([2, 1, 0], [1, 2, 0]) == [0, 0, 3]", "1"},
+		{"matrix([1, 2], [[3, 4], [5, 6]])", "mat:3x2:1,2,3,4,5,6"},
+		{"if([0, 0], 1, 2)", "2"},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.ParseProgram(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		scope := map[string]Value{"x": Float(2)}
+		var result Value
+		for _, stmt := range program.Statements {
+			if result, err = Evaluate(stmt.Value, scope, ""); err != nil {
+				break
+			}
+			if stmt.IsAssignment() {
+				scope[stmt.Name] = result
+			}
+		}
+		if err != nil {
+			t.Errorf("Evaluate(%s) failed: %v", tt.expr, err)
+			continue
+		}
+		if result.String() != tt.expected {
+			t.Errorf("Evaluate(%s) = %s, expected %s", tt.expr, result, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_MatrixErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		mode string
+	}{
+		{"[1, 2, 3] + [1, 2]", ""},
+		{"[1, 2, 3] < [4, 5, 6]", ""},
+		{"dot([1, 2], [1, 2, 3])", ""},
+		{"cross([1, 2], [3, 4])", ""},
+		{"det([[1, 2, 3]])", ""},
+		{"inv([[1, 2], [2, 4]])", ""},
+		{"matmul([[1, 2, 3]], [[1, 2]])", ""},
+		{"transpose(3)", ""},
+		{"vector([[1, 2]])", ""},
+		{"matrix([1, 2], [3])", ""},
+		{"[1, 2, 3]*i", ""},
+		{"[1, 2, 3]*1 m", ""},
+		{"[1, 2, 3]±1", ""},
+		{"interval(1, 2) + vector(1, 2)", ""},
+		{"sqrt([1, -1, 4])", ""},
+		{"[1, 2, 3]", ModeExact},
+		{"det(1, 2)", ""},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		if result, err := Evaluate(node, nil, tt.mode); err == nil {
+			t.Errorf("Expected error for %s, got %v", tt.expr, result)
+		}
+	}
+}
+
+func TestParseValue_Matrix(t *testing.T) {
+	for _, input := range []string{"vec:1,2.5,-3", "mat:2x3:1,2,3,4,5,6", "mat:1x1:7"} {
+		value, err := ParseValue(input)
+		if err != nil {
+			t.Errorf("ParseValue(%q) failed: %v", input, err)
+			continue
+		}
+		if value.String() != input {
+			t.Errorf("Expected %q to round-trip, got %q", input, value.String())
+		}
+	}
+	for _, input := range []string{"vec:", "vec:1,x", "mat:2x2:1,2,3", "mat:0x1:", "mat:2:1,2", "mat:axb:1"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+
+	m, _ := ParseValue("mat:3x2:1,2,3,4,5,6")
+	block := m.(Matrix).RowBlock(1, 3)
+	if block.String() != "mat:2x2:3,4,5,6" {
+		t.Errorf("Expected rows 2..3, got %s", block)
+	}
+	if grid := m.(Matrix).Grid(); len(grid) != 3 || grid[2][1] != 6 {
+		t.Errorf("Unexpected grid %v", grid)
+	}
+}
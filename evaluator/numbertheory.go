@@ -0,0 +1,373 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FactorialCall = parser.FactorialCall
+	FactorCall    = "factor"
+	TrialDivision = "trial"
+)
+
+const (
+	factorsPrefix    = "fac:"
+	maxFactorial     = 100000
+	smallPrimeBound  = 1000
+	maxRhoIterations = 1 << 20
+)
+
+var numberTheoryArity = map[string]int{
+	FactorialCall: 1,
+	"binom":       2,
+	"gcd":         2,
+	"lcm":         2,
+	"isprime":     1,
+	FactorCall:    1,
+	"modpow":      3,
+}
+
+type Factors string
+
+func (f Factors) Float() float64 {
+	return BigInt{f.product()}.Float()
+}
+
+func (f Factors) Overflows() bool {
+	return BigInt{f.product()}.Overflows()
+}
+
+func (f Factors) String() string {
+	return factorsPrefix + string(f)
+}
+
+func IsNumberTheory(name string) bool {
+	_, ok := numberTheoryArity[name]
+	return ok
+}
+
+func NumberTheoryArity(name string) int {
+	return numberTheoryArity[name]
+}
+
+func parseFactors(s string) (Value, error) {
+	f := Factors(strings.TrimPrefix(s, factorsPrefix))
+	if _, err := f.primes(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func newFactors(primes []*big.Int) Factors {
+	sorted := append([]*big.Int(nil), primes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	var terms []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j].Cmp(sorted[i]) == 0 {
+			j++
+		}
+		term := sorted[i].String()
+		if j-i > 1 {
+			term += "^" + strconv.Itoa(j-i)
+		}
+		terms = append(terms, term)
+		i = j
+	}
+	if len(terms) == 0 {
+		return "1"
+	}
+	return Factors(strings.Join(terms, "*"))
+}
+
+func (f Factors) primes() ([]*big.Int, error) {
+	if f == "1" {
+		return nil, nil
+	}
+	var primes []*big.Int
+	for _, term := range strings.Split(string(f), "*") {
+		base, power, raised := strings.Cut(term, "^")
+		p, ok := new(big.Int).SetString(base, 10)
+		if !ok || p.Cmp(big.NewInt(2)) < 0 {
+			return nil, fmt.Errorf("некорректное разложение на множители %q", string(f))
+		}
+		k := 1
+		if raised {
+			n, err := strconv.Atoi(power)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("некорректное разложение на множители %q", string(f))
+			}
+			k = n
+		}
+		for i := 0; i < k; i++ {
+			primes = append(primes, p)
+		}
+	}
+	return primes, nil
+}
+
+func (f Factors) product() *big.Int {
+	result := big.NewInt(1)
+	primes, _ := f.primes()
+	for _, p := range primes {
+		result.Mul(result, p)
+	}
+	return result
+}
+
+func hasFactors(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Factors); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyFactors(op string, args ...Value) (Value, error) {
+	if op == "*" && len(args) == 2 {
+		left, leftOk := args[0].(Factors)
+		right, rightOk := args[1].(Factors)
+		if leftOk && rightOk {
+			a, err := left.primes()
+			if err != nil {
+				return nil, err
+			}
+			b, err := right.primes()
+			if err != nil {
+				return nil, err
+			}
+			return newFactors(append(a, b...)), nil
+		}
+	}
+	converted := make([]Value, len(args))
+	for i, arg := range args {
+		converted[i] = arg
+		if f, ok := arg.(Factors); ok {
+			converted[i] = IntValue(f.product())
+		}
+	}
+	return ApplyValue(op, converted...)
+}
+
+func ApplyNumberTheory(op string, args ...Value) (Value, error) {
+	arity, ok := numberTheoryArity[op]
+	switch {
+	case op == TrialDivision:
+		arity, ok = 3, true
+	case op == FactorCall && len(args) == 2:
+		return completeFactors(args[0], args[1])
+	}
+	if !ok {
+		return nil, fmt.Errorf("неизвестная функция %q", op)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	exact := false
+	ints := make([]*big.Int, len(args))
+	for i, arg := range args {
+		if _, ok := arg.(Rat); ok {
+			exact = true
+		}
+		n, err := toBigInt(op, arg)
+		if err != nil {
+			return nil, err
+		}
+		ints[i] = n
+	}
+
+	switch op {
+	case FactorCall:
+		if ints[0].Sign() <= 0 {
+			return nil, fmt.Errorf("разложение на множители определено только для натуральных чисел")
+		}
+		primes, err := factorize(ints[0])
+		if err != nil {
+			return nil, err
+		}
+		return newFactors(primes), nil
+	case TrialDivision:
+		if ints[0].Sign() <= 0 || !ints[1].IsInt64() || !ints[2].IsInt64() {
+			return nil, fmt.Errorf("некорректный диапазон пробного деления")
+		}
+		primes, _ := trialDivision(ints[0], ints[1].Int64(), ints[2].Int64())
+		return newFactors(primes), nil
+	}
+
+	result, err := numberTheory(op, ints)
+	if err != nil {
+		return nil, err
+	}
+	if exact {
+		return Rat{new(big.Rat).SetInt(result)}, nil
+	}
+	return IntValue(result), nil
+}
+
+func numberTheory(op string, args []*big.Int) (*big.Int, error) {
+	result := new(big.Int)
+	switch op {
+	case FactorialCall:
+		n := args[0]
+		if n.Sign() < 0 {
+			return nil, fmt.Errorf("факториал определён только для неотрицательных целых чисел")
+		}
+		if n.Cmp(big.NewInt(maxFactorial)) > 0 {
+			return nil, fmt.Errorf("аргумент факториала %s слишком велик: допускается не больше %d", n, maxFactorial)
+		}
+		return result.MulRange(1, n.Int64()), nil
+	case "binom":
+		n, k := args[0], args[1]
+		if n.Sign() < 0 {
+			return nil, fmt.Errorf("биномиальный коэффициент определён только для неотрицательных n")
+		}
+		if k.Sign() < 0 || k.Cmp(n) > 0 {
+			return result, nil
+		}
+		smaller := new(big.Int).Sub(n, k)
+		if k.Cmp(smaller) < 0 {
+			smaller = k
+		}
+		if !n.IsInt64() || smaller.Cmp(big.NewInt(maxFactorial)) > 0 {
+			return nil, fmt.Errorf("аргументы binom(%s, %s) слишком велики", n, k)
+		}
+		return result.Binomial(n.Int64(), smaller.Int64()), nil
+	case "gcd":
+		return result.GCD(nil, nil, new(big.Int).Abs(args[0]), new(big.Int).Abs(args[1])), nil
+	case "lcm":
+		if args[0].Sign() == 0 || args[1].Sign() == 0 {
+			return result, nil
+		}
+		gcd := new(big.Int).GCD(nil, nil, new(big.Int).Abs(args[0]), new(big.Int).Abs(args[1]))
+		result.Mul(args[0], args[1])
+		return result.Abs(result.Quo(result, gcd)), nil
+	case "isprime":
+		return result.SetInt64(boolInt(args[0].Sign() > 0 && args[0].ProbablyPrime(20))), nil
+	case "modpow":
+		base, exponent, modulus := args[0], args[1], args[2]
+		if modulus.Sign() <= 0 {
+			return nil, fmt.Errorf("модуль в modpow должен быть положительным, получено %s", modulus)
+		}
+		if exponent.Sign() < 0 {
+			return nil, fmt.Errorf("показатель степени в modpow должен быть неотрицательным, получено %s", exponent)
+		}
+		return result.Exp(new(big.Int).Mod(base, modulus), exponent, modulus), nil
+	}
+	return nil, fmt.Errorf("неизвестная функция %q", op)
+}
+
+func TrialLimit(value Value) (int64, error) {
+	n, err := toBigInt(FactorCall, value)
+	if err != nil {
+		return 0, err
+	}
+	if n.Sign() <= 0 {
+		return 0, fmt.Errorf("разложение на множители определено только для натуральных чисел")
+	}
+	limit := new(big.Int).Sqrt(n)
+	if !limit.IsInt64() {
+		return math.MaxInt64, nil
+	}
+	return limit.Int64(), nil
+}
+
+func completeFactors(n, partial Value) (Value, error) {
+	found, ok := partial.(Factors)
+	if !ok {
+		return nil, fmt.Errorf("функция %s ожидает 1 аргумент(а), получено 2", FactorCall)
+	}
+	number, err := toBigInt(FactorCall, n)
+	if err != nil {
+		return nil, err
+	}
+	primes, err := found.primes()
+	if err != nil {
+		return nil, err
+	}
+	cofactor, remainder := new(big.Int).QuoRem(number, found.product(), new(big.Int))
+	if remainder.Sign() != 0 || cofactor.Sign() <= 0 {
+		return nil, fmt.Errorf("разложение %s не делит число %s", string(found), number)
+	}
+	rest, err := factorize(cofactor)
+	if err != nil {
+		return nil, err
+	}
+	return newFactors(append(primes, rest...)), nil
+}
+
+func trialDivision(n *big.Int, lo, hi int64) ([]*big.Int, *big.Int) {
+	if lo < 2 {
+		lo = 2
+	}
+	rest := new(big.Int).Set(n)
+	quotient, remainder, divisor := new(big.Int), new(big.Int), new(big.Int)
+	var primes []*big.Int
+	for d := lo; d <= hi && d > 0; d++ {
+		divisor.SetInt64(d)
+		if remainder.Mod(rest, divisor).Sign() != 0 || !divisor.ProbablyPrime(0) {
+			continue
+		}
+		for {
+			quotient.QuoRem(rest, divisor, remainder)
+			if remainder.Sign() != 0 {
+				break
+			}
+			rest.Set(quotient)
+			primes = append(primes, big.NewInt(d))
+		}
+	}
+	return primes, rest
+}
+
+func factorize(n *big.Int) ([]*big.Int, error) {
+	primes, rest := trialDivision(n, 2, smallPrimeBound)
+	pending := []*big.Int{rest}
+	for len(pending) > 0 {
+		m := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if m.Cmp(big.NewInt(1)) == 0 {
+			continue
+		}
+		if m.ProbablyPrime(20) {
+			primes = append(primes, m)
+			continue
+		}
+		d := pollardRho(m)
+		if d == nil {
+			return nil, fmt.Errorf("не удалось разложить число %s на множители", m)
+		}
+		pending = append(pending, d, new(big.Int).Quo(m, d))
+	}
+	return primes, nil
+}
+
+func pollardRho(n *big.Int) *big.Int {
+	one := big.NewInt(1)
+	for c := int64(1); c <= 10; c++ {
+		increment := big.NewInt(c)
+		step := func(x *big.Int) {
+			x.Mul(x, x)
+			x.Add(x, increment)
+			x.Mod(x, n)
+		}
+		x, y, d, diff := big.NewInt(2), big.NewInt(2), big.NewInt(1), new(big.Int)
+		for i := 0; d.Cmp(one) == 0 && i < maxRhoIterations; i++ {
+			step(x)
+			step(y)
+			step(y)
+			d.GCD(nil, nil, diff.Abs(diff.Sub(x, y)), n)
+		}
+		if d.Cmp(one) != 0 && d.Cmp(n) != 0 {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestEvaluate_NumberTheory(t *testing.T) {
+	tests := []struct {
+		expr     string
+		mode     string
+		expected string
+	}{
+		{"100!", "", "93326215443944152681699238856266700490715968264381621468592963895217599993229915608941463976156518286253697920827223758251185210916864000000000000000000000000"},
+		{"0! + 5!", "", "121"},
+		{"3!!", "", "720"},
+		{"-3!", "", "-6"},
+		{"2*3!^2", "", "72"},
+		{"100!/98!", "", "9900"},
+		{"25! % 1000007", "", "913534"},
+		{"20! + 1", "", "2432902008176640001"},
+		{"21! - 21!", "", "0"},
+		{"binom(50, 25)", "", "126410606437752"},
+		{"binom(5, 7)", "", "0"},
+		{"binom(200, 198)", "", "19900"},
+		{"gcd(-12, 18)", "", "6"},
+		{"lcm(4, -6)", "", "12"},
+		{"lcm(0, 6)", "", "0"},
+		{"isprime(97)", "", "1"},
+		{"isprime(2^61 - 1)", "", "1"},
+		{"isprime(1)", "", "0"},
+		{"modpow(2, 100, 1000000007)", "", "976371285"},
+		{"modpow(-2, 3, 5)", "", "2"},
+		{"factor(360)", "", "fac:2^3*3^2*5"},
+		{"factor(1)", "", "fac:1"},
+		{"factor(600851475143)", "", "fac:71*839*1471*6857"},
+		{"factor(1000000016000000063)", "", "fac:1000000007*1000000009"},
+		{"factor(18446744073709551617)", "", "fac:274177*67280421310721"},
+		{"factor(12)*factor(10)", "", "fac:2^3*3*5"},
+		{"factor(360) + 1", "", "361"},
+		{"30! > 1e30", "", "1"},
+		{"99999999999999999999 + 1", "", "100000000000000000000"},
+		{"5!", ModeExact, "rat:120"},
+		{"binom(10, 3)/7", ModeExact, "rat:120/7"},
+		{"factor(84)", ModeExact, "fac:2^2*3*7"},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		result, err := Evaluate(node, nil, tt.mode)
+		if err != nil {
+			t.Errorf("Evaluate(%s) failed: %v", tt.expr, err)
+			continue
+		}
+		if result.String() != tt.expected {
+			t.Errorf("Evaluate(%s) = %s, expected %s", tt.expr, result, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_NumberTheoryErrors(t *testing.T) {
+	for _, expr := range []string{
+		"(-1)!",
+		"2.5!",
+		"100001!",
+		"binom(-1, 2)",
+		"gcd(1.5, 3)",
+		"modpow(2, 3, 0)",
+		"modpow(2, -1, 5)",
+		"modpow(2, 3)",
+		"factor(0)",
+		"factor(1, 2)",
+		"isprime(1+i)",
+		"(2 m)!",
+	} {
+		node, err := parser.Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", expr, err)
+		}
+		if result, err := Evaluate(node, nil, ""); err == nil {
+			t.Errorf("Expected error for %s, got %v", expr, result)
+		}
+	}
+}
+
+func TestApplyBig(t *testing.T) {
+	huge, _ := new(big.Int).SetString("100000000000000000000", 10)
+	tests := []struct {
+		op       string
+		args     []*big.Int
+		expected string
+	}{
+		{"+", []*big.Int{huge, big.NewInt(-1)}, "99999999999999999999"},
+		{"-", []*big.Int{huge, huge}, "0"},
+		{"/", []*big.Int{huge, big.NewInt(8)}, "12500000000000000000"},
+		{"/", []*big.Int{huge, big.NewInt(3)}, "3.333333333333333e+19"},
+		{"//", []*big.Int{new(big.Int).Neg(huge), big.NewInt(3)}, "-33333333333333333334"},
+		{"%", []*big.Int{huge, big.NewInt(-7)}, "-5"},
+		{"^", []*big.Int{huge, big.NewInt(2)}, "10000000000000000000000000000000000000000"},
+		{"^", []*big.Int{huge, big.NewInt(-1)}, "1e-20"},
+		{"<<", []*big.Int{huge, big.NewInt(1)}, "200000000000000000000"},
+		{"min", []*big.Int{huge, big.NewInt(1)}, "1"},
+		{">", []*big.Int{huge, big.NewInt(1)}, "1"},
+		{"neg", []*big.Int{huge}, "-100000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		got, err := ApplyBig(tt.op, tt.args...)
+		if err != nil {
+			t.Errorf("ApplyBig(%q, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got.String() != tt.expected {
+			t.Errorf("ApplyBig(%q, %v) = %s, expected %s", tt.op, tt.args, got, tt.expected)
+		}
+	}
+
+	if _, err := ApplyBig("/", huge, big.NewInt(0)); err == nil {
+		t.Error("Expected division by zero error")
+	}
+	if f := (BigInt{new(big.Int).Lsh(huge, 2000)}).Float(); f != math.MaxFloat64 {
+		t.Errorf("Expected overflowing integer to be clamped to the largest float, got %v", f)
+	}
+}
+
+func TestFactors(t *testing.T) {
+	value, err := ParseValue("fac:2^2*3*5")
+	if err != nil {
+		t.Fatalf("ParseValue failed: %v", err)
+	}
+	if value != Factors("2^2*3*5") || value.Float() != 60 {
+		t.Errorf("Expected factors of 60, got %v (%v)", value, value.Float())
+	}
+	for _, input := range []string{"fac:", "fac:1*2", "fac:2^0", "fac:x", "fac:2^-1"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+
+	chunks := []Value{}
+	for _, bounds := range [][2]int64{{2, 10}, {11, 20}, {21, 30}} {
+		chunk, err := ApplyValue(TrialDivision, Int(2*2*7*13*29*31), Int(bounds[0]), Int(bounds[1]))
+		if err != nil {
+			t.Fatalf("Trial division failed: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if chunks[0] != Factors("2^2*7") || chunks[1] != Factors("13") || chunks[2] != Factors("29") {
+		t.Errorf("Unexpected trial division chunks %v", chunks)
+	}
+
+	found, err := Reduce(chunks, func(left, right Value) (Value, error) {
+		return ApplyValue("*", left, right)
+	})
+	if err != nil {
+		t.Fatalf("Merging chunks failed: %v", err)
+	}
+	complete, err := ApplyValue(FactorCall, Int(2*2*7*13*29*31), found)
+	if err != nil || complete != Factors("2^2*7*13*29*31") {
+		t.Errorf("Expected complete factorization, got %v, %v", complete, err)
+	}
+	if _, err := ApplyValue(FactorCall, Int(2*2*7), Factors("3")); err == nil {
+		t.Error("Expected error for partial factorization that does not divide the number")
+	}
+
+	integer, err := ParseValue("18446744073709551617")
+	if err != nil || integer.String() != "18446744073709551617" {
+		t.Errorf("Expected digits of a big integer to round-trip, got %v, %v", integer, err)
+	}
+}
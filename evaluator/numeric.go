@@ -0,0 +1,317 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+)
+
+const (
+	Integrate = "integrate"
+	Solve     = "solve"
+
+	NumericTolerance    = 1e-10
+	IntegrationSegments = 16
+
+	defaultNumericVariable = "x"
+	maxQuadratureDepth     = 40
+	maxQuadratureEvals     = 1000000
+	maxSolveIterations     = 200
+)
+
+type Estimate struct {
+	Value      float64
+	Error      float64
+	Iterations int
+}
+
+type NumericCall struct {
+	Name     string
+	Variable string
+	Body     parser.Node
+	From     parser.Node
+	To       parser.Node
+}
+
+func IsNumeric(name string) bool {
+	return name == Integrate || name == Solve
+}
+
+func ParseNumeric(call *parser.Call) (*NumericCall, error) {
+	nc := &NumericCall{Name: call.Name, Variable: defaultNumericVariable}
+	switch len(call.Args) {
+	case 3:
+		nc.Body, nc.From, nc.To = call.Args[0], call.Args[1], call.Args[2]
+	case 4:
+		variable, ok := call.Args[1].(*parser.Ident)
+		if !ok {
+			return nil, fmt.Errorf("второй аргумент %s должен быть именем переменной", call.Name)
+		}
+		nc.Variable = variable.Name
+		nc.Body, nc.From, nc.To = call.Args[0], call.Args[2], call.Args[3]
+	default:
+		return nil, fmt.Errorf("функция %s ожидает 3 или 4 аргумента, получено %d", call.Name, len(call.Args))
+	}
+	if IsReserved(nc.Variable) {
+		return nil, fmt.Errorf("имя %q зарезервировано", nc.Variable)
+	}
+	return nc, nil
+}
+
+func (nc *NumericCall) Bounds(scope map[string]Value, mode string, budget *Budget) (float64, float64, error) {
+	if mode == ModeExact {
+		return 0, 0, fmt.Errorf("функция %s недоступна в точном режиме", nc.Name)
+	}
+	bounds := make([]float64, 2)
+	for i, node := range []parser.Node{nc.From, nc.To} {
+		value, err := evaluate(node, scope, mode, budget)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch value.(type) {
+		case Interval:
+			return 0, 0, fmt.Errorf("границы %s не могут быть интервалами", nc.Name)
+		case Complex:
+			return 0, 0, fmt.Errorf("границы %s должны быть действительными числами", nc.Name)
+		case Quantity:
+			return 0, 0, fmt.Errorf("границы %s должны быть безразмерными числами", nc.Name)
+		}
+		bounds[i] = value.Float()
+		if math.IsInf(bounds[i], 0) || math.IsNaN(bounds[i]) {
+			return 0, 0, fmt.Errorf("границы %s должны быть конечными числами", nc.Name)
+		}
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func Segments(from, to float64, n int) []float64 {
+	points := make([]float64, n+1)
+	for i := range points {
+		points[i] = from + (to-from)*float64(i)/float64(n)
+	}
+	points[n] = to
+	return points
+}
+
+func EstimateNumeric(name, variable string, from, to float64, body parser.Node, scope map[string]Value) (Estimate, error) {
+	return estimateNumeric(name, variable, from, to, body, scope, nil)
+}
+
+func estimateNumeric(name, variable string, from, to float64, body parser.Node, scope map[string]Value, budget *Budget) (Estimate, error) {
+	local := make(map[string]Value, len(scope)+1)
+	for name, value := range scope {
+		local[name] = value
+	}
+	f := func(t float64) (float64, error) {
+		local[variable] = Float(t)
+		value, err := evaluate(body, local, "", budget)
+		if err != nil {
+			return 0, err
+		}
+		switch value.(type) {
+		case Interval:
+			return 0, fmt.Errorf("функция %s не поддерживает интервалы", name)
+		case Complex:
+			return 0, fmt.Errorf("функция %s не поддерживает комплексные значения", name)
+		case Quantity:
+			return 0, fmt.Errorf("функция %s не поддерживает величины с единицами измерения", name)
+		}
+		result := value.Float()
+		if math.IsInf(result, 0) || math.IsNaN(result) {
+			return 0, fmt.Errorf("функция не определена в точке %s", FormatNumber(t))
+		}
+		return result, nil
+	}
+
+	switch name {
+	case Integrate:
+		return AdaptiveSimpson(f, from, to, NumericTolerance/IntegrationSegments, NumericTolerance)
+	case Solve:
+		return Brent(f, from, to, NumericTolerance)
+	}
+	return Estimate{}, fmt.Errorf("неизвестная численная функция %q", name)
+}
+
+func AdaptiveSimpson(f func(float64) (float64, error), a, b, absTol, relTol float64) (Estimate, error) {
+	q := &quadrature{f: f}
+	fa, err := q.eval(a)
+	if err != nil {
+		return Estimate{}, err
+	}
+	fb, err := q.eval(b)
+	if err != nil {
+		return Estimate{}, err
+	}
+	m := (a + b) / 2
+	fm, err := q.eval(m)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	scale := math.Abs(b-a) / 6 * (math.Abs(fa) + 4*math.Abs(fm) + math.Abs(fb))
+	tol := math.Max(absTol, relTol*scale)
+	value, estimate, err := q.simpson(a, b, fa, fm, fb, (b-a)/6*(fa+4*fm+fb), tol, maxQuadratureDepth)
+	if err != nil {
+		return Estimate{}, err
+	}
+	return Estimate{Value: value, Error: estimate, Iterations: q.evals}, nil
+}
+
+type quadrature struct {
+	f     func(float64) (float64, error)
+	evals int
+}
+
+func (q *quadrature) eval(x float64) (float64, error) {
+	if q.evals >= maxQuadratureEvals {
+		return 0, fmt.Errorf("интеграл не сходится: более %d вычислений функции", maxQuadratureEvals)
+	}
+	q.evals++
+	return q.f(x)
+}
+
+func (q *quadrature) simpson(a, b, fa, fm, fb, whole, tol float64, depth int) (float64, float64, error) {
+	m := (a + b) / 2
+	flm, err := q.eval((a + m) / 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	frm, err := q.eval((m + b) / 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	delta := left + right - whole
+	if depth <= 0 || math.Abs(delta) <= 15*tol {
+		return left + right + delta/15, math.Abs(delta) / 15, nil
+	}
+
+	leftValue, leftError, err := q.simpson(a, m, fa, flm, fm, left, tol/2, depth-1)
+	if err != nil {
+		return 0, 0, err
+	}
+	rightValue, rightError, err := q.simpson(m, b, fm, frm, fb, right, tol/2, depth-1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return leftValue + rightValue, leftError + rightError, nil
+}
+
+func Brent(f func(float64) (float64, error), a, b, tol float64) (Estimate, error) {
+	fa, err := f(a)
+	if err != nil {
+		return Estimate{}, err
+	}
+	fb, err := f(b)
+	if err != nil {
+		return Estimate{}, err
+	}
+	if fa == 0 {
+		return Estimate{Value: a}, nil
+	}
+	if fb == 0 {
+		return Estimate{Value: b}, nil
+	}
+	if (fa > 0) == (fb > 0) {
+		return Estimate{}, fmt.Errorf("функция не меняет знак на [%s, %s]", FormatNumber(a), FormatNumber(b))
+	}
+
+	c, fc := a, fa
+	d := b - a
+	e := d
+	for i := 1; i <= maxSolveIterations; i++ {
+		if (fb > 0) == (fc > 0) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+
+		margin := 2*math.SmallestNonzeroFloat64 + tol/2 + 2*1e-16*math.Abs(b)
+		half := (c - b) / 2
+		if math.Abs(half) <= margin || fb == 0 {
+			return Estimate{Value: b, Error: math.Abs(half), Iterations: i}, nil
+		}
+
+		if math.Abs(e) >= margin && math.Abs(fa) > math.Abs(fb) {
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				p = 2 * half * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*half*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < math.Min(3*half*q-math.Abs(margin*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				d = half
+				e = d
+			}
+		} else {
+			d = half
+			e = d
+		}
+
+		a, fa = b, fb
+		if math.Abs(d) > margin {
+			b += d
+		} else if half > 0 {
+			b += margin
+		} else {
+			b -= margin
+		}
+		if fb, err = f(b); err != nil {
+			return Estimate{}, err
+		}
+	}
+	return Estimate{}, fmt.Errorf("решение не найдено за %d итераций", maxSolveIterations)
+}
+
+func evaluateNumeric(call *parser.Call, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	nc, err := ParseNumeric(call)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := nc.Bounds(scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	if nc.Name == Solve {
+		estimate, err := estimateNumeric(nc.Name, nc.Variable, from, to, nc.Body, scope, budget)
+		if err != nil {
+			return nil, err
+		}
+		return Float(estimate.Value), nil
+	}
+
+	points := Segments(from, to, IntegrationSegments)
+	values := make([]float64, IntegrationSegments)
+	for i := range values {
+		estimate, err := estimateNumeric(nc.Name, nc.Variable, points[i], points[i+1], nc.Body, scope, budget)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = estimate.Value
+	}
+	total, err := Reduce(values, func(left, right float64) (float64, error) {
+		return Apply("+", left, right)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Float(total), nil
+}
@@ -0,0 +1,163 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"math"
+	"testing"
+)
+
+func TestParseNumeric(t *testing.T) {
+	tests := []struct {
+		expr     string
+		variable string
+		body     string
+	}{
+		{"integrate(x^2, 0, 1)", "x", "x^2"},
+		{"integrate(t*a, t, 0, 1)", "t", "t*a"},
+		{"solve(y^2 - 2, y, 0, 2)", "y", "y^2 - 2"},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		nc, err := ParseNumeric(node.(*parser.Call))
+		if err != nil {
+			t.Fatalf("ParseNumeric(%s) failed: %v", tt.expr, err)
+		}
+		if nc.Variable != tt.variable || parser.Format(nc.Body) != tt.body {
+			t.Errorf("ParseNumeric(%s) = %s, %s, expected %s, %s", tt.expr, nc.Variable, parser.Format(nc.Body), tt.variable, tt.body)
+		}
+	}
+}
+
+func TestEvaluate_Numeric(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected float64
+	}{
+		{"integrate(x^2, 0, 1)", 1.0 / 3},
+		{"integrate(sin(x), 0, pi)", 2},
+		{"integrate(t*a, t, 0, 2)", 6},
+		{"integrate(x, 1, 0)", -0.5},
+		{"integrate(exp(-x^2), -10, 10)^2", math.Pi},
+		{"solve(x^2 - 2, x, 0, 2)", math.Sqrt2},
+		{"solve(cos(x) - x, 0, 1)", 0.7390851332151607},
+		{"solve(x - a, x, 0, 10)", 3},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err := Evaluate(node, map[string]Value{"a": Int(3)}, "")
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+		}
+		if math.Abs(result.Float()-tt.expected) > 1e-9 {
+			t.Errorf("Evaluate(%s) = %v, expected %v", tt.expr, result.Float(), tt.expected)
+		}
+	}
+}
+
+func TestEstimateNumeric_RelativeTolerance(t *testing.T) {
+	tests := []struct {
+		body     string
+		from, to float64
+		expected float64
+	}{
+		{"exp(x)", 0, 20, math.Exp(20) - 1},
+		{"sin(x)*exp(x)", 0, 50, math.Exp(50)*(math.Sin(50)-math.Cos(50))/2 + 0.5},
+		{"1e-12*x", 0, 1, 5e-13},
+	}
+
+	for _, tt := range tests {
+		body, err := parser.Parse(tt.body)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		points := Segments(tt.from, tt.to, IntegrationSegments)
+		total, evaluations := 0.0, 0
+		for i := 0; i < IntegrationSegments; i++ {
+			estimate, err := EstimateNumeric(Integrate, "x", points[i], points[i+1], body, nil)
+			if err != nil {
+				t.Fatalf("EstimateNumeric(%s) failed: %v", tt.body, err)
+			}
+			total += estimate.Value
+			evaluations += estimate.Iterations
+		}
+		if math.Abs(total-tt.expected) > 1e-9*math.Max(1, math.Abs(tt.expected)) {
+			t.Errorf("integrate(%s, %v, %v) = %v, expected %v", tt.body, tt.from, tt.to, total, tt.expected)
+		}
+		if evaluations > 20000 {
+			t.Errorf("Expected integrate(%s, %v, %v) to converge quickly, took %d evaluations", tt.body, tt.from, tt.to, evaluations)
+		}
+	}
+}
+
+func TestEvaluate_NumericErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		mode string
+	}{
+		{"integrate(x^2, 0)", ""},
+		{"integrate(x^2, 2, 0, 1)", ""},
+		{"integrate(pi, pi, 0, 1)", ""},
+		{"integrate(1/x, 0, 1)", ""},
+		{"integrate(x, 0, 1/0)", ""},
+		{"integrate(x, 0, 1)", ModeExact},
+		{"solve(x^2 + 1, x, -1, 1)", ""},
+		{"solve(x - y, x, 0, 1)", ""},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := Evaluate(node, nil, tt.mode); err == nil {
+			t.Errorf("Expected error for %s", tt.expr)
+		}
+	}
+}
+
+func TestEstimateNumeric(t *testing.T) {
+	body, err := parser.Parse("x^4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	estimate, err := EstimateNumeric(Integrate, "x", 0, 1, body, nil)
+	if err != nil {
+		t.Fatalf("EstimateNumeric failed: %v", err)
+	}
+	if math.Abs(estimate.Value-0.2) > 1e-10 {
+		t.Errorf("Expected 0.2, got %v", estimate.Value)
+	}
+	if estimate.Error > NumericTolerance || estimate.Iterations < 5 {
+		t.Errorf("Expected error estimate below tolerance and evaluation count, got %+v", estimate)
+	}
+
+	root, err := EstimateNumeric(Solve, "x", 0, 1, &parser.BinaryOp{Op: "-", Left: body, Right: &parser.Number{Value: 0.0625, Literal: "0.0625"}}, nil)
+	if err != nil {
+		t.Fatalf("EstimateNumeric failed: %v", err)
+	}
+	if math.Abs(root.Value-0.5) > 1e-9 || root.Iterations == 0 {
+		t.Errorf("Expected root 0.5 with iterations, got %+v", root)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	points := Segments(0, 1, 4)
+	expected := []float64{0, 0.25, 0.5, 0.75, 1}
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for i := range expected {
+		if points[i] != expected[i] {
+			t.Errorf("Expected point %d to be %v, got %v", i, expected[i], points[i])
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+)
+
+var operationArity = map[string]int{
+	"+":   2,
+	"-":   2,
+	"*":   2,
+	"/":   2,
+	"^":   2,
+	"//":  2,
+	"%":   2,
+	"&":   2,
+	"|":   2,
+	"xor": 2,
+	"<<":  2,
+	">>":  2,
+	"<":   2,
+	"<=":  2,
+	"==":  2,
+	"!=":  2,
+	">=":  2,
+	">":   2,
+	"min": 2,
+	"max": 2,
+	"neg": 1,
+	"not": 1,
+}
+
+var commutative = map[string]bool{
+	"+":   true,
+	"*":   true,
+	"&":   true,
+	"|":   true,
+	"xor": true,
+	"==":  true,
+	"!=":  true,
+	"min": true,
+	"max": true,
+}
+
+var functions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"ln":    math.Log,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"re":    func(x float64) float64 { return x },
+	"im":    func(x float64) float64 { return 0 },
+	"conj":  func(x float64) float64 { return x },
+}
+
+func IsCommutative(op string) bool {
+	return commutative[op]
+}
+
+func IsFunction(name string) bool {
+	_, ok := functions[name]
+	return ok
+}
+
+func Apply(op string, args ...float64) (float64, error) {
+	if fn, ok := functions[op]; ok {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", op, len(args))
+		}
+		result := fn(args[0])
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return 0, fmt.Errorf("функция %s не определена для аргумента %v", op, args[0])
+		}
+		return result, nil
+	}
+
+	if arity, ok := operationArity[op]; !ok {
+		return 0, fmt.Errorf("неизвестная операция %q", op)
+	} else if len(args) != arity {
+		return 0, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	if IsIntegerOperation(op) {
+		a, err := toInt64(op, Float(args[0]))
+		if err != nil {
+			return 0, err
+		}
+		b, err := toInt64(op, Float(args[1]))
+		if err != nil {
+			return 0, err
+		}
+		result, err := ApplyInt(op, a, b)
+		if err != nil {
+			return 0, err
+		}
+		return finite(op, result.Float(), args)
+	}
+
+	if IsComparison(op) {
+		return float64(compare(op, compareFloat(args[0], args[1]))), nil
+	}
+
+	switch op {
+	case "+":
+		return finite(op, args[0]+args[1], args)
+	case "-":
+		return finite(op, args[0]-args[1], args)
+	case "*":
+		return finite(op, args[0]*args[1], args)
+	case "/":
+		if args[1] == 0 {
+			return 0, fmt.Errorf("деление на ноль")
+		}
+		return finite(op, args[0]/args[1], args)
+	case "//":
+		if args[1] == 0 {
+			return 0, fmt.Errorf("деление на ноль")
+		}
+		return finite(op, math.Floor(args[0]/args[1]), args)
+	case "%":
+		if args[1] == 0 {
+			return 0, fmt.Errorf("деление на ноль")
+		}
+		r := math.Mod(args[0], args[1])
+		if r != 0 && (r < 0) != (args[1] < 0) {
+			r += args[1]
+		}
+		return r, nil
+	case "^":
+		result := math.Pow(args[0], args[1])
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return 0, fmt.Errorf("возведение %v в степень %v не определено", args[0], args[1])
+		}
+		return result, nil
+	case "min":
+		return math.Min(args[0], args[1]), nil
+	case "max":
+		return math.Max(args[0], args[1]), nil
+	case "neg":
+		return -args[0], nil
+	case "not":
+		return float64(boolInt(args[0] == 0)), nil
+	}
+	return 0, fmt.Errorf("неизвестная операция %q", op)
+}
+
+func finite(op string, result float64, args []float64) (float64, error) {
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("переполнение: результат %v %s %v выходит за пределы float64", args[0], op, args[1])
+	}
+	return result, nil
+}
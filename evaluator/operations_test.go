@@ -0,0 +1,126 @@
+package evaluator
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		op       string
+		args     []float64
+		expected float64
+	}{
+		{"+", []float64{2, 3}, 5},
+		{"-", []float64{2, 3}, -1},
+		{"*", []float64{2, 3}, 6},
+		{"/", []float64{3, 2}, 1.5},
+		{"^", []float64{2, 10}, 1024},
+		{"^", []float64{4, 0.5}, 2},
+		{"^", []float64{2, -1}, 0.5},
+		{"min", []float64{2, -3}, -3},
+		{"max", []float64{2, -3}, 2},
+		{"neg", []float64{4}, -4},
+		{"neg", []float64{-4}, 4},
+		{"sqrt", []float64{16}, 4},
+		{"abs", []float64{-2.5}, 2.5},
+		{"sin", []float64{0}, 0},
+		{"cos", []float64{0}, 1},
+		{"tan", []float64{0}, 0},
+		{"ln", []float64{1}, 0},
+		{"log10", []float64{1000}, 3},
+		{"exp", []float64{0}, 1},
+		{"floor", []float64{-1.5}, -2},
+		{"ceil", []float64{1.2}, 2},
+		{"round", []float64{2.5}, 3},
+		{"//", []float64{7, 2}, 3},
+		{"//", []float64{-7, 2}, -4},
+		{"//", []float64{7.5, 2}, 3},
+		{"%", []float64{-7, 3}, 2},
+		{"%", []float64{7, -3}, -2},
+		{"%", []float64{7.5, 2}, 1.5},
+		{"&", []float64{12, 10}, 8},
+		{"|", []float64{12, 10}, 14},
+		{"xor", []float64{12, 10}, 6},
+		{"<<", []float64{1, 10}, 1024},
+		{"<<", []float64{1, 64}, 18446744073709551616},
+		{">>", []float64{-16, 2}, -4},
+		{"<", []float64{1, 2}, 1},
+		{"<", []float64{2, 2}, 0},
+		{"<=", []float64{2, 2}, 1},
+		{"==", []float64{0.5, 0.5}, 1},
+		{"!=", []float64{0.5, 0.5}, 0},
+		{">=", []float64{1, 2}, 0},
+		{">", []float64{2.5, 2}, 1},
+		{"not", []float64{0}, 1},
+		{"not", []float64{-3}, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := Apply(tt.op, tt.args...)
+		if err != nil {
+			t.Errorf("Apply(%q, %v) unexpected error: %v", tt.op, tt.args, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Apply(%q, %v) = %v, expected %v", tt.op, tt.args, got, tt.expected)
+		}
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		op   string
+		args []float64
+	}{
+		{"/", []float64{1, 0}},
+		{"?", []float64{1, 2}},
+		{"//", []float64{1, 0}},
+		{"%", []float64{1, 0}},
+		{"&", []float64{2.5, 1}},
+		{"<<", []float64{1, -1}},
+		{">>", []float64{1, -1}},
+		{"<", []float64{1}},
+		{"not", []float64{1, 2}},
+		{"^", []float64{-8, 0.5}},
+		{"^", []float64{0, -1}},
+		{"+", []float64{1}},
+		{"neg", []float64{1, 2}},
+		{"sqrt", []float64{-1}},
+		{"ln", []float64{0}},
+		{"log10", []float64{-10}},
+		{"abs", []float64{1, 2}},
+		{"sqrt", nil},
+		{"+", []float64{1e308, 1e308}},
+		{"-", []float64{-1e308, 1e308}},
+		{"*", []float64{1e308, 10}},
+		{"/", []float64{1e308, 1e-10}},
+		{"//", []float64{1e308, 0.1}},
+		{"^", []float64{10, 400}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Apply(tt.op, tt.args...); err == nil {
+			t.Errorf("Apply(%q, %v) expected error", tt.op, tt.args)
+		}
+	}
+}
+
+func TestIsFunction(t *testing.T) {
+	if !IsFunction("sqrt") || !IsFunction("log10") {
+		t.Error("Expected sqrt and log10 to be functions")
+	}
+	if IsFunction("+") || IsFunction("foo") {
+		t.Error("Expected + and foo not to be functions")
+	}
+}
+
+func TestIsCommutative(t *testing.T) {
+	for _, op := range []string{"+", "*", "&", "|", "xor", "==", "!=", "min", "max"} {
+		if !IsCommutative(op) {
+			t.Errorf("Expected %s to be commutative", op)
+		}
+	}
+	for _, op := range []string{"-", "/", "^", "//", "%", "<<", "<", "sqrt"} {
+		if IsCommutative(op) {
+			t.Errorf("Expected %s not to be commutative", op)
+		}
+	}
+}
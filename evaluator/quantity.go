@@ -0,0 +1,208 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"calculator/units"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	UnitCall    = parser.UnitCall
+	ConvertCall = parser.ConvertCall
+)
+
+const quantityPrefix = "q:"
+
+type Quantity struct {
+	SI   float64
+	Unit units.Unit
+}
+
+func (q Quantity) Float() float64 {
+	return q.SI / q.Unit.Scale
+}
+
+func (q Quantity) String() string {
+	return quantityPrefix + FormatNumber(q.SI) + "," + FormatNumber(q.Unit.Scale) + "," + q.Unit.Dim.String() + "," + q.Unit.Name
+}
+
+func UnitValue(name string) (Value, error) {
+	unit, err := units.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	return Quantity{SI: unit.Scale, Unit: unit}, nil
+}
+
+func QuantityOperation(name string) string {
+	if name == UnitCall {
+		return "*"
+	}
+	return ConvertCall
+}
+
+func evaluateQuantity(n *parser.Call, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	unit, ok := n.Args[1].(*parser.Unit)
+	if !ok {
+		return nil, fmt.Errorf("функция %s ожидает единицу измерения вторым аргументом", n.Name)
+	}
+	value, err := evaluate(n.Args[0], scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	target, err := UnitValue(unit.Name)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyValue(QuantityOperation(n.Name), value, target)
+}
+
+func parseQuantity(s string) (Value, error) {
+	parts := strings.Split(strings.TrimPrefix(s, quantityPrefix), ",")
+	if len(parts) != 4 || parts[3] == "" {
+		return nil, fmt.Errorf("некорректная величина %q", s)
+	}
+	si, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректная величина %q", s)
+	}
+	scale, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || scale <= 0 {
+		return nil, fmt.Errorf("некорректная величина %q", s)
+	}
+	dim, err := units.ParseDimension(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("некорректная величина %q", s)
+	}
+	return Quantity{SI: si, Unit: units.Unit{Name: parts[3], Scale: scale, Dim: dim}}, nil
+}
+
+func hasQuantity(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Quantity); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyQuantity(op string, args ...Value) (Value, error) {
+	qs := make([]Quantity, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case Quantity:
+			qs[i] = v
+		case Rat:
+			return nil, fmt.Errorf("единицы измерения недоступны в точном режиме")
+		case Interval:
+			return nil, fmt.Errorf("интервалы не поддерживают единицы измерения")
+		case Complex:
+			return nil, fmt.Errorf("комплексные числа не поддерживают единицы измерения")
+		default:
+			qs[i] = Quantity{SI: arg.Float(), Unit: units.Unit{Scale: 1}}
+		}
+	}
+
+	if op == ConvertCall {
+		if len(qs) != 2 {
+			return nil, fmt.Errorf("операция %q ожидает 2 аргумента, получено %d", op, len(qs))
+		}
+		if qs[0].Unit.Dim != qs[1].Unit.Dim {
+			return nil, units.Mismatch(op, qs[0].Unit, qs[1].Unit)
+		}
+		return quantityValue(op, qs[0].SI, qs[1].Unit)
+	}
+
+	switch op {
+	case "abs", "floor", "ceil", "round", "neg":
+		if len(qs) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", op, len(qs))
+		}
+		result, err := Apply(op, qs[0].Float())
+		if err != nil {
+			return nil, err
+		}
+		return quantityValue(op, result*qs[0].Unit.Scale, qs[0].Unit)
+	case "sqrt":
+		if len(qs) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", op, len(qs))
+		}
+		return quantityPower(op, qs[0], 0.5)
+	case "not":
+		if len(qs) != 1 {
+			return nil, fmt.Errorf("операция %q ожидает 1 аргумент(а), получено %d", op, len(qs))
+		}
+		return Int(boolInt(qs[0].SI == 0)), nil
+	}
+	if _, ok := functions[op]; ok {
+		return nil, units.NotDimensionless(op, qs[0].Unit)
+	}
+	if arity, ok := operationArity[op]; !ok {
+		return nil, fmt.Errorf("неизвестная операция %q", op)
+	} else if len(qs) != arity {
+		return nil, fmt.Errorf("операция %q ожидает %d аргумент(а), получено %d", op, arity, len(qs))
+	}
+
+	a, b := qs[0], qs[1]
+	switch op {
+	case "*":
+		return quantityValue(op, a.SI*b.SI, a.Unit.Mul(b.Unit))
+	case "/":
+		if b.SI == 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		return quantityValue(op, a.SI/b.SI, a.Unit.Div(b.Unit))
+	case "^":
+		if !b.Unit.Dimensionless() {
+			return nil, units.NotDimensionless(op, b.Unit)
+		}
+		return quantityPower(op, a, b.SI)
+	}
+
+	if a.Unit.Dim != b.Unit.Dim {
+		return nil, units.Mismatch(op, a.Unit, b.Unit)
+	}
+	unit := a.Unit
+	if unit.Dimensionless() {
+		unit = b.Unit
+	}
+	switch op {
+	case "+":
+		return quantityValue(op, a.SI+b.SI, unit)
+	case "-":
+		return quantityValue(op, a.SI-b.SI, unit)
+	case "min":
+		return quantityValue(op, math.Min(a.SI, b.SI), unit)
+	case "max":
+		return quantityValue(op, math.Max(a.SI, b.SI), unit)
+	}
+	if IsComparison(op) {
+		return Int(compare(op, compareFloat(a.SI, b.SI))), nil
+	}
+	return nil, fmt.Errorf("операция %q не определена для величин с единицами измерения", op)
+}
+
+func quantityPower(op string, q Quantity, p float64) (Value, error) {
+	unit, err := q.Unit.Pow(p)
+	if err != nil {
+		return nil, err
+	}
+	result := math.Pow(q.SI, p)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil, fmt.Errorf("возведение %v в степень %v не определено", q.SI, p)
+	}
+	return quantityValue(op, result, unit)
+}
+
+func quantityValue(op string, si float64, unit units.Unit) (Value, error) {
+	if math.IsNaN(si) || math.IsInf(si, 0) {
+		return nil, fmt.Errorf("операция %q над величинами не определена", op)
+	}
+	if unit.Dimensionless() {
+		return Float(si), nil
+	}
+	return Quantity{SI: si, Unit: unit}, nil
+}
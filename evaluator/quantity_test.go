@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"calculator/units"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEvaluate_Quantity(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected float64
+		unit     string
+	}{
+		{"3 m * 2 s^-1 + 4 km/h", 6 + 4000.0/3600, "m/s"},
+		{"3 m * 2 s^-1 + 4 km/h to km/h", 25.6, "km/h"},
+		{"60 mph to km/h", 96.56064, "km/h"},
+		{"1 km + 500 m", 1.5, "km"},
+		{"2 * 3 kg", 6, "kg"},
+		{"10 m / 2 s", 5, "m/s"},
+		{"1 / 4 s", 0.25, "s^-1"},
+		{"(3 m)^2", 9, "m^2"},
+		{"sqrt(16 m^2)", 4, "m"},
+		{"abs(-2 N) to kg*m/s^2", 2, "kg*m/s^2"},
+		{"round(2.6 h)", 3, "h"},
+		{"max(1 km, 900 m)", 1, "km"},
+		{"-(5 min) to s", -300, "s"},
+		{"1 kWh to J", 3.6e6, "J"},
+		{"x to cm", 250, "cm"},
+		{"sum(k, 1, 3, k*1 m)", 6, "m"},
+		{"10 km / 5 m", 2000, ""},
+		{"1 h / 30 min", 2, ""},
+		{"2 m > 150 cm", 1, ""},
+		{"if(2 m < 1 ft, 1, 2)", 2, ""},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		result, err := Evaluate(node, map[string]Value{"x": Quantity{SI: 2.5, Unit: units.Unit{Name: "m", Scale: 1, Dim: units.Dimension{1}}}}, "")
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+		}
+		unit := ""
+		if q, ok := result.(Quantity); ok {
+			unit = q.Unit.Name
+		}
+		if math.Abs(result.Float()-tt.expected) > 1e-9*math.Max(1, math.Abs(tt.expected)) || unit != tt.unit {
+			t.Errorf("Evaluate(%s) = %v %s, expected %v %s", tt.expr, result.Float(), unit, tt.expected, tt.unit)
+		}
+	}
+}
+
+func TestEvaluate_QuantityErrors(t *testing.T) {
+	tests := []struct {
+		expr      string
+		mode      string
+		dimension bool
+	}{
+		{"3 m + 2 kg", "", true},
+		{"3 m < 2 s", "", true},
+		{"3 m + 2", "", true},
+		{"5 km/h to m", "", true},
+		{"5 to m", "", true},
+		{"sin(2 m)", "", true},
+		{"2^(1 m)", "", true},
+		{"min(1 m, 1 kg)", "", true},
+		{"sqrt(2 m)", "", false},
+		{"(2 m)^0.5", "", false},
+		{"1 m / (0 s)", "", false},
+		{"3 m % 2 m", "", false},
+		{"[1, 2]*1 m", "", false},
+		{"2 m * i", "", false},
+		{"sum(k, 1, 2 m, k)", "", false},
+		{"integrate(x*1 m, 0, 1)", "", false},
+		{"integrate(x, 0, 1 m)", "", false},
+		{"3 m", ModeExact, false},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		result, err := Evaluate(node, nil, tt.mode)
+		if err == nil {
+			t.Errorf("Expected error for %s, got %v", tt.expr, result)
+			continue
+		}
+		var dimErr *units.DimensionError
+		if errors.As(err, &dimErr) != tt.dimension {
+			t.Errorf("Evaluate(%s) error %v, expected dimension error: %v", tt.expr, err, tt.dimension)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	value, err := UnitValue("km/h")
+	if err != nil {
+		t.Fatalf("UnitValue failed: %v", err)
+	}
+	q, err := ApplyValue("*", Float(36), value)
+	if err != nil {
+		t.Fatalf("ApplyValue failed: %v", err)
+	}
+	if q.String() != "q:10,0.2777777777777778,m/s,km/h" || q.Float() != 36 {
+		t.Errorf("Unexpected quantity %s", q)
+	}
+
+	parsed, err := ParseValue(q.String())
+	if err != nil {
+		t.Fatalf("ParseValue(%s) failed: %v", q, err)
+	}
+	if parsed != q {
+		t.Errorf("Expected %v, got %v", q, parsed)
+	}
+
+	for _, input := range []string{"q:", "q:1,1,m", "q:a,1,m,m", "q:1,0,m,m", "q:1,1,km,km", "q:1,1,m,"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+
+	node, err := ValueNode(q)
+	if err != nil || parser.Format(node) != "36.0 km/h" {
+		t.Errorf("Expected quantity node 36.0 km/h, got %v, %v", node, err)
+	}
+}
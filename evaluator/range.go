@@ -0,0 +1,324 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	RangeSum     = "sum"
+	RangeProduct = "prod"
+
+	MaxRangeIterations = 10000000
+)
+
+var rangeIdentity = map[string]string{
+	RangeSum:     "0",
+	RangeProduct: "1",
+}
+
+func RangeIndex(call *parser.Call) (string, bool) {
+	if _, ok := rangeIdentity[call.Name]; !ok || len(call.Args) != 4 {
+		return "", false
+	}
+	index, ok := call.Args[0].(*parser.Ident)
+	if !ok {
+		return "", false
+	}
+	return index.Name, true
+}
+
+func RangeBounds(call *parser.Call, scope map[string]Value, mode string, budget *Budget) (int64, int64, error) {
+	index, _ := RangeIndex(call)
+	if IsReserved(index) {
+		return 0, 0, fmt.Errorf("имя %q зарезервировано", index)
+	}
+	if _, bound := scope[index]; bound {
+		return 0, 0, fmt.Errorf("неоднозначный вызов %s: имя индекса %q уже определено, переименуйте индекс диапазона", call.Name, index)
+	}
+
+	bounds := make([]int64, 2)
+	for i, arg := range call.Args[1:3] {
+		value, err := evaluate(arg, scope, mode, budget)
+		if err != nil {
+			return 0, 0, err
+		}
+		if bounds[i], err = RangeBound(value); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	from, to := bounds[0], bounds[1]
+	if to >= from && (to-from >= MaxRangeIterations || to-from < 0) {
+		return 0, 0, fmt.Errorf("слишком много итераций в %s: максимум %d", call.Name, MaxRangeIterations)
+	}
+	return from, to, nil
+}
+
+func RangeBound(value Value) (int64, error) {
+	switch v := value.(type) {
+	case Int:
+		return int64(v), nil
+	case Rat:
+		if v.IsInt() && v.Num().IsInt64() {
+			return v.Num().Int64(), nil
+		}
+	case Float:
+		if f := float64(v); f == math.Trunc(f) && math.Abs(f) < 1<<62 {
+			return int64(f), nil
+		}
+	}
+	return 0, fmt.Errorf("границы диапазона должны быть целыми числами, получено %s", value.String())
+}
+
+func IndexValue(i int64, mode string) Value {
+	if mode == ModeExact {
+		return Rat{big.NewRat(i, 1)}
+	}
+	return Int(i)
+}
+
+func ModeOf(value Value) string {
+	if _, ok := value.(Rat); ok {
+		return ModeExact
+	}
+	return ""
+}
+
+func ReduceRange(name, index string, from, to int64, body parser.Node, scope map[string]Value, mode string) (Value, error) {
+	return reduceRange(name, index, from, to, body, scope, mode, nil)
+}
+
+func reduceRange(name, index string, from, to int64, body parser.Node, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	op, err := AggregateOperation(name)
+	if err != nil {
+		return nil, err
+	}
+	identity, ok := rangeIdentity[name]
+	if !ok {
+		return nil, fmt.Errorf("неизвестная функция диапазона %q", name)
+	}
+	if from > to {
+		return Literal(identity, mode)
+	}
+
+	local := make(map[string]Value, len(scope)+1)
+	for name, value := range scope {
+		local[name] = value
+	}
+	var result Value
+	for i := from; ; i++ {
+		local[index] = IndexValue(i, mode)
+		value, err := evaluate(body, local, mode, budget)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = value
+		} else if result, err = ApplyValue(op, result, value); err != nil {
+			return nil, err
+		}
+		if i == to {
+			return result, nil
+		}
+	}
+}
+
+func Substitute(node parser.Node, scope map[string]Value) (parser.Node, error) {
+	return substitute(node, scope, nil)
+}
+
+func substitute(node parser.Node, scope map[string]Value, bound map[string]bool) (parser.Node, error) {
+	switch n := node.(type) {
+	case *parser.Ident:
+		if value, ok := scope[n.Name]; ok && !bound[n.Name] {
+			return ValueNode(value)
+		}
+		return n, nil
+	case *parser.UnaryOp:
+		operand, err := substitute(n.Operand, scope, bound)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.UnaryOp{Op: n.Op, Operand: operand, At: n.At}, nil
+	case *parser.BinaryOp:
+		left, err := substitute(n.Left, scope, bound)
+		if err != nil {
+			return nil, err
+		}
+		right, err := substitute(n.Right, scope, bound)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.BinaryOp{Op: n.Op, Left: left, Right: right, At: n.At}, nil
+	case *parser.Call:
+		variable, binds := boundArgs(n)
+		args := make([]parser.Node, len(n.Args))
+		for i, arg := range n.Args {
+			argBound := bound
+			if binds(i) {
+				argBound = make(map[string]bool, len(bound)+1)
+				for name := range bound {
+					argBound[name] = true
+				}
+				argBound[variable] = true
+			}
+			var err error
+			if args[i], err = substitute(arg, scope, argBound); err != nil {
+				return nil, err
+			}
+		}
+		return &parser.Call{Name: n.Name, Args: args, At: n.At}, nil
+	}
+	return node, nil
+}
+
+func boundArgs(call *parser.Call) (string, func(int) bool) {
+	if index, ok := RangeIndex(call); ok {
+		return index, func(i int) bool { return i == 0 || i == 3 }
+	}
+	if IsNumeric(call.Name) {
+		if nc, err := ParseNumeric(call); err == nil {
+			return nc.Variable, func(i int) bool { return i == 0 || (i == 1 && len(call.Args) == 4) }
+		}
+	}
+	return "", func(int) bool { return false }
+}
+
+func CheckBody(node parser.Node, variable, mode string) error {
+	return checkBody(node, map[string]bool{variable: true}, mode)
+}
+
+func checkBody(node parser.Node, bound map[string]bool, mode string) error {
+	switch n := node.(type) {
+	case *parser.Ident:
+		if !bound[n.Name] {
+			_, err := ResolveIdent(n.Name, nil, mode)
+			return err
+		}
+	case *parser.UnaryOp:
+		return checkBody(n.Operand, bound, mode)
+	case *parser.BinaryOp:
+		if err := checkBody(n.Left, bound, mode); err != nil {
+			return err
+		}
+		return checkBody(n.Right, bound, mode)
+	case *parser.Call:
+		variable, binds := boundArgs(n)
+		for i, arg := range n.Args {
+			argBound := bound
+			if binds(i) {
+				argBound = make(map[string]bool, len(bound)+1)
+				for name := range bound {
+					argBound[name] = true
+				}
+				argBound[variable] = true
+			}
+			if err := checkBody(arg, argBound, mode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ValueNode(value Value) (parser.Node, error) {
+	var node parser.Node
+	negative := false
+	switch v := value.(type) {
+	case Int:
+		n := big.NewInt(int64(v))
+		negative = n.Sign() < 0
+		node = integerNode(n.Abs(n))
+	case Rat:
+		r := new(big.Rat).Abs(v.Rat)
+		negative = v.Sign() < 0
+		node = integerNode(r.Num())
+		if !r.IsInt() {
+			node = &parser.BinaryOp{Op: "/", Left: node, Right: integerNode(r.Denom())}
+		}
+	case Interval:
+		lo, err := ValueNode(Float(v.Lo))
+		if err != nil {
+			return nil, err
+		}
+		hi, err := ValueNode(Float(v.Hi))
+		if err != nil {
+			return nil, err
+		}
+		return &parser.Call{Name: IntervalCall, Args: []parser.Node{lo, hi}}, nil
+	case Complex:
+		re, err := ValueNode(Float(real(v)))
+		if err != nil {
+			return nil, err
+		}
+		im, err := ValueNode(Float(imag(v)))
+		if err != nil {
+			return nil, err
+		}
+		return &parser.Call{Name: ComplexCall, Args: []parser.Node{re, im}}, nil
+	case Quantity:
+		magnitude, err := ValueNode(Float(v.Float()))
+		if err != nil {
+			return nil, err
+		}
+		return &parser.Call{Name: UnitCall, Args: []parser.Node{magnitude, &parser.Unit{Name: v.Unit.Name}}}, nil
+	case Matrix:
+		if v.Vector {
+			return elementNodes(VectorCall, v.Data)
+		}
+		rows := make([]parser.Node, v.Rows)
+		for i := range rows {
+			row, err := elementNodes(VectorCall, v.Row(i))
+			if err != nil {
+				return nil, err
+			}
+			rows[i] = row
+		}
+		return &parser.Call{Name: MatrixCall, Args: rows}, nil
+	default:
+		f := value.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("значение %s нельзя подставить в тело диапазона", value.String())
+		}
+		negative = f < 0
+		literal := FormatNumber(math.Abs(f))
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0"
+		}
+		node = &parser.Number{Value: math.Abs(f), Literal: literal}
+	}
+	if negative {
+		node = &parser.UnaryOp{Op: "-", Operand: node}
+	}
+	return node, nil
+}
+
+func elementNodes(name string, elements []float64) (parser.Node, error) {
+	args := make([]parser.Node, len(elements))
+	for i, x := range elements {
+		node, err := ValueNode(Float(x))
+		if err != nil {
+			return nil, err
+		}
+		args[i] = node
+	}
+	return &parser.Call{Name: name, Args: args}, nil
+}
+
+func integerNode(n *big.Int) parser.Node {
+	value, _ := new(big.Float).SetInt(n).Float64()
+	return &parser.Number{Value: value, Literal: n.String(), Integer: true}
+}
+
+func evaluateRange(call *parser.Call, scope map[string]Value, mode string, budget *Budget) (Value, error) {
+	from, to, err := RangeBounds(call, scope, mode, budget)
+	if err != nil {
+		return nil, err
+	}
+	index, _ := RangeIndex(call)
+	return reduceRange(call.Name, index, from, to, call.Args[3], scope, mode, budget)
+}
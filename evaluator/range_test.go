@@ -0,0 +1,163 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"math/big"
+	"testing"
+)
+
+func TestRangeIndex(t *testing.T) {
+	tests := []struct {
+		expr     string
+		index    string
+		expected bool
+	}{
+		{"sum(i, 1, 10, i^2)", "i", true},
+		{"prod(k, 1, 5, k+1)", "k", true},
+		{"sum(i, 1, 10, 2)", "i", true},
+		{"sum(1, 2, 3, x)", "", false},
+		{"sum(1, 2, 3, 4)", "", false},
+		{"sum(i, 1, 10)", "", false},
+		{"max(i, 1, 10, i)", "", false},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		index, ok := RangeIndex(node.(*parser.Call))
+		if ok != tt.expected || index != tt.index {
+			t.Errorf("RangeIndex(%s) = %q, %v, expected %q, %v", tt.expr, index, ok, tt.index, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_Range(t *testing.T) {
+	tests := []struct {
+		expr     string
+		mode     string
+		expected string
+	}{
+		{"sum(i, 1, 100, i)", "", "5050"},
+		{"sum(i, 1, 3, i*x)", "", "12"},
+		{"prod(k, 1, 10, k)", "", "3628800"},
+		{"sum(i, 10, 1, i)", "", "0"},
+		{"prod(i, 10, 1, i)", "", "1"},
+		{"sum(i, 1, 4, 1/i)", ModeExact, "rat:25/12"},
+		{"sum(i, 1, 3, sum(j, 1, i, j))", "", "10"},
+		{"sum(i, -2, 2, i)", "", "0"},
+		{"sum(i, 1.0, 3, i)", "", "6"},
+		{"prod(2, 3, 4)", "", "24"},
+		{"sum(k, 1, 10, 1)", "", "10"},
+		{"sum(i, 1, 3, 2)", "", "6"},
+		{"prod(i, 1, 3, x)", "", "8"},
+		{"sum(i, 1, 3, i)", "", "6"},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scope := map[string]Value{"x": Int(2)}
+		if tt.mode == ModeExact {
+			scope["x"] = IndexValue(2, tt.mode)
+		}
+		result, err := Evaluate(node, scope, tt.mode)
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.expr, err)
+		}
+		if result.String() != tt.expected {
+			t.Errorf("Evaluate(%s) = %s, expected %s", tt.expr, result.String(), tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_RangeErrors(t *testing.T) {
+	tests := []string{
+		"sum(i, 1.5, 3, i)",
+		"sum(i, 1, 100000000, i)",
+		"sum(pi, 1, 3, pi)",
+		"sum(i, 1, 3, i/0)",
+		"sum(i, 1, y, i)",
+	}
+
+	for _, expr := range tests {
+		node, err := parser.Parse(expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := Evaluate(node, nil, ""); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+}
+
+func TestEvaluate_RangeAmbiguousIndex(t *testing.T) {
+	scope := map[string]Value{"x": Int(5)}
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"sum(x, 1, 2, x)", ""},
+		{"prod(x, 1, 2, x + 1)", ""},
+		{"sum(i, 1, 3, sum(i, 1, 2, i))", ""},
+		{"sum(x, 1, 2, 3)", ""},
+		{"sum(1, 2, 3, x)", "11"},
+		{"sum(i, 1, 3, i*x)", "30"},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err := Evaluate(node, scope, "")
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("Expected ambiguity error for %s, got %s", tt.expr, result)
+			}
+			continue
+		}
+		if err != nil || result.String() != tt.expected {
+			t.Errorf("Evaluate(%s) = %v, %v; expected %s", tt.expr, result, err, tt.expected)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	scope := map[string]Value{
+		"a": Int(-3),
+		"b": Float(2),
+		"c": Rat{big.NewRat(-1, 3)},
+		"i": Int(100),
+		"x": Int(5),
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"i*a + b", "100*-3 + 2.0"},
+		{"sum(i, 1, a, i*b)", "sum(i, 1, -3, i*2.0)"},
+		{"c", "-(1/3)"},
+		{"sqrt(z)", "sqrt(z)"},
+		{"integrate(x*b, 0, x)", "integrate(x*2.0, 0, 5)"},
+		{"solve(t - x, t, 0, i)", "solve(t - 5, t, 0, 100)"},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		substituted, err := Substitute(node, scope)
+		if err != nil {
+			t.Fatalf("Substitute(%s) failed: %v", tt.expr, err)
+		}
+		if got := parser.Format(substituted); got != tt.expected {
+			t.Errorf("Substitute(%s) = %s, expected %s", tt.expr, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const MomentsCall = "moments"
+
+const momentsPrefix = "mom:"
+
+var statisticArity = map[string]int{
+	"mean":        1,
+	"median":      1,
+	"stddev":      1,
+	"variance":    1,
+	"percentile":  2,
+	"correlation": 2,
+	"linreg":      2,
+}
+
+var momentStatistics = map[string]bool{
+	"mean":        true,
+	"stddev":      true,
+	"variance":    true,
+	"correlation": true,
+	"linreg":      true,
+}
+
+type Moments struct {
+	Count  float64
+	Sum    float64
+	SumSq  float64
+	Paired bool
+	SumY   float64
+	SumSqY float64
+	SumXY  float64
+}
+
+func (m Moments) Float() float64 {
+	return m.Sum / m.Count
+}
+
+func (m Moments) String() string {
+	fields := []float64{m.Count, m.Sum, m.SumSq}
+	if m.Paired {
+		fields = append(fields, m.SumY, m.SumSqY, m.SumXY)
+	}
+	elements := make([]string, len(fields))
+	for i, x := range fields {
+		elements[i] = FormatNumber(x)
+	}
+	return momentsPrefix + strings.Join(elements, ",")
+}
+
+func IsStatistic(name string) bool {
+	_, ok := statisticArity[name]
+	return ok
+}
+
+func StatisticArity(name string) int {
+	return statisticArity[name]
+}
+
+func IsMomentStatistic(name string) bool {
+	return momentStatistics[name]
+}
+
+func parseMoments(s string) (Value, error) {
+	fields, err := parseElements(strings.TrimPrefix(s, momentsPrefix))
+	if err != nil || (len(fields) != 3 && len(fields) != 6) || fields[0] < 1 {
+		return nil, fmt.Errorf("некорректные частичные суммы %q", s)
+	}
+	m := Moments{Count: fields[0], Sum: fields[1], SumSq: fields[2]}
+	if len(fields) == 6 {
+		m.Paired, m.SumY, m.SumSqY, m.SumXY = true, fields[3], fields[4], fields[5]
+	}
+	return m, nil
+}
+
+func hasMoments(args []Value) bool {
+	for _, arg := range args {
+		if _, ok := arg.(Moments); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ApplyStatistic(op string, args ...Value) (Value, error) {
+	for _, arg := range args {
+		switch arg.(type) {
+		case Rat:
+			return nil, fmt.Errorf("статистические функции недоступны в точном режиме")
+		case Interval, Complex, Quantity:
+			return nil, fmt.Errorf("статистические функции поддерживают только действительные числа")
+		}
+	}
+
+	if op == "+" && len(args) == 2 {
+		left, leftOk := args[0].(Moments)
+		right, rightOk := args[1].(Moments)
+		if leftOk && rightOk && left.Paired == right.Paired {
+			return Moments{
+				Count:  left.Count + right.Count,
+				Sum:    left.Sum + right.Sum,
+				SumSq:  left.SumSq + right.SumSq,
+				Paired: left.Paired,
+				SumY:   left.SumY + right.SumY,
+				SumSqY: left.SumSqY + right.SumSqY,
+				SumXY:  left.SumXY + right.SumXY,
+			}, nil
+		}
+	}
+	if len(args) == 1 && momentStatistics[op] {
+		if m, ok := args[0].(Moments); ok {
+			return finishMoments(op, m)
+		}
+	}
+	if hasMoments(args) {
+		return nil, fmt.Errorf("операция %q не определена для частичных сумм", op)
+	}
+
+	if op == MomentsCall {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("функция %s ожидает 1 или 2 аргумента, получено %d", op, len(args))
+		}
+		return momentsOf(op, args)
+	}
+	arity, ok := statisticArity[op]
+	if !ok {
+		return nil, fmt.Errorf("неизвестная функция %q", op)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", op, arity, len(args))
+	}
+
+	switch op {
+	case "median":
+		xs, err := sample(op, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return Float(percentile(xs, 50)), nil
+	case "percentile":
+		xs, err := sample(op, args[0])
+		if err != nil {
+			return nil, err
+		}
+		p := args[1].Float()
+		if _, ok := args[1].(Matrix); ok || p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, fmt.Errorf("процентиль должен быть числом от 0 до 100, получено %s", args[1])
+		}
+		return Float(percentile(xs, p)), nil
+	}
+
+	m, err := momentsOf(op, args)
+	if err != nil {
+		return nil, err
+	}
+	return finishMoments(op, m)
+}
+
+func sample(op string, arg Value) ([]float64, error) {
+	m, ok := arg.(Matrix)
+	if !ok {
+		return nil, fmt.Errorf("функция %s ожидает список чисел, получено число %s", op, FormatNumber(arg.Float()))
+	}
+	if !m.Vector && m.Rows != 1 && m.Cols != 1 {
+		return nil, fmt.Errorf("функция %s ожидает список чисел, получено: %s", op, m.shape())
+	}
+	return m.Data, nil
+}
+
+func momentsOf(op string, args []Value) (Moments, error) {
+	xs, err := sample(op, args[0])
+	if err != nil {
+		return Moments{}, err
+	}
+	m := Moments{Count: float64(len(xs))}
+	for _, x := range xs {
+		m.Sum += x
+		m.SumSq += x * x
+	}
+	if len(args) == 1 {
+		return m, nil
+	}
+
+	ys, err := sample(op, args[1])
+	if err != nil {
+		return Moments{}, err
+	}
+	if len(ys) != len(xs) {
+		return Moments{}, fmt.Errorf("ряды имеют разную длину: %d и %d", len(xs), len(ys))
+	}
+	m.Paired = true
+	for i, y := range ys {
+		m.SumY += y
+		m.SumSqY += y * y
+		m.SumXY += xs[i] * y
+	}
+	return m, nil
+}
+
+func finishMoments(op string, m Moments) (Value, error) {
+	n := m.Count
+	sxx := math.Max(n*m.SumSq-m.Sum*m.Sum, 0)
+	switch op {
+	case "mean":
+		return Float(m.Sum / n), nil
+	case "variance":
+		return Float(sxx / (n * n)), nil
+	case "stddev":
+		return Float(math.Sqrt(sxx) / n), nil
+	}
+
+	if !m.Paired {
+		return nil, fmt.Errorf("функция %s ожидает 2 аргумента, получено 1", op)
+	}
+	sxy := n*m.SumXY - m.Sum*m.SumY
+	switch op {
+	case "correlation":
+		syy := math.Max(n*m.SumSqY-m.SumY*m.SumY, 0)
+		if sxx == 0 || syy == 0 {
+			return nil, fmt.Errorf("корреляция не определена для постоянного ряда")
+		}
+		return Float(sxy / math.Sqrt(sxx*syy)), nil
+	case "linreg":
+		if sxx == 0 {
+			return nil, fmt.Errorf("линейная регрессия не определена: все значения x совпадают")
+		}
+		slope := sxy / sxx
+		return NewVector([]float64{slope, (m.SumY - slope*m.Sum) / n}), nil
+	}
+	return nil, fmt.Errorf("неизвестная функция %q", op)
+}
+
+func percentile(xs []float64, p float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"calculator/parser"
+	"testing"
+)
+
+func TestEvaluate_Statistics(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"mean([2, 4, 4, 4, 5, 5, 7, 9])", "5"},
+		{"variance([2, 4, 4, 4, 5, 5, 7, 9])", "4"},
+		{"stddev([2, 4, 4, 4, 5, 5, 7, 9])", "2"},
+		{"median([5, 1, 3])", "3"},
+		{"median([4, 1, 3, 2])", "2.5"},
+		{"percentile([1, 2, 3, 4, 5], 25)", "2"},
+		{"percentile([10, 20], 0)", "10"},
+		{"percentile([10, 20], 100)", "20"},
+		{"percentile([10, 20, 30], 75)", "25"},
+		{"mean([x])", "2"},
+		{"mean([1, 2, 3]*x)", "4"},
+		{"mean(transpose([1, 2, 3]))", "2"},
+		{"correlation([1, 2, 3, 4, 5], [2, 4, 6, 8, 10])", "1"},
+		{"correlation([1, 2, 3, 4, 5], [5, 4, 3, 2, 1])", "-1"},
+		{"linreg([1, 2, 3, 4, 5], [3, 5, 7, 9, 11])", "vec:2,1"},
+		{"linreg([1, 2], [1, 2])", "vec:1,0"},
+		{"xs = [1, 2, 3]; mean(xs) + median(xs)", "4"},
+	}
+
+	for _, tt := range tests {
+		program, err := parser.ParseProgram(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		scope := map[string]Value{"x": Float(2)}
+		var result Value
+		for _, stmt := range program.Statements {
+			if result, err = Evaluate(stmt.Value, scope, ""); err != nil {
+				break
+			}
+			if stmt.IsAssignment() {
+				scope[stmt.Name] = result
+			}
+		}
+		if err != nil {
+			t.Errorf("Evaluate(%s) failed: %v", tt.expr, err)
+			continue
+		}
+		if result.String() != tt.expected {
+			t.Errorf("Evaluate(%s) = %s, expected %s", tt.expr, result, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate_StatisticsErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		mode string
+	}{
+		{"mean(5)", ""},
+		{"mean([[1, 2], [3, 4]])", ""},
+		{"mean([1, 2], [3, 4])", ""},
+		{"percentile([1, 2, 3])", ""},
+		{"percentile([1, 2, 3], 101)", ""},
+		{"percentile([1, 2, 3], -1)", ""},
+		{"correlation([1, 2, 3], [1, 2])", ""},
+		{"correlation([1, 1, 1], [1, 2, 3])", ""},
+		{"linreg([2, 2], [1, 3])", ""},
+		{"moments([1, 2, 3])", ""},
+		{"mean([1, 2, 3])", ModeExact},
+	}
+
+	for _, tt := range tests {
+		node, err := parser.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.expr, err)
+		}
+		if result, err := Evaluate(node, nil, tt.mode); err == nil {
+			t.Errorf("Expected error for %s, got %v", tt.expr, result)
+		}
+	}
+}
+
+func TestMoments(t *testing.T) {
+	xs := NewVector([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	var parts []Value
+	for _, bounds := range [][2]int{{0, 3}, {3, 6}, {6, 8}} {
+		part, err := ApplyValue(MomentsCall, NewVector(xs.Data[bounds[0]:bounds[1]]))
+		if err != nil {
+			t.Fatalf("Moments failed: %v", err)
+		}
+		encoded, err := ParseValue(part.String())
+		if err != nil || encoded != part {
+			t.Fatalf("Expected %s to round-trip, got %v, %v", part, encoded, err)
+		}
+		parts = append(parts, encoded)
+	}
+	total, err := Reduce(parts, func(left, right Value) (Value, error) {
+		return ApplyValue("+", left, right)
+	})
+	if err != nil {
+		t.Fatalf("Combining moments failed: %v", err)
+	}
+	if total.String() != "mom:8,40,232" {
+		t.Errorf("Expected combined moments mom:8,40,232, got %s", total)
+	}
+	for op, expected := range map[string]string{"mean": "5", "variance": "4", "stddev": "2"} {
+		result, err := ApplyValue(op, total)
+		if err != nil || result.String() != expected {
+			t.Errorf("%s(%s) = %v, %v, expected %s", op, total, result, err, expected)
+		}
+	}
+
+	paired, err := ApplyValue(MomentsCall, NewVector([]float64{1, 2, 3}), NewVector([]float64{3, 5, 7}))
+	if err != nil || paired.String() != "mom:3,6,14,15,83,34" {
+		t.Fatalf("Expected paired moments, got %v, %v", paired, err)
+	}
+	if fit, err := ApplyValue("linreg", paired); err != nil || fit.String() != "vec:2,1" {
+		t.Errorf("Expected linreg from moments vec:2,1, got %v, %v", fit, err)
+	}
+	if _, err := ApplyValue("correlation", total); err == nil {
+		t.Error("Expected error for correlation of unpaired moments")
+	}
+	if _, err := ApplyValue("+", total, paired); err == nil {
+		t.Error("Expected error for adding paired and unpaired moments")
+	}
+	for _, input := range []string{"mom:", "mom:0,0,0", "mom:1,2", "mom:1,2,3,4"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+}
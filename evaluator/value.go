@@ -0,0 +1,238 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const ModeExact = "exact"
+
+const ratPrefix = "rat:"
+
+type Value interface {
+	Float() float64
+	String() string
+}
+
+type Float float64
+
+type Rat struct {
+	*big.Rat
+}
+
+func (f Float) Float() float64 {
+	return float64(f)
+}
+
+func (f Float) String() string {
+	return FormatNumber(float64(f))
+}
+
+func (r Rat) Float() float64 {
+	value, _ := r.Rat.Float64()
+	return value
+}
+
+func (r Rat) String() string {
+	return ratPrefix + r.RatString()
+}
+
+func ParseValue(s string) (Value, error) {
+	if strings.HasPrefix(s, intervalPrefix) {
+		return parseInterval(s)
+	}
+	if strings.HasPrefix(s, complexPrefix) {
+		return parseComplex(s)
+	}
+	if strings.HasPrefix(s, quantityPrefix) {
+		return parseQuantity(s)
+	}
+	if strings.HasPrefix(s, vectorPrefix) {
+		return parseVector(s)
+	}
+	if strings.HasPrefix(s, matrixPrefix) {
+		return parseMatrix(s)
+	}
+	if strings.HasPrefix(s, momentsPrefix) {
+		return parseMoments(s)
+	}
+	if strings.HasPrefix(s, factorsPrefix) {
+		return parseFactors(s)
+	}
+	if strings.HasPrefix(s, ratPrefix) {
+		r, ok := new(big.Rat).SetString(strings.TrimPrefix(s, ratPrefix))
+		if !ok {
+			return nil, fmt.Errorf("некорректное рациональное число %q", s)
+		}
+		return Rat{r}, nil
+	}
+	if value, ok, err := ParseInt(s); err != nil {
+		return nil, err
+	} else if ok {
+		return Int(value), nil
+	}
+	if value, ok := parseBigInt(s); ok {
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное число %q", s)
+	}
+	return Float(value), nil
+}
+
+func Literal(literal string, mode string) (Value, error) {
+	if mode != ModeExact {
+		return ParseValue(literal)
+	}
+	r, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return nil, fmt.Errorf("некорректное число %q", literal)
+	}
+	return Rat{r}, nil
+}
+
+func FromFloat(value float64, mode string) (Value, error) {
+	if mode != ModeExact {
+		return Float(value), nil
+	}
+	return Literal(FormatNumber(value), mode)
+}
+
+func ApplyValue(op string, args ...Value) (Value, error) {
+	if IsNumberTheory(op) || op == TrialDivision {
+		return ApplyNumberTheory(op, args...)
+	}
+	if IsStatistic(op) || op == MomentsCall || hasMoments(args) {
+		return ApplyStatistic(op, args...)
+	}
+	if IsMatrixOperation(op) || hasMatrix(args) {
+		return ApplyMatrix(op, args...)
+	}
+	if hasFactors(args) {
+		return ApplyFactors(op, args...)
+	}
+	if op == ConvertCall || hasQuantity(args) {
+		return ApplyQuantity(op, args...)
+	}
+	if op == ComplexCall || hasComplex(args) {
+		return ApplyComplex(op, args...)
+	}
+	if IsIntervalOperation(op) || hasInterval(args) {
+		return ApplyInterval(op, args...)
+	}
+
+	exact, integers := false, true
+	for _, arg := range args {
+		switch arg.(type) {
+		case Rat:
+			exact = true
+		case Int, BigInt:
+		default:
+			integers = false
+		}
+	}
+
+	if !exact && hasBigInt(args) && (IsIntegerOperation(op) || (integers && isIntOperation(op))) {
+		ints := make([]*big.Int, len(args))
+		for i, arg := range args {
+			value, err := toBigInt(op, arg)
+			if err != nil {
+				return nil, err
+			}
+			ints[i] = value
+		}
+		return ApplyBig(op, ints...)
+	}
+
+	if !exact && (IsIntegerOperation(op) || (integers && isIntOperation(op))) {
+		ints := make([]int64, len(args))
+		for i, arg := range args {
+			value, err := toInt64(op, arg)
+			if err != nil {
+				return nil, err
+			}
+			ints[i] = value
+		}
+		return ApplyInt(op, ints...)
+	}
+
+	if !exact {
+		floats := make([]float64, len(args))
+		for i, arg := range args {
+			floats[i] = arg.Float()
+		}
+		if promotesToComplex(op, floats) {
+			return ApplyComplex(op, args...)
+		}
+		result, err := Apply(op, floats...)
+		if err != nil {
+			return nil, err
+		}
+		return Float(result), nil
+	}
+
+	rats := make([]*big.Rat, len(args))
+	for i, arg := range args {
+		r, err := toRat(arg)
+		if err != nil {
+			return nil, err
+		}
+		rats[i] = r
+	}
+	result, err := ApplyRat(op, rats...)
+	if err != nil {
+		return nil, err
+	}
+	return Rat{result}, nil
+}
+
+func AggregateValue(name string, args []Value) (Value, error) {
+	op, err := AggregateOperation(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("функция %s ожидает хотя бы один аргумент", name)
+	}
+
+	result, err := Reduce(args, func(left, right Value) (Value, error) {
+		return ApplyValue(op, left, right)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if name == "avg" {
+		count := Value(Float(len(args)))
+		if _, ok := result.(Rat); ok {
+			count = Rat{new(big.Rat).SetInt64(int64(len(args)))}
+		}
+		return ApplyValue("/", result, count)
+	}
+	return result, nil
+}
+
+func isIntOperation(op string) bool {
+	_, binary := operationArity[op]
+	return binary || exactFunction(op)
+}
+
+func toRat(value Value) (*big.Rat, error) {
+	switch v := value.(type) {
+	case Rat:
+		return v.Rat, nil
+	case Int:
+		return new(big.Rat).SetInt64(int64(v)), nil
+	case BigInt:
+		return new(big.Rat).SetInt(v.Int), nil
+	case Float:
+		r := new(big.Rat)
+		if r.SetFloat64(float64(v)) == nil {
+			return nil, fmt.Errorf("значение %v нельзя представить точно", float64(v))
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("неподдерживаемый тип значения %T", value)
+}
@@ -0,0 +1,118 @@
+package evaluator
+
+import "testing"
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		float    float64
+	}{
+		{"2.5", "2.5", 2.5},
+		{"6.02e+23", "6.02e+23", 6.02e23},
+		{"rat:1/3", "rat:1/3", 1.0 / 3},
+		{"rat:4/2", "rat:2", 2},
+		{"rat:-7", "rat:-7", -7},
+		{"42", "42", 42},
+		{"0xFF", "255", 255},
+		{"-9223372036854775808", "-9223372036854775808", -9223372036854775808},
+	}
+
+	for _, tt := range tests {
+		value, err := ParseValue(tt.input)
+		if err != nil {
+			t.Errorf("ParseValue(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if value.String() != tt.expected || value.Float() != tt.float {
+			t.Errorf("ParseValue(%q) = %s (%v), want %s (%v)", tt.input, value, value.Float(), tt.expected, tt.float)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "rat:", "rat:1/0", "rat:x"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) expected error", input)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		literal  string
+		mode     string
+		expected string
+	}{
+		{"0.1", "", "0.1"},
+		{"0.1", ModeExact, "rat:1/10"},
+		{"1.5e-3", ModeExact, "rat:3/2000"},
+		{"0.30000000000000000001", ModeExact, "rat:30000000000000000001/100000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		value, err := Literal(tt.literal, tt.mode)
+		if err != nil {
+			t.Errorf("Literal(%q, %q) unexpected error: %v", tt.literal, tt.mode, err)
+			continue
+		}
+		if value.String() != tt.expected {
+			t.Errorf("Literal(%q, %q) = %s, want %s", tt.literal, tt.mode, value, tt.expected)
+		}
+	}
+
+	value, err := FromFloat(0.07, ModeExact)
+	if err != nil || value.String() != "rat:7/100" {
+		t.Errorf("FromFloat(0.07) = %v, %v; want rat:7/100", value, err)
+	}
+}
+
+func TestApplyValue(t *testing.T) {
+	tenth, _ := Literal("0.1", ModeExact)
+	fifth, _ := Literal("0.2", ModeExact)
+
+	sum, err := ApplyValue("+", tenth, fifth)
+	if err != nil || sum.String() != "rat:3/10" {
+		t.Errorf("Expected exact 3/10, got %v, %v", sum, err)
+	}
+
+	floatSum, err := ApplyValue("+", Float(0.1), Float(0.2))
+	if err != nil || floatSum.String() != "0.30000000000000004" {
+		t.Errorf("Expected float arithmetic, got %v, %v", floatSum, err)
+	}
+
+	mixed, err := ApplyValue("*", tenth, Float(4))
+	if err != nil || mixed.String() != "rat:2/5" {
+		t.Errorf("Expected mixed arithmetic to stay exact, got %v, %v", mixed, err)
+	}
+
+	if _, err := ApplyValue("sqrt", fifth); err == nil {
+		t.Error("Expected sqrt to be unavailable in exact mode")
+	}
+}
+
+func TestAggregateValue(t *testing.T) {
+	args := make([]Value, 3)
+	for i, literal := range []string{"0.1", "0.2", "0.4"} {
+		args[i], _ = Literal(literal, ModeExact)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"sum", "rat:7/10"},
+		{"avg", "rat:7/30"},
+		{"min", "rat:1/10"},
+		{"max", "rat:2/5"},
+	}
+	for _, tt := range tests {
+		got, err := AggregateValue(tt.name, args)
+		if err != nil || got.String() != tt.expected {
+			t.Errorf("AggregateValue(%s) = %v, %v; want %s", tt.name, got, err, tt.expected)
+		}
+	}
+
+	avg, err := AggregateValue("avg", []Value{Float(1), Float(2)})
+	if err != nil || avg.String() != "1.5" {
+		t.Errorf("Expected float average 1.5, got %v, %v", avg, err)
+	}
+}
@@ -35,9 +35,9 @@ func (ch *CalculateHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	expression, err := ch.expressionService.CreateExpression(claims.UserID, reqBody.Expression)
+	expression, err := ch.expressionService.CreateExpressionFromRequest(claims.UserID, &reqBody)
 	if err != nil {
-		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusUnprocessableEntity)
+		respondWithError(w, err, http.StatusUnprocessableEntity)
 		return
 	}
 
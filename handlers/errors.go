@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"calculator/parser"
+	"calculator/units"
+	"calculator/utils"
+	"errors"
+	"net/http"
+)
+
+type errorResponse struct {
+	Error          string                `json:"error"`
+	ParseError     *parser.ParseError    `json:"parse_error,omitempty"`
+	DimensionError *units.DimensionError `json:"dimension_error,omitempty"`
+}
+
+func respondWithError(w http.ResponseWriter, err error, status int) {
+	response := errorResponse{Error: err.Error()}
+	var parseErr *parser.ParseError
+	if errors.As(err, &parseErr) {
+		response.ParseError = parseErr
+	}
+	var dimensionErr *units.DimensionError
+	if errors.As(err, &dimensionErr) {
+		response.DimensionError = dimensionErr
+	}
+	utils.RespondWithJSON(w, response, status)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"calculator/parser"
+	"calculator/services"
+	"calculator/units"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespondWithError(t *testing.T) {
+	_, parseErr := parser.Parse("2+*2")
+	if parseErr == nil {
+		t.Fatal("Expected parse error")
+	}
+
+	w := httptest.NewRecorder()
+	respondWithError(w, fmt.Errorf("invalid expression: %w", parseErr), http.StatusUnprocessableEntity)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+	var response struct {
+		Error      string             `json:"error"`
+		ParseError *parser.ParseError `json:"parse_error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error != "invalid expression: "+parseErr.Error() {
+		t.Errorf("Unexpected error text %q", response.Error)
+	}
+	if response.ParseError == nil || response.ParseError.Code != parser.CodeMisplacedOperator ||
+		response.ParseError.Column != 3 || response.ParseError.Token != "*" {
+		t.Errorf("Unexpected parse error %+v", response.ParseError)
+	}
+
+	w = httptest.NewRecorder()
+	respondWithError(w, fmt.Errorf("division by zero"), http.StatusUnprocessableEntity)
+
+	var plain map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &plain); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if _, ok := plain["parse_error"]; ok || plain["error"] != "division by zero" {
+		t.Errorf("Expected a plain error response, got %v", plain)
+	}
+
+	_, err := services.CalcInMode("3 m + 2 kg", nil, "")
+	w = httptest.NewRecorder()
+	respondWithError(w, fmt.Errorf("invalid expression: %w", err), http.StatusUnprocessableEntity)
+
+	var mismatch struct {
+		ParseError     *parser.ParseError    `json:"parse_error"`
+		DimensionError *units.DimensionError `json:"dimension_error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &mismatch); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if mismatch.ParseError != nil || mismatch.DimensionError == nil || mismatch.DimensionError.Operation != "+" ||
+		mismatch.DimensionError.Left != "m" || mismatch.DimensionError.Right != "kg" {
+		t.Errorf("Unexpected dimension error %s", w.Body.String())
+	}
+}
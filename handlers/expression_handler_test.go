@@ -20,7 +20,7 @@ func (m *MockExpressionServiceForExpressionHandler) GetExpression(id string, use
 		UserID:     userID,
 		Expression: "2+2",
 		Status:     models.StatusDone,
-		Result:     func() *float64 { r := 4.0; return &r }(),
+		Value:      models.Value{Result: func() *float64 { r := 4.0; return &r }()},
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}, nil
@@ -33,7 +33,7 @@ func (m *MockExpressionServiceForExpressionHandler) GetUserExpressions(userID in
 			UserID:     userID,
 			Expression: "2+2",
 			Status:     models.StatusDone,
-			Result:     func() *float64 { r := 4.0; return &r }(),
+			Value:      models.Value{Result: func() *float64 { r := 4.0; return &r }()},
 			CreatedAt:  time.Now(),
 			UpdatedAt:  time.Now(),
 		},
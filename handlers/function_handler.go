@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"calculator/middleware"
+	"calculator/models"
+	"calculator/services"
+	"calculator/utils"
+	"encoding/json"
+	"net/http"
+)
+
+type FunctionHandler struct {
+	functionService *services.FunctionService
+}
+
+func NewFunctionHandler(functionService *services.FunctionService) *FunctionHandler {
+	return &FunctionHandler{functionService: functionService}
+}
+
+func (fh *FunctionHandler) DefineFunction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.FunctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": "Invalid request body"}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	function, err := fh.functionService.DefineFunction(claims.UserID, &req)
+	if err != nil {
+		respondWithError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	utils.RespondWithJSON(w, function, http.StatusOK)
+}
+
+func (fh *FunctionHandler) GetFunctions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	functions, err := fh.functionService.GetFunctions(claims.UserID)
+	if err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	if functions == nil {
+		functions = []*models.Function{}
+	}
+
+	utils.RespondWithJSON(w, functions, http.StatusOK)
+}
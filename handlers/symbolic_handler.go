@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"calculator/middleware"
+	"calculator/models"
+	"calculator/parser"
+	"calculator/services"
+	"calculator/utils"
+	"encoding/json"
+	"net/http"
+)
+
+type SymbolicHandler struct {
+	symbolicService *services.SymbolicService
+}
+
+type symbolicResponse struct {
+	Operation string           `json:"operation"`
+	Result    string           `json:"result"`
+	AST       *parser.JSONNode `json:"ast"`
+}
+
+func NewSymbolicHandler(symbolicService *services.SymbolicService) *SymbolicHandler {
+	return &SymbolicHandler{symbolicService: symbolicService}
+}
+
+func (sh *SymbolicHandler) Transform(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SymbolicRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": "Invalid request body"}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := sh.symbolicService.Transform(claims.UserID, &req)
+	if err != nil {
+		respondWithError(w, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	operation := req.Operation
+	if operation == "" {
+		operation = "simplify"
+	}
+	utils.RespondWithJSON(w, symbolicResponse{
+		Operation: operation,
+		Result:    parser.Format(result),
+		AST:       parser.ToJSON(result),
+	}, http.StatusOK)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"calculator/models"
 	"calculator/services"
 	"calculator/utils"
 	"encoding/json"
@@ -64,15 +65,13 @@ func (th *TaskHandler) SubmitTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var reqBody struct {
-		Result float64 `json:"result"`
-	}
+	var reqBody models.TaskResult
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 		utils.RespondWithJSON(w, map[string]string{"error": "Неверный формат запроса"}, http.StatusBadRequest)
 		return
 	}
 
-	if err := th.expressionService.SubmitTaskResult(path, reqBody.Result); err != nil {
+	if err := th.expressionService.SubmitTaskReport(path, &reqBody); err != nil {
 		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
 		return
 	}
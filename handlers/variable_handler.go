@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"calculator/middleware"
+	"calculator/models"
+	"calculator/services"
+	"calculator/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const maxCSVSize = 10 << 20
+
+type VariableHandler struct {
+	variableService *services.VariableService
+}
+
+func NewVariableHandler(variableService *services.VariableService) *VariableHandler {
+	return &VariableHandler{variableService: variableService}
+}
+
+func (vh *VariableHandler) SetVariable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.VariableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": "Invalid request body"}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	variable, err := vh.variableService.SetVariable(claims.UserID, &req)
+	if err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	utils.RespondWithJSON(w, variable, http.StatusOK)
+}
+
+func (vh *VariableHandler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	variables, err := vh.variableService.ImportCSV(claims.UserID, http.MaxBytesReader(w, r.Body, maxCSVSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.RespondWithJSON(w, map[string]string{"error": fmt.Sprintf("CSV must not exceed %d bytes", tooLarge.Limit)}, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	utils.RespondWithJSON(w, variables, http.StatusOK)
+}
+
+func (vh *VariableHandler) GetVariables(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, ok := middleware.GetUserFromContext(r)
+	if !ok {
+		utils.RespondWithJSON(w, map[string]string{"error": "User not authorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	variables, err := vh.variableService.GetVariables(claims.UserID)
+	if err != nil {
+		utils.RespondWithJSON(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	if variables == nil {
+		variables = []*models.Variable{}
+	}
+
+	utils.RespondWithJSON(w, variables, http.StatusOK)
+}
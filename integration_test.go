@@ -2,13 +2,22 @@ package main
 
 import (
 	"bytes"
+	"calculator/evaluator"
 	"calculator/handlers"
 	"calculator/middleware"
+	"calculator/models"
+	"calculator/parser"
 	"calculator/services"
+	"calculator/units"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -272,6 +281,1068 @@ func TestUserIsolation(t *testing.T) {
 	}
 }
 
+func TestVariablesWorkflow(t *testing.T) {
+	dbPath := "./test_variables.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	authService := services.NewAuthService(db, "test-secret-key")
+	expressionService := services.NewExpressionService(db)
+	variableService := services.NewVariableService(db)
+
+	authHandler := handlers.NewAuthHandler(authService)
+	calculateHandler := handlers.NewCalculateHandler(expressionService)
+	expressionHandler := handlers.NewExpressionHandler(expressionService)
+	variableHandler := handlers.NewVariableHandler(variableService)
+
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	body, _ := json.Marshal(map[string]string{"login": "varuser", "password": "varpass123"})
+	rr := httptest.NewRecorder()
+	authHandler.Register(rr, httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register user: %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(body)))
+	var login map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &login)
+	token := login["token"]
+
+	setVariable := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/variables", bytes.NewBufferString(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(variableHandler.SetVariable)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := setVariable(`{"name":"rate","value":0.07}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := setVariable(`{"name":"pi","value":3}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected reserved name to be rejected, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression":"100*(1+rate)"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	var created map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	if rr := setVariable(`{"name":"rate","value":0.5}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected variable update to succeed, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/expressions/"+created["id"], nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(expressionHandler.GetExpression)).ServeHTTP(rr, req)
+
+	var expression struct {
+		Variables map[string]float64 `json:"variables"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &expression); err != nil {
+		t.Fatalf("Failed to parse expression response: %v", err)
+	}
+	if expression.Variables["rate"] != 0.07 {
+		t.Errorf("Expected snapshot rate 0.07 after update, got %v", expression.Variables)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression":"unknown*2"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected undefined variable to be rejected, got %d", rr.Code)
+	}
+
+	importCSV := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/variables/csv", bytes.NewBufferString(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(variableHandler.ImportCSV)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := importCSV("x,y\n1,3\n2,5\n3,7\n"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected CSV import to succeed, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := importCSV("x,y\n1,abc\n"); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected invalid CSV to be rejected, got %d", rr.Code)
+	}
+	if rr := importCSV("z\n" + strings.Repeat("1\n", 6<<20)); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected oversized CSV to be rejected, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/variables", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(variableHandler.GetVariables)).ServeHTTP(rr, req)
+	var variables []models.Variable
+	if err := json.Unmarshal(rr.Body.Bytes(), &variables); err != nil {
+		t.Fatalf("Failed to parse variables response: %v", err)
+	}
+	if len(variables) != 3 || variables[2].Name != "y" || len(variables[2].Values) != 3 || variables[2].Values[2] != 7 {
+		t.Errorf("Expected imported columns x and y, got %+v", variables)
+	}
+
+	calculate := func(expr string) string {
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression":"`+expr+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Errorf("Expected %s over imported columns to be accepted, got %d. Body: %s", expr, rr.Code, rr.Body.String())
+		}
+		var created map[string]string
+		json.Unmarshal(rr.Body.Bytes(), &created)
+		return created["id"]
+	}
+	lists := func(id string) map[string]string {
+		req := httptest.NewRequest("GET", "/api/v1/expressions/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(expressionHandler.GetExpression)).ServeHTTP(rr, req)
+		var expression struct {
+			Lists map[string]string `json:"lists"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &expression)
+		return expression.Lists
+	}
+
+	regression := calculate("linreg(x, y)")
+	mean := calculate("mean(y) + rate")
+	before := lists(regression)
+	if len(before) != 2 || before["x"] == "" || before["y"] == "" {
+		t.Errorf("Expected list digests of x and y, got %v", before)
+	}
+	if snapshot := lists(mean); len(snapshot) != 1 || snapshot["y"] != before["y"] {
+		t.Errorf("Expected list digest of y only, got %v", snapshot)
+	}
+
+	if rr := importCSV("y\n3\n5\n8\n"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected CSV re-import to succeed, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if snapshot := lists(regression); snapshot["y"] != before["y"] {
+		t.Errorf("Expected stored digest of y to survive re-import, got %v", snapshot)
+	}
+	if after := lists(calculate("linreg(x, y)")); after["x"] != before["x"] || after["y"] == before["y"] {
+		t.Errorf("Expected only the digest of y to change after re-import, got %v, was %v", after, before)
+	}
+}
+
+func TestFunctionsWorkflow(t *testing.T) {
+	dbPath := "./test_functions.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	authService := services.NewAuthService(db, "test-secret-key")
+	expressionService := services.NewExpressionService(db)
+	functionService := services.NewFunctionService(db)
+
+	authHandler := handlers.NewAuthHandler(authService)
+	calculateHandler := handlers.NewCalculateHandler(expressionService)
+	functionHandler := handlers.NewFunctionHandler(functionService)
+
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	body, _ := json.Marshal(map[string]string{"login": "funcuser", "password": "funcpass123"})
+	rr := httptest.NewRecorder()
+	authHandler.Register(rr, httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register user: %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(body)))
+	var login map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &login)
+	token := login["token"]
+
+	defineFunction := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/functions", bytes.NewBufferString(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(functionHandler.DefineFunction)).ServeHTTP(rr, req)
+		return rr
+	}
+	calculate := func(expression string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]string{"expression": expression})
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBuffer(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := defineFunction(`{"definition":"hyp(a,b) = sqrt(a*a+b*b)"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := defineFunction(`{"definition":"fact(n) = n*fact(n-1)"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected recursive definition to be rejected, got %d", rr.Code)
+	}
+
+	if rr := calculate("hyp(3,4)*2"); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	if rr := calculate("hyp(3)"); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected wrong arity to be rejected, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/functions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(functionHandler.GetFunctions)).ServeHTTP(rr, req)
+
+	var functions []struct {
+		Name   string   `json:"name"`
+		Params []string `json:"params"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &functions); err != nil {
+		t.Fatalf("Failed to parse functions response: %v", err)
+	}
+	if len(functions) != 1 || functions[0].Name != "hyp" || len(functions[0].Params) != 2 {
+		t.Errorf("Expected only hyp(a,b) to be stored, got %+v", functions)
+	}
+}
+
+func TestExactModeWorkflow(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "0")
+
+	dbPath := "./test_exact.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	authService := services.NewAuthService(db, "test-secret-key")
+	expressionService := services.NewExpressionService(db)
+
+	authHandler := handlers.NewAuthHandler(authService)
+	calculateHandler := handlers.NewCalculateHandler(expressionService)
+	expressionHandler := handlers.NewExpressionHandler(expressionService)
+	taskHandler := handlers.NewTaskHandler(expressionService)
+
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	body, _ := json.Marshal(map[string]string{"login": "exactuser", "password": "exactpass123"})
+	rr := httptest.NewRecorder()
+	authHandler.Register(rr, httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register user: %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(body)))
+	var login map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &login)
+	token := login["token"]
+
+	calculate := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := calculate(`{"expression":"sqrt(2)","mode":"exact"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected inexact function to be rejected, got %d", rr.Code)
+	}
+	if rr := calculate(`{"expression":"1+1","mode":"symbolic"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected unknown mode to be rejected, got %d", rr.Code)
+	}
+
+	rr = calculate(`{"expression":"0.1+0.2","mode":"exact"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	var created map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	rr = httptest.NewRecorder()
+	taskHandler.GetTask(rr, httptest.NewRequest("GET", "/internal/task", nil))
+	var task models.Task
+	if err := json.Unmarshal(rr.Body.Bytes(), &task); err != nil {
+		t.Fatalf("Failed to parse task: %v", err)
+	}
+	left, _ := evaluator.ParseValue(task.Arg1)
+	right, _ := evaluator.ParseValue(task.Arg2)
+	result, err := evaluator.ApplyValue(task.Operation, left, right)
+	if err != nil {
+		t.Fatalf("Task failed: %v", err)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"result": result.Float(), "value": result.String()})
+	rr = httptest.NewRecorder()
+	taskHandler.SubmitTask(rr, httptest.NewRequest("POST", "/internal/task/"+task.ID, bytes.NewBuffer(payload)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to submit task: %d %s", rr.Code, rr.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/expressions/"+created["id"], nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	authMiddleware(http.HandlerFunc(expressionHandler.GetExpression)).ServeHTTP(rr, req)
+
+	var expression struct {
+		Status  string  `json:"status"`
+		Result  float64 `json:"result"`
+		Mode    string  `json:"mode"`
+		Exact   string  `json:"exact"`
+		Decimal string  `json:"decimal"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &expression); err != nil {
+		t.Fatalf("Failed to parse expression response: %v", err)
+	}
+	if expression.Status != "done" || expression.Mode != "exact" || expression.Exact != "3/10" ||
+		expression.Decimal != "0.3" || expression.Result != 0.3 {
+		t.Errorf("Unexpected exact expression %+v", expression)
+	}
+}
+
+func TestComplexWorkflow(t *testing.T) {
+	dbPath := "./test_complex.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	authService := services.NewAuthService(db, "test-secret-key")
+	expressionService := services.NewExpressionService(db)
+
+	authHandler := handlers.NewAuthHandler(authService)
+	calculateHandler := handlers.NewCalculateHandler(expressionService)
+	expressionHandler := handlers.NewExpressionHandler(expressionService)
+	taskHandler := handlers.NewTaskHandler(expressionService)
+
+	authMiddleware := middleware.AuthMiddleware(authService)
+
+	body, _ := json.Marshal(map[string]string{"login": "complexuser", "password": "complexpass123"})
+	rr := httptest.NewRecorder()
+	authHandler.Register(rr, httptest.NewRequest("POST", "/api/v1/register", bytes.NewBuffer(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register user: %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	authHandler.Login(rr, httptest.NewRequest("POST", "/api/v1/login", bytes.NewBuffer(body)))
+	var login map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &login)
+	token := login["token"]
+
+	getExpression := func(payload string) map[string]interface{} {
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(payload))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(calculateHandler.Calculate)).ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", rr.Code, rr.Body.String())
+		}
+		var created map[string]string
+		json.Unmarshal(rr.Body.Bytes(), &created)
+
+		results := map[string]evaluator.Value{}
+		for attempts := 0; attempts < 10; attempts++ {
+			rr = httptest.NewRecorder()
+			taskHandler.GetTask(rr, httptest.NewRequest("GET", "/internal/task", nil))
+			if rr.Code != http.StatusOK {
+				break
+			}
+			var task models.Task
+			if err := json.Unmarshal(rr.Body.Bytes(), &task); err != nil {
+				t.Fatalf("Failed to parse task: %v", err)
+			}
+			var args []evaluator.Value
+			for _, arg := range []string{task.Arg1, task.Arg2} {
+				if value, ok := results[strings.TrimPrefix(arg, "$")]; ok {
+					args = append(args, value)
+				} else if value, err := evaluator.ParseValue(arg); err == nil {
+					args = append(args, value)
+				}
+			}
+			result, err := evaluator.ApplyValue(task.Operation, args...)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			results[task.ID] = result
+
+			payload, _ := json.Marshal(map[string]interface{}{"result": result.Float(), "value": result.String()})
+			rr = httptest.NewRecorder()
+			taskHandler.SubmitTask(rr, httptest.NewRequest("POST", "/internal/task/"+task.ID, bytes.NewBuffer(payload)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Failed to submit task: %d %s", rr.Code, rr.Body.String())
+			}
+		}
+
+		req = httptest.NewRequest("GET", "/api/v1/expressions/"+created["id"], nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr = httptest.NewRecorder()
+		authMiddleware(http.HandlerFunc(expressionHandler.GetExpression)).ServeHTTP(rr, req)
+		var expression map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &expression); err != nil {
+			t.Fatalf("Failed to parse expression response: %v", err)
+		}
+		return expression
+	}
+
+	expression := getExpression(`{"expression":"(1+2i)*3 - sqrt(-4)"}`)
+	if expression["status"] != "done" || expression["result"] != 3.0 || expression["imag"] != 4.0 {
+		t.Errorf("Expected 3+4i, got %+v", expression)
+	}
+
+	expression = getExpression(`{"expression":"(1+i)*(1-i)"}`)
+	if _, ok := expression["imag"]; ok || expression["status"] != "done" || expression["result"] != 2.0 {
+		t.Errorf("Expected a plain real result, got %+v", expression)
+	}
+}
+
+func TestUnitsWorkflow(t *testing.T) {
+	dbPath := "./test_units.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+	calculateHandler := handlers.NewCalculateHandler(expressionService)
+	expressionHandler := handlers.NewExpressionHandler(expressionService)
+
+	calculate := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(payload))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &services.Claims{UserID: 1}))
+		rr := httptest.NewRecorder()
+		calculateHandler.Calculate(rr, req)
+		return rr
+	}
+
+	rr := calculate(`{"expression":"2 km to mi"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	var created map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	req := httptest.NewRequest("GET", "/api/v1/expressions/"+created["id"], nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &services.Claims{UserID: 1}))
+	rr = httptest.NewRecorder()
+	expressionHandler.GetExpression(rr, req)
+	var expression map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &expression); err != nil {
+		t.Fatalf("Failed to parse expression response: %v", err)
+	}
+	if result, ok := expression["result"].(float64); !ok || math.Abs(result-2000/1609.344) > 1e-12 || expression["unit"] != "mi" {
+		t.Errorf("Expected a result in miles, got %+v", expression)
+	}
+
+	rr = calculate(`{"expression":"3 m + 2 kg"}`)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d. Body: %s", rr.Code, rr.Body.String())
+	}
+	var failure struct {
+		Error          string                `json:"error"`
+		DimensionError *units.DimensionError `json:"dimension_error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &failure); err != nil {
+		t.Fatalf("Failed to parse error response: %v", err)
+	}
+	if failure.DimensionError == nil || failure.DimensionError.Code != units.CodeDimensionMismatch ||
+		failure.DimensionError.Left != "m" || failure.DimensionError.Right != "kg" {
+		t.Errorf("Expected a dimension mismatch, got %s", rr.Body.String())
+	}
+}
+
+func TestProgramWorkflow(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "0")
+
+	dbPath := "./test_program.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	literal, err := expressionService.CreateExpression(1, "x = 5; y = -x; y")
+	if err != nil {
+		t.Fatalf("Failed to create literal program: %v", err)
+	}
+	stored, err := expressionService.GetExpression(literal.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || *stored.Result != -5 || assigned(stored.Assignments, "x") != 5 || assigned(stored.Assignments, "y") != -5 {
+		t.Errorf("Expected literal program to complete immediately, got %+v", stored)
+	}
+
+	program, err := expressionService.CreateExpression(1, "x = 2+3; y = x*4; y - x")
+	if err != nil {
+		t.Fatalf("Failed to create program: %v", err)
+	}
+
+	var pending []*models.Task
+	for {
+		task, err := expressionService.GetNextTask()
+		if err != nil {
+			break
+		}
+		pending = append(pending, task)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("Expected 3 tasks with x shared, got %d", len(pending))
+	}
+
+	resolve := func(arg string) (float64, bool) {
+		if !strings.HasPrefix(arg, "$") {
+			value, err := strconv.ParseFloat(arg, 64)
+			return value, err == nil
+		}
+		dependency, err := expressionService.GetTaskByID(strings.TrimPrefix(arg, "$"))
+		if err != nil || dependency.Result == nil {
+			return 0, false
+		}
+		return *dependency.Result, true
+	}
+
+	for len(pending) > 0 {
+		var waiting []*models.Task
+		for _, task := range pending {
+			left, ok := resolve(task.Arg1)
+			right, ok2 := resolve(task.Arg2)
+			if !ok || !ok2 {
+				waiting = append(waiting, task)
+				continue
+			}
+			result, err := evaluator.Apply(task.Operation, left, right)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			if err := expressionService.SubmitTaskResult(task.ID, result); err != nil {
+				t.Fatalf("Failed to submit result: %v", err)
+			}
+		}
+		if len(waiting) == len(pending) {
+			t.Fatalf("Tasks have unresolved dependencies: %+v", waiting)
+		}
+		pending = waiting
+	}
+
+	stored, err = expressionService.GetExpression(program.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || *stored.Result != 15 {
+		t.Errorf("Expected result 15, got status %s", stored.Status)
+	}
+	if assigned(stored.Assignments, "x") != 5 || assigned(stored.Assignments, "y") != 20 {
+		t.Errorf("Expected assignments x=5 y=20, got %v", stored.Assignments)
+	}
+}
+
+func TestSharedSubexpressionWorkflow(t *testing.T) {
+	dbPath := "./test_shared.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	expr, err := expressionService.CreateExpression(1, "(2*3) + (2*3)*(3*2)")
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	if expr.Dispatched != 3 {
+		t.Errorf("Expected 3 dispatched operations, got %d", expr.Dispatched)
+	}
+
+	resolve := func(arg string) (float64, bool) {
+		if !strings.HasPrefix(arg, "$") {
+			value, err := strconv.ParseFloat(arg, 64)
+			return value, err == nil
+		}
+		dependency, err := expressionService.GetTaskByID(strings.TrimPrefix(arg, "$"))
+		if err != nil || dependency.Result == nil {
+			return 0, false
+		}
+		return *dependency.Result, true
+	}
+
+	var pending []*models.Task
+	for {
+		task, err := expressionService.GetNextTask()
+		if err != nil {
+			break
+		}
+		pending = append(pending, task)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("Expected 3 tasks with 2*3 shared, got %d", len(pending))
+	}
+
+	for len(pending) > 0 {
+		var waiting []*models.Task
+		for _, task := range pending {
+			left, ok := resolve(task.Arg1)
+			right, ok2 := resolve(task.Arg2)
+			if !ok || !ok2 {
+				waiting = append(waiting, task)
+				continue
+			}
+			result, err := evaluator.Apply(task.Operation, left, right)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			if err := expressionService.SubmitTaskResult(task.ID, result); err != nil {
+				t.Fatalf("Failed to submit result: %v", err)
+			}
+		}
+		if len(waiting) == len(pending) {
+			t.Fatalf("Tasks have unresolved dependencies: %+v", waiting)
+		}
+		pending = waiting
+	}
+
+	stored, err := expressionService.GetExpression(expr.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || *stored.Result != 42 {
+		t.Errorf("Expected result 42, got status %s", stored.Status)
+	}
+}
+
+func TestConditionalWorkflow(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "0")
+
+	dbPath := "./test_conditional.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	expr, err := expressionService.CreateExpression(1, "x = 3; if(x*2 > 5, x+10, sqrt(x)) * 2")
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	resolve := func(arg string) (evaluator.Value, bool) {
+		if !strings.HasPrefix(arg, "$") {
+			value, err := evaluator.ParseValue(arg)
+			return value, err == nil
+		}
+		dependency, err := expressionService.GetTaskByID(strings.TrimPrefix(arg, "$"))
+		if err != nil || dependency.Status != "done" {
+			return nil, false
+		}
+		if dependency.Value != nil {
+			value, err := evaluator.ParseValue(*dependency.Value)
+			return value, err == nil
+		}
+		return evaluator.Float(*dependency.Result), true
+	}
+
+	var computed []string
+	var pending []*models.Task
+	for attempts := 0; attempts < 20; attempts++ {
+		for {
+			task, err := expressionService.GetNextTask()
+			if err != nil {
+				break
+			}
+			pending = append(pending, task)
+		}
+
+		var waiting []*models.Task
+		for _, task := range pending {
+			args := []string{task.Arg1}
+			if task.Arg2 != "" {
+				args = append(args, task.Arg2)
+			}
+			var values []evaluator.Value
+			for _, arg := range args {
+				if value, ok := resolve(arg); ok {
+					values = append(values, value)
+				}
+			}
+			if len(values) != len(args) {
+				waiting = append(waiting, task)
+				continue
+			}
+			result, err := evaluator.ApplyValue(task.Operation, values...)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			value := result.String()
+			if err := expressionService.SubmitTaskValue(task.ID, result.Float(), &value); err != nil {
+				t.Fatalf("Failed to submit result: %v", err)
+			}
+			computed = append(computed, task.Operation)
+		}
+		pending = waiting
+	}
+
+	stored, err := expressionService.GetExpression(expr.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || *stored.Result != 26 {
+		t.Fatalf("Expected result 26, got status %s", stored.Status)
+	}
+	if strings.Join(computed, " ") != "* > + *" {
+		t.Errorf("Expected only the selected branch to reach agents, got %v", computed)
+	}
+
+	tasks, err := db.GetTasksByExpressionID(expr.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Operation == "sqrt" && task.Status != "skipped" {
+			t.Errorf("Expected the unselected branch to be skipped, got %s", task.Status)
+		}
+	}
+}
+
+func TestRangeWorkflow(t *testing.T) {
+	dbPath := "./test_range.db"
+	defer os.Remove(dbPath)
+	t.Setenv("RANGE_CHUNK_SIZE", "1000")
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	expr, err := expressionService.CreateExpression(1, "n = 5000; sum(i, 1, n, i^2) / prod(k, 1, 3, k)")
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	resolve := func(arg string) (evaluator.Value, bool) {
+		if !strings.HasPrefix(arg, "$") {
+			value, err := evaluator.ParseValue(arg)
+			return value, err == nil
+		}
+		dependency, err := expressionService.GetTaskByID(strings.TrimPrefix(arg, "$"))
+		if err != nil || dependency.Status != "done" {
+			return nil, false
+		}
+		return evaluator.Float(*dependency.Result), true
+	}
+
+	chunks := 0
+	var pending []*models.Task
+	for attempts := 0; attempts < 20; attempts++ {
+		for {
+			task, err := expressionService.GetNextTask()
+			if err != nil {
+				break
+			}
+			pending = append(pending, task)
+		}
+
+		var waiting []*models.Task
+		for _, task := range pending {
+			left, ok := resolve(task.Arg1)
+			right, ok2 := resolve(task.Arg2)
+			if !ok || !ok2 {
+				waiting = append(waiting, task)
+				continue
+			}
+
+			var result evaluator.Value
+			if task.Body != "" {
+				chunks++
+				body, err := parser.Parse(task.Body)
+				if err != nil {
+					t.Fatalf("Failed to parse chunk body: %v", err)
+				}
+				from, _ := evaluator.RangeBound(left)
+				to, _ := evaluator.RangeBound(right)
+				result, err = evaluator.ReduceRange(task.Operation, task.Index, from, to, body, nil, "")
+				if err != nil {
+					t.Fatalf("Chunk %s failed: %v", task.ID, err)
+				}
+			} else if result, err = evaluator.ApplyValue(task.Operation, left, right); err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			if err := expressionService.SubmitTaskResult(task.ID, result.Float()); err != nil {
+				t.Fatalf("Failed to submit result: %v", err)
+			}
+		}
+		pending = waiting
+	}
+
+	stored, err := expressionService.GetExpression(expr.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || *stored.Result != 41679167500/6.0 {
+		t.Fatalf("Expected result %v, got %+v", 41679167500/6.0, stored)
+	}
+	if chunks != 6 {
+		t.Errorf("Expected 5 chunks for the sum and 1 for the product, got %d", chunks)
+	}
+}
+
+func TestNumericWorkflow(t *testing.T) {
+	dbPath := "./test_numeric.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	expr, err := expressionService.CreateExpression(1, "integrate(x^2, 0, 3) + solve(x^2 - 2, x, 0, 2)")
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	if len(expr.Numerics) != 2 {
+		t.Fatalf("Expected 2 numeric calls, got %+v", expr.Numerics)
+	}
+
+	results := map[string]float64{}
+	for attempts := 0; attempts < 100; attempts++ {
+		task, err := expressionService.GetNextTask()
+		if err != nil {
+			break
+		}
+		report := &models.TaskResult{}
+		if task.Body != "" {
+			body, err := parser.Parse(task.Body)
+			if err != nil {
+				t.Fatalf("Failed to parse task body: %v", err)
+			}
+			from, _ := evaluator.ParseValue(task.Arg1)
+			to, _ := evaluator.ParseValue(task.Arg2)
+			estimate, err := evaluator.EstimateNumeric(task.Operation, task.Index, from.Float(), to.Float(), body, nil)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			report = &models.TaskResult{Result: estimate.Value, ErrorEstimate: &estimate.Error, Iterations: estimate.Iterations}
+		} else {
+			args := []float64{}
+			for _, arg := range []string{task.Arg1, task.Arg2} {
+				if value, ok := results[strings.TrimPrefix(arg, "$")]; ok {
+					args = append(args, value)
+				} else if value, err := evaluator.ParseValue(arg); err == nil {
+					args = append(args, value.Float())
+				}
+			}
+			if report.Result, err = evaluator.Apply(task.Operation, args[0], args[1]); err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+		}
+		results[task.ID] = report.Result
+		if err := expressionService.SubmitTaskReport(task.ID, report); err != nil {
+			t.Fatalf("Failed to submit report: %v", err)
+		}
+	}
+
+	stored, err := expressionService.GetExpression(expr.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != "done" || math.Abs(*stored.Result-(9+math.Sqrt2)) > 1e-9 {
+		t.Fatalf("Expected result %v, got %+v", 9+math.Sqrt2, stored)
+	}
+	for _, numeric := range stored.Numerics {
+		if numeric.Result == nil || numeric.ErrorEstimate == nil || numeric.Iterations == 0 {
+			t.Errorf("Expected numeric result with error estimate and iterations, got %+v", numeric)
+		}
+	}
+	if stored.Numerics[0].Call != "integrate(x^2, 0, 3)" || len(stored.Numerics[0].Tasks) != evaluator.IntegrationSegments {
+		t.Errorf("Unexpected integration summary %+v", stored.Numerics[0])
+	}
+}
+
+func TestFailedTaskWorkflow(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "0")
+	dbPath := "./test_failed.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+
+	started := time.Now()
+	if _, err := expressionService.CreateExpression(1, "sum(i, 1, 100000, sum(j, 1, 100000, i*j))"); err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the nested sum to be planned without evaluating it, took %v", elapsed)
+	}
+	if _, err := expressionService.CreateExpression(1, "3 m + 2 kg"); err == nil {
+		t.Error("Expected the dimension mismatch to be rejected before planning")
+	}
+
+	expr, err := expressionService.CreateExpression(1, "1/0 + 2*3")
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	for {
+		task, err := expressionService.GetNextTask()
+		if err != nil {
+			break
+		}
+		if task.ExpressionID != expr.ID {
+			continue
+		}
+		message := "деление на ноль"
+		if err := expressionService.SubmitTaskReport(task.ID, &models.TaskResult{Error: &message}); err != nil {
+			t.Fatalf("Failed to submit failure: %v", err)
+		}
+		break
+	}
+
+	stored, err := expressionService.GetExpression(expr.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get expression: %v", err)
+	}
+	if stored.Status != models.StatusFailed || stored.Error == nil || *stored.Error != "деление на ноль" || stored.Result != nil {
+		t.Errorf("Expected failed expression with the task error, got %+v", stored)
+	}
+	tasks, err := db.GetTasksByExpressionID(expr.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Status == "pending" || task.Status == "waiting" {
+			t.Errorf("Expected task %s to be cancelled, got %s", task.ID, task.Status)
+		}
+	}
+}
+
+func TestSimplifiedWorkflow(t *testing.T) {
+	dbPath := "./test_simplified.db"
+	defer os.Remove(dbPath)
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	expressionService := services.NewExpressionService(db)
+	source := "y = 3; (y+1)*(y+1) - (y+1)^2 + y*2"
+
+	plain, err := expressionService.CreateExpressionFromRequest(1, &models.RequestBody{Expression: source})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	simplified, err := expressionService.CreateExpressionFromRequest(1, &models.RequestBody{Expression: source, Simplify: true})
+	if err != nil {
+		t.Fatalf("Failed to create simplified expression: %v", err)
+	}
+	if simplified.Dispatched >= plain.Dispatched {
+		t.Errorf("Expected simplification to dispatch fewer tasks, got %d and %d", simplified.Dispatched, plain.Dispatched)
+	}
+	if simplified.Expression != source {
+		t.Errorf("Expected original expression to be stored, got %s", simplified.Expression)
+	}
+
+	if _, err := expressionService.CreateExpressionFromRequest(1, &models.RequestBody{Expression: "x/(x-x)", Simplify: true}); err == nil {
+		t.Errorf("Expected error for division by zero after simplification")
+	}
+}
+
+func TestBaselineSchemaMigration(t *testing.T) {
+	dbPath := "./test_baseline_schema.db"
+	defer os.Remove(dbPath)
+
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	for _, query := range []string{
+		`CREATE TABLE expressions (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			expression TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			result REAL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE tasks (
+			id TEXT PRIMARY KEY,
+			expression_id TEXT NOT NULL,
+			arg1 TEXT NOT NULL,
+			arg2 TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			operation_time INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			result REAL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	} {
+		if _, err := legacy.Exec(query); err != nil {
+			t.Fatalf("Failed to create legacy table: %v", err)
+		}
+	}
+	legacy.Close()
+
+	db, err := services.NewDatabaseService(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	defer db.Close()
+
+	expression, err := services.NewExpressionService(db).CreateExpression(1, "-(2*3)")
+	if err != nil {
+		t.Fatalf("Expected unary task to be stored in a legacy tasks table, got %v", err)
+	}
+	tasks, err := db.GetTasksByExpressionID(expression.ID)
+	if err != nil || len(tasks) != 2 || tasks[1].Arg2 != "" {
+		t.Errorf("Expected multiplication and negation tasks, got %+v, %v", tasks, err)
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	dbPath := "./test_errors.db"
 	defer os.Remove(dbPath)
@@ -285,6 +1356,38 @@ func TestErrorHandling(t *testing.T) {
 	authService := services.NewAuthService(db, "test-secret-key")
 	authHandler := handlers.NewAuthHandler(authService)
 
+	t.Run("Structured Parse Error", func(t *testing.T) {
+		calculateHandler := handlers.NewCalculateHandler(services.NewExpressionService(db))
+
+		req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression":"(1 + 2) * / 3"}`))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserContextKey, &services.Claims{UserID: 1, Login: "parser"}))
+		rr := httptest.NewRecorder()
+
+		calculateHandler.Calculate(rr, req)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d", rr.Code)
+		}
+
+		var response struct {
+			Error      string `json:"error"`
+			ParseError struct {
+				Code    string `json:"code"`
+				Offset  int    `json:"offset"`
+				Column  int    `json:"column"`
+				Token   string `json:"token"`
+				Snippet string `json:"snippet"`
+			} `json:"parse_error"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response.Error == "" || response.ParseError.Code != "misplaced_operator" || response.ParseError.Offset != 10 ||
+			response.ParseError.Column != 11 || response.ParseError.Token != "/" ||
+			response.ParseError.Snippet != "(1 + 2) * / 3\n          ^" {
+			t.Errorf("Unexpected error response %s", rr.Body.String())
+		}
+	})
+
 	t.Run("Duplicate User Registration", func(t *testing.T) {
 		reqBody := map[string]string{
 			"login":    "duplicate",
@@ -328,3 +1431,10 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func assigned(assignments map[string]models.Value, name string) float64 {
+	if value, ok := assignments[name]; ok && value.Result != nil {
+		return *value.Result
+	}
+	return math.NaN()
+}
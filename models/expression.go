@@ -8,6 +8,7 @@ const (
 	StatusPending   ExpressionStatus = "pending"
 	StatusComputing ExpressionStatus = "computing"
 	StatusDone      ExpressionStatus = "done"
+	StatusFailed    ExpressionStatus = "failed"
 )
 
 type Expression struct {
@@ -15,7 +16,40 @@ type Expression struct {
 	UserID     int              `json:"user_id" db:"user_id"`
 	Expression string           `json:"expression" db:"expression"`
 	Status     ExpressionStatus `json:"status" db:"status"`
-	Result     *float64         `json:"result,omitempty" db:"result"`
-	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time        `json:"updated_at" db:"updated_at"`
+	Value
+	Error        *string            `json:"error,omitempty" db:"error"`
+	Mode         string             `json:"mode,omitempty" db:"mode"`
+	Variables    map[string]float64 `json:"variables,omitempty" db:"variables"`
+	Lists        map[string]string  `json:"lists,omitempty" db:"lists"`
+	Assignments  map[string]Value   `json:"assignments,omitempty" db:"assignments"`
+	Folded       int                `json:"folded_operations" db:"folded"`
+	Dispatched   int                `json:"dispatched_operations" db:"dispatched"`
+	Depth        int                `json:"depth" db:"depth"`
+	CriticalPath int64              `json:"critical_path_ms" db:"critical_path"`
+	Numerics     []NumericResult    `json:"numerics,omitempty" db:"numerics"`
+	ResultRef    string             `json:"-" db:"result_ref"`
+	Bindings     map[string]string  `json:"-" db:"bindings"`
+	CreatedAt    time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+type Value struct {
+	Result  *float64    `json:"result,omitempty" db:"result"`
+	Exact   *string     `json:"exact,omitempty" db:"exact"`
+	Decimal *string     `json:"decimal,omitempty" db:"decimal"`
+	Lower   *float64    `json:"lower,omitempty" db:"lower"`
+	Upper   *float64    `json:"upper,omitempty" db:"upper"`
+	Imag    *float64    `json:"imag,omitempty" db:"imag"`
+	Unit    *string     `json:"unit,omitempty" db:"unit"`
+	Vector  []float64   `json:"vector,omitempty" db:"vector"`
+	Matrix  [][]float64 `json:"matrix,omitempty" db:"matrix"`
+}
+
+type NumericResult struct {
+	Function      string   `json:"function"`
+	Call          string   `json:"call"`
+	Result        *float64 `json:"result,omitempty"`
+	ErrorEstimate *float64 `json:"error_estimate,omitempty"`
+	Iterations    int      `json:"iterations"`
+	Tasks         []string `json:"tasks"`
 }
@@ -25,7 +25,7 @@ func TestExpression_JSONSerialization(t *testing.T) {
 		UserID:     1,
 		Expression: "2+2",
 		Status:     StatusDone,
-		Result:     &result,
+		Value:      Value{Result: &result},
 		CreatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
 		UpdatedAt:  time.Date(2023, 1, 1, 0, 0, 1, 0, time.UTC),
 	}
@@ -69,7 +69,6 @@ func TestExpression_JSONSerialization_NilResult(t *testing.T) {
 		UserID:     1,
 		Expression: "2+2",
 		Status:     StatusPending,
-		Result:     nil,
 		CreatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
 		UpdatedAt:  time.Date(2023, 1, 1, 0, 0, 1, 0, time.UTC),
 	}
@@ -94,3 +93,49 @@ func TestExpression_JSONSerialization_NilResult(t *testing.T) {
 		t.Errorf("Expected nil result, got %v", unmarshaled.Result)
 	}
 }
+
+func TestExpression_JSONSerialization_Assignments(t *testing.T) {
+	expr := Expression{
+		ID:          "test-id",
+		Expression:  "x = 2+3; x*2",
+		Status:      StatusDone,
+		Assignments: map[string]Value{"x": {Result: &[]float64{5}[0]}, "v": {Vector: []float64{1, 2}}},
+		ResultRef:   "$test-id_task2",
+		Bindings:    map[string]string{"x": "$test-id_task1"},
+	}
+
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatalf("Failed to marshal expression: %v", err)
+	}
+
+	jsonStr := string(data)
+	if !contains(jsonStr, `"assignments":{"v":{"vector":[1,2]},"x":{"result":5}}`) {
+		t.Errorf("Expected assignments in JSON, got %s", jsonStr)
+	}
+	if contains(jsonStr, "task1") || contains(jsonStr, "task2") {
+		t.Errorf("Task references should not be serialized, got %s", jsonStr)
+	}
+}
+
+func TestExpression_JSONSerialization_Matrix(t *testing.T) {
+	expr := Expression{
+		ID:         "test-id",
+		Expression: "[[1, 2], [3, 4]]",
+		Status:     StatusDone,
+		Value:      Value{Matrix: [][]float64{{1, 2}, {3, 4}}},
+	}
+
+	data, err := json.Marshal(expr)
+	if err != nil {
+		t.Fatalf("Failed to marshal expression: %v", err)
+	}
+
+	jsonStr := string(data)
+	if !contains(jsonStr, `"matrix":[[1,2],[3,4]]`) {
+		t.Errorf("Expected matrix rows in JSON, got %s", jsonStr)
+	}
+	if contains(jsonStr, "result") || contains(jsonStr, "vector") {
+		t.Errorf("Expected only the matrix result, got %s", jsonStr)
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Function struct {
+	UserID     int       `json:"-" db:"user_id"`
+	Name       string    `json:"name" db:"name"`
+	Params     []string  `json:"params" db:"params"`
+	Definition string    `json:"definition" db:"definition"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type FunctionRequest struct {
+	Definition string `json:"definition"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFunction_JSONSerialization(t *testing.T) {
+	function := Function{
+		UserID:     7,
+		Name:       "hyp",
+		Params:     []string{"a", "b"},
+		Definition: "hyp(a,b) = sqrt(a*a+b*b)",
+		UpdatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	data, err := json.Marshal(function)
+	if err != nil {
+		t.Fatalf("Failed to marshal function: %v", err)
+	}
+
+	jsonStr := string(data)
+	if contains(jsonStr, "user_id") {
+		t.Error("UserID should not be serialized to JSON")
+	}
+	if !contains(jsonStr, `"name":"hyp"`) || !contains(jsonStr, `"params":["a","b"]`) {
+		t.Errorf("Name and params should be serialized to JSON, got %s", jsonStr)
+	}
+
+	var req FunctionRequest
+	if err := json.Unmarshal([]byte(`{"definition":"sq(x) = x*x"}`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if req.Definition != "sq(x) = x*x" {
+		t.Errorf("Expected definition to be parsed, got %q", req.Definition)
+	}
+}
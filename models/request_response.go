@@ -2,6 +2,14 @@ package models
 
 type RequestBody struct {
 	Expression string `json:"expression"`
+	Mode       string `json:"mode,omitempty"`
+	Simplify   bool   `json:"simplify,omitempty"`
+}
+
+type SymbolicRequest struct {
+	Expression string `json:"expression"`
+	Operation  string `json:"operation"`
+	Variable   string `json:"variable,omitempty"`
 }
 
 type ResponseBody struct {
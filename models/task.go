@@ -6,11 +6,28 @@ type Task struct {
 	ID            string    `json:"id" db:"id"`
 	ExpressionID  string    `json:"expression_id" db:"expression_id"`
 	Arg1          string    `json:"arg1" db:"arg1"`
-	Arg2          string    `json:"arg2" db:"arg2"`
+	Arg2          string    `json:"arg2,omitempty" db:"arg2"`
+	Arg3          string    `json:"arg3,omitempty" db:"arg3"`
 	Operation     string    `json:"operation" db:"operation"`
 	OperationTime int64     `json:"operation_time" db:"operation_time"`
 	Status        string    `json:"status" db:"status"`
 	Result        *float64  `json:"result,omitempty" db:"result"`
+	Value         *string   `json:"value,omitempty" db:"value"`
+	Condition     string    `json:"condition,omitempty" db:"condition"`
+	Guard         string    `json:"guard,omitempty" db:"guard"`
+	Index         string    `json:"index,omitempty" db:"range_index"`
+	Body          string    `json:"body,omitempty" db:"body"`
+	ErrorEstimate *float64  `json:"error_estimate,omitempty" db:"error_estimate"`
+	Iterations    int       `json:"iterations,omitempty" db:"iterations"`
+	Error         *string   `json:"error,omitempty" db:"error"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
+
+type TaskResult struct {
+	Result        float64  `json:"result"`
+	Value         *string  `json:"value,omitempty"`
+	ErrorEstimate *float64 `json:"error_estimate,omitempty"`
+	Iterations    int      `json:"iterations,omitempty"`
+	Error         *string  `json:"error,omitempty"`
+}
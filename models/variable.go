@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Variable struct {
+	UserID    int       `json:"-" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Value     float64   `json:"value" db:"value"`
+	Values    []float64 `json:"values,omitempty" db:"vals"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type VariableRequest struct {
+	Name   string    `json:"name"`
+	Value  *float64  `json:"value"`
+	Values []float64 `json:"values"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestVariable_JSONSerialization(t *testing.T) {
+	variable := Variable{
+		UserID:    7,
+		Name:      "rate",
+		Value:     0.07,
+		UpdatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	data, err := json.Marshal(variable)
+	if err != nil {
+		t.Fatalf("Failed to marshal variable: %v", err)
+	}
+
+	jsonStr := string(data)
+	if contains(jsonStr, "user_id") {
+		t.Error("UserID should not be serialized to JSON")
+	}
+	if !contains(jsonStr, `"name":"rate"`) || !contains(jsonStr, `"value":0.07`) {
+		t.Errorf("Name and value should be serialized to JSON, got %s", jsonStr)
+	}
+	if contains(jsonStr, "values") {
+		t.Errorf("Values should be omitted for a scalar variable, got %s", jsonStr)
+	}
+
+	variable.Values = []float64{1, 2.5}
+	data, _ = json.Marshal(variable)
+	if !contains(string(data), `"values":[1,2.5]`) {
+		t.Errorf("Values should be serialized for a list variable, got %s", data)
+	}
+}
+
+func TestVariableRequest_MissingValue(t *testing.T) {
+	var req VariableRequest
+	if err := json.Unmarshal([]byte(`{"name":"rate"}`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+	if req.Value != nil || req.Values != nil {
+		t.Errorf("Expected nil value and values when they are missing, got %+v", req)
+	}
+}
@@ -0,0 +1,69 @@
+package parser
+
+type Node interface {
+	Pos() int
+}
+
+type Number struct {
+	Value   float64
+	Literal string
+	Integer bool
+	At      int
+}
+
+type Ident struct {
+	Name string
+	At   int
+}
+
+type UnaryOp struct {
+	Op      string
+	Operand Node
+	At      int
+}
+
+type BinaryOp struct {
+	Op    string
+	Left  Node
+	Right Node
+	At    int
+}
+
+type Unit struct {
+	Name string
+	At   int
+}
+
+type Call struct {
+	Name string
+	Args []Node
+	At   int
+}
+
+func (n *Number) Pos() int   { return n.At }
+func (n *Ident) Pos() int    { return n.At }
+func (n *UnaryOp) Pos() int  { return n.At }
+func (n *BinaryOp) Pos() int { return n.At }
+func (n *Unit) Pos() int     { return n.At }
+func (n *Call) Pos() int     { return n.At }
+
+type Statement struct {
+	Name  string
+	Value Node
+	At    int
+}
+
+type Program struct {
+	Statements []*Statement
+}
+
+func (s *Statement) IsAssignment() bool {
+	return s.Name != ""
+}
+
+type Definition struct {
+	Name   string
+	Params []string
+	Body   Node
+	At     int
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	CodeEmptyExpression      = "empty_expression"
+	CodeInvalidCharacter     = "invalid_character"
+	CodeInvalidNumber        = "invalid_number"
+	CodeNumberOverflow       = "number_overflow"
+	CodeUnexpectedToken      = "unexpected_token"
+	CodeUnexpectedEnd        = "unexpected_end"
+	CodeMisplacedOperator    = "misplaced_operator"
+	CodeUnclosedParenthesis  = "unclosed_parenthesis"
+	CodeUnmatchedParenthesis = "unmatched_parenthesis"
+	CodeUnclosedBracket      = "unclosed_bracket"
+	CodeExpectedSeparator    = "expected_separator"
+	CodeInvalidDefinition    = "invalid_definition"
+	CodeInvalidUnit          = "invalid_unit"
+	CodeInvalidMatrix        = "invalid_matrix"
+)
+
+type ParseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Offset  int    `json:"offset"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Token   string `json:"token,omitempty"`
+	Snippet string `json:"snippet"`
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+func newParseError(src, code string, position int, token, format string, args ...interface{}) *ParseError {
+	offset := 0
+	for ; position > 0 && offset < len(src); position-- {
+		_, size := utf8.DecodeRuneInString(src[offset:])
+		offset += size
+	}
+	lineStart := strings.LastIndexByte(src[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(src[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += offset
+	}
+
+	var caret strings.Builder
+	for _, r := range src[lineStart:offset] {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	width := utf8.RuneCountInString(token)
+	if width == 0 {
+		width = 1
+	}
+	caret.WriteString(strings.Repeat("^", width))
+
+	return &ParseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Offset:  offset,
+		Line:    strings.Count(src[:offset], "\n") + 1,
+		Column:  utf8.RuneCountInString(src[lineStart:offset]) + 1,
+		Token:   token,
+		Snippet: strings.TrimRight(src[lineStart:lineEnd], "\r") + "\n" + caret.String(),
+	}
+}
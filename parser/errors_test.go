@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		src     string
+		code    string
+		offset  int
+		column  int
+		token   string
+		snippet string
+	}{
+		{"2+*2", CodeMisplacedOperator, 2, 3, "*", "2+*2\n  ^"},
+		{"", CodeEmptyExpression, 0, 1, "", "\n^"},
+		{"2 $ 3", CodeInvalidCharacter, 2, 3, "$", "2 $ 3\n  ^"},
+		{"(2+3", CodeUnclosedParenthesis, 0, 1, "(", "(2+3\n^"},
+		{"2+", CodeUnexpectedEnd, 2, 3, "", "2+\n  ^"},
+		{"sqrt(2 3", CodeExpectedSeparator, 7, 8, "3", "sqrt(2 3\n       ^"},
+		{"sqrt(2", CodeUnclosedParenthesis, 4, 5, "(", "sqrt(2\n    ^"},
+		{"[1, 2", CodeUnclosedBracket, 0, 1, "[", "[1, 2\n^"},
+		{"[1 2]", CodeExpectedSeparator, 3, 4, "2", "[1 2]\n   ^"},
+		{"[[1], 2]", CodeInvalidMatrix, 0, 1, "[", "[[1], 2]\n^"},
+		{"2+3)", CodeUnmatchedParenthesis, 3, 4, ")", "2+3)\n   ^"},
+		{"1 + 0b", CodeInvalidNumber, 4, 5, "0b", "1 + 0b\n    ^^"},
+		{"0x1FFFFFFFFFFFFFFFF", CodeNumberOverflow, 0, 1, "0x1FFFFFFFFFFFFFFFF", "0x1FFFFFFFFFFFFFFFF\n^^^^^^^^^^^^^^^^^^^"},
+		{"2 pi", CodeUnexpectedToken, 2, 3, "pi", "2 pi\n  ^^"},
+		{"3 m to kgs", CodeInvalidUnit, 7, 8, "kgs", "3 m to kgs\n       ^^^"},
+		{"2 m^x", CodeInvalidUnit, 4, 5, "x", "2 m^x\n    ^"},
+		{"αβ + * 1", CodeMisplacedOperator, 7, 6, "*", "αβ + * 1\n     ^"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) expected *ParseError, got %v", tt.src, err)
+			continue
+		}
+		if parseErr.Code != tt.code || parseErr.Offset != tt.offset || parseErr.Column != tt.column ||
+			parseErr.Token != tt.token || parseErr.Snippet != tt.snippet {
+			t.Errorf("Parse(%q) = %+v, expected code %s offset %d column %d token %q snippet %q",
+				tt.src, parseErr, tt.code, tt.offset, tt.column, tt.token, tt.snippet)
+		}
+		if parseErr.Error() != parseErr.Message || parseErr.Message == "" {
+			t.Errorf("Parse(%q) expected the message as error text, got %q", tt.src, parseErr.Error())
+		}
+	}
+}
+
+func TestParseError_MessagePosition(t *testing.T) {
+	tests := []struct {
+		src     string
+		message string
+	}{
+		{"αβ + * 1", `неверная расстановка операторов: "*" в позиции 6`},
+		{"2 ± 1)", "неверно расставлены скобки: лишняя ')' в позиции 6"},
+		{"π € 1", `недопустимый символ '€' в позиции 3`},
+		{"αβ + 0b", `некорректное число "0b" в позиции 6`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) expected *ParseError, got %v", tt.src, err)
+			continue
+		}
+		if parseErr.Message != tt.message {
+			t.Errorf("Parse(%q) message = %q, expected %q", tt.src, parseErr.Message, tt.message)
+		}
+	}
+}
+
+func TestParseError_Multiline(t *testing.T) {
+	_, err := ParseProgram("x = 1;\n\ty = x +;\nx")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *ParseError, got %v", err)
+	}
+	if parseErr.Code != CodeUnexpectedToken || parseErr.Line != 2 || parseErr.Column != 9 || parseErr.Snippet != "\ty = x +;\n\t       ^" {
+		t.Errorf("Unexpected position %+v", parseErr)
+	}
+}
+
+func TestParseError_Definition(t *testing.T) {
+	_, err := ParseDefinition("f(x, 1) = x")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *ParseError, got %v", err)
+	}
+	if parseErr.Code != CodeInvalidDefinition || parseErr.Offset != 5 || parseErr.Token != "1" {
+		t.Errorf("Unexpected error %+v", parseErr)
+	}
+}
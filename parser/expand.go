@@ -0,0 +1,130 @@
+package parser
+
+import "fmt"
+
+type expander struct {
+	functions map[string]*Definition
+	maxNodes  int
+	nodes     int
+	active    []string
+}
+
+type argument struct {
+	node   Node
+	scope  map[string]*argument
+	at     int
+	active []string
+}
+
+func Expand(node Node, functions map[string]*Definition, maxNodes int) (Node, error) {
+	e := &expander{functions: functions, maxNodes: maxNodes}
+	return e.expand(node, nil, -1)
+}
+
+func ExpandProgram(program *Program, functions map[string]*Definition, maxNodes int) (*Program, error) {
+	e := &expander{functions: functions, maxNodes: maxNodes}
+	expanded := &Program{Statements: make([]*Statement, len(program.Statements))}
+	for i, stmt := range program.Statements {
+		value, err := e.expand(stmt.Value, nil, -1)
+		if err != nil {
+			return nil, err
+		}
+		expanded.Statements[i] = &Statement{Name: stmt.Name, Value: value, At: stmt.At}
+	}
+	return expanded, nil
+}
+
+func (e *expander) expand(node Node, scope map[string]*argument, at int) (Node, error) {
+	if ident, ok := node.(*Ident); ok {
+		if arg, bound := scope[ident.Name]; bound {
+			return e.expandArgument(arg)
+		}
+	}
+	if call, ok := node.(*Call); ok {
+		if def, defined := e.functions[call.Name]; defined {
+			return e.inline(def, call, scope, at)
+		}
+	}
+
+	if e.maxNodes > 0 && e.nodes >= e.maxNodes {
+		return nil, fmt.Errorf("выражение слишком велико после подстановки функций: более %d узлов", e.maxNodes)
+	}
+	e.nodes++
+
+	pos := node.Pos()
+	if at >= 0 {
+		pos = at
+	}
+
+	switch n := node.(type) {
+	case *Number:
+		return &Number{Value: n.Value, Literal: n.Literal, Integer: n.Integer, At: pos}, nil
+	case *Ident:
+		return &Ident{Name: n.Name, At: pos}, nil
+	case *Unit:
+		return &Unit{Name: n.Name, At: pos}, nil
+	case *UnaryOp:
+		operand, err := e.expand(n.Operand, scope, at)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOp{Op: n.Op, Operand: operand, At: pos}, nil
+	case *BinaryOp:
+		left, err := e.expand(n.Left, scope, at)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.expand(n.Right, scope, at)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryOp{Op: n.Op, Left: left, Right: right, At: pos}, nil
+	case *Call:
+		args := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			expanded, err := e.expand(arg, scope, at)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = expanded
+		}
+		return &Call{Name: n.Name, Args: args, At: pos}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный узел выражения %T", node)
+	}
+}
+
+func (e *expander) inline(def *Definition, call *Call, scope map[string]*argument, at int) (Node, error) {
+	pos := call.At
+	if at >= 0 {
+		pos = at
+	}
+
+	for _, name := range e.active {
+		if name == def.Name {
+			return nil, fmt.Errorf("рекурсивный вызов функции %q в позиции %d", def.Name, pos+1)
+		}
+	}
+	if len(call.Args) != len(def.Params) {
+		return nil, fmt.Errorf("функция %s ожидает %d аргументов, получено %d", def.Name, len(def.Params), len(call.Args))
+	}
+
+	caller := append([]string(nil), e.active...)
+	bound := make(map[string]*argument, len(def.Params))
+	for i, param := range def.Params {
+		bound[param] = &argument{node: call.Args[i], scope: scope, at: at, active: caller}
+	}
+
+	e.active = append(e.active, def.Name)
+	body, err := e.expand(def.Body, bound, pos)
+	e.active = caller
+	return body, err
+}
+
+func (e *expander) expandArgument(arg *argument) (Node, error) {
+	active := e.active
+	e.active = arg.active
+	node, err := e.expand(arg.node, arg.scope, arg.at)
+	e.active = active
+	return node, err
+}
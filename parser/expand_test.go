@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func definitions(t *testing.T, sources ...string) map[string]*Definition {
+	t.Helper()
+	functions := make(map[string]*Definition, len(sources))
+	for _, src := range sources {
+		def, err := ParseDefinition(src)
+		if err != nil {
+			t.Fatalf("ParseDefinition(%q) unexpected error: %v", src, err)
+		}
+		functions[def.Name] = def
+	}
+	return functions
+}
+
+func TestExpand(t *testing.T) {
+	functions := definitions(t,
+		"hyp(a,b) = sqrt(a*a+b*b)",
+		"sq(x) = x*x",
+		"norm(a,b) = sqrt(sq(a)+sq(b))",
+		"inc(x) = x+1",
+		"answer() = 42",
+	)
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"hyp(3,4)", "sqrt(((3 * 3) + (4 * 4)))"},
+		{"hyp(1+2, b)", "sqrt((((1 + 2) * (1 + 2)) + (b * b)))"},
+		{"norm(a, 2)", "sqrt(((a * a) + (2 * 2)))"},
+		{"inc(inc(1))", "((1 + 1) + 1)"},
+		{"answer()*2", "(42 * 2)"},
+		{"sqrt(sq(2))", "sqrt((2 * 2))"},
+		{"1+2", "(1 + 2)"},
+	}
+
+	for _, tt := range tests {
+		tree, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+		}
+		expanded, err := Expand(tree, functions, 0)
+		if err != nil {
+			t.Errorf("Expand(%q) unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got := render(expanded); got != tt.expected {
+			t.Errorf("Expand(%q) = %s, want %s", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestExpand_CallSitePositions(t *testing.T) {
+	functions := definitions(t, "half(x) = x/2")
+	tree, err := Parse("1 + half(8)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expanded, err := Expand(tree, functions, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	division := expanded.(*BinaryOp).Right.(*BinaryOp)
+	if division.Pos() != 4 || division.Right.Pos() != 4 {
+		t.Errorf("Expected inlined body at call offset 4, got %d and %d", division.Pos(), division.Right.Pos())
+	}
+	if division.Left.Pos() != 9 {
+		t.Errorf("Expected argument to keep its offset 9, got %d", division.Left.Pos())
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	functions := definitions(t,
+		"loop(x) = loop(x)+1",
+		"ping(x) = pong(x)",
+		"pong(x) = ping(x)",
+		"id(x) = x",
+		"pair(a,b) = a+b",
+		"d1(x) = x+x",
+		"d2(x) = d1(d1(x))",
+		"d3(x) = d2(d2(x))",
+		"d4(x) = d3(d3(x))",
+	)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"loop(1)", "рекурсивный вызов"},
+		{"ping(1)", "рекурсивный вызов"},
+		{"id(loop(1))", "рекурсивный вызов"},
+		{"pair(1)", "ожидает 2 аргументов"},
+		{"d4(1)", "слишком велико"},
+	}
+
+	for _, tt := range tests {
+		tree, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+		}
+		_, err = Expand(tree, functions, 100)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expand(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+
+	tree, _ := Parse("id(id(id(1)))")
+	if _, err := Expand(tree, functions, 100); err != nil {
+		t.Errorf("Expected nested non-recursive calls to expand, got %v", err)
+	}
+	tree, _ = Parse("d3(1)")
+	if _, err := Expand(tree, functions, 100); err != nil {
+		t.Errorf("Expected d3 to fit into 100 nodes, got %v", err)
+	}
+}
+
+func TestExpandProgram(t *testing.T) {
+	functions := definitions(t, "scale(a) = a*10")
+	program, err := ParseProgram("a = 2; scale(a+1) - a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expanded, err := ExpandProgram(program, functions, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := render(expanded.Statements[1].Value); got != "(((a + 1) * 10) - a)" {
+		t.Errorf("Unexpected expansion %s", got)
+	}
+	if expanded.Statements[0].Name != "a" || render(program.Statements[1].Value) != "(scale((a + 1)) - a)" {
+		t.Errorf("Expected original program to stay untouched")
+	}
+}
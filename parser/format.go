@@ -0,0 +1,200 @@
+package parser
+
+import "strings"
+
+const (
+	precedenceConvert = 0
+	precedenceUnary   = 11
+	precedencePower   = 12
+	precedenceAtom    = 13
+)
+
+var precedences = map[string]int{
+	"||":  1,
+	"&&":  2,
+	"<":   3,
+	"<=":  3,
+	"==":  3,
+	"!=":  3,
+	">=":  3,
+	">":   3,
+	"|":   4,
+	"xor": 5,
+	"&":   6,
+	"<<":  7,
+	">>":  7,
+	"+":   8,
+	"-":   8,
+	"*":   9,
+	"/":   9,
+	"//":  9,
+	"%":   9,
+	"±":   10,
+	"^":   precedencePower,
+}
+
+func Format(node Node) string {
+	var b strings.Builder
+	format(&b, node)
+	return b.String()
+}
+
+func FormatProgram(program *Program) string {
+	statements := make([]string, len(program.Statements))
+	for i, stmt := range program.Statements {
+		statements[i] = Format(stmt.Value)
+		if stmt.IsAssignment() {
+			statements[i] = stmt.Name + " = " + statements[i]
+		}
+	}
+	return strings.Join(statements, "; ")
+}
+
+func format(b *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case *Number:
+		b.WriteString(n.Literal)
+	case *Ident:
+		b.WriteString(n.Name)
+	case *Unit:
+		b.WriteString(n.Name)
+	case *UnaryOp:
+		b.WriteString(n.Op)
+		formatOperand(b, n.Operand, precedence(n.Operand) < precedenceUnary)
+	case *BinaryOp:
+		prec := precedence(n)
+		if n.Op == "^" {
+			formatOperand(b, n.Left, precedence(n.Left) <= prec)
+		} else {
+			formatOperand(b, n.Left, precedence(n.Left) < prec || ((n.Op == "*" || n.Op == "/") && isQuantity(n.Left)))
+		}
+		if prec <= precedences["+"] {
+			b.WriteString(" " + n.Op + " ")
+		} else {
+			b.WriteString(n.Op)
+		}
+		if n.Op == "^" {
+			formatOperand(b, n.Right, precedence(n.Right) < prec)
+		} else {
+			formatOperand(b, n.Right, precedence(n.Right) <= prec)
+		}
+	case *Call:
+		if n.Name == IntervalCall && len(n.Args) == 2 {
+			b.WriteString("[")
+			format(b, n.Args[0])
+			b.WriteString("; ")
+			format(b, n.Args[1])
+			b.WriteString("]")
+			return
+		}
+		if n.Name == VectorCall && len(n.Args) > 0 {
+			formatElements(b, n.Args)
+			return
+		}
+		if n.Name == MatrixCall && isMatrixLiteral(n) {
+			b.WriteString("[")
+			for i, row := range n.Args {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				formatElements(b, row.(*Call).Args)
+			}
+			b.WriteString("]")
+			return
+		}
+		if (n.Name == UnitCall || n.Name == ConvertCall) && len(n.Args) == 2 {
+			formatOperand(b, n.Args[0], n.Name == UnitCall && precedence(n.Args[0]) < precedenceUnary)
+			if n.Name == ConvertCall {
+				b.WriteString(" " + ConvertCall)
+			}
+			b.WriteString(" ")
+			format(b, n.Args[1])
+			return
+		}
+		if n.Name == FactorialCall && len(n.Args) == 1 {
+			formatOperand(b, n.Args[0], precedence(n.Args[0]) < precedenceAtom)
+			b.WriteString("!")
+			return
+		}
+		if imaginary, ok := imaginaryLiteral(n); ok {
+			b.WriteString(imaginary.Literal + imaginaryUnit)
+			return
+		}
+		b.WriteString(n.Name + "(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			format(b, arg)
+		}
+		b.WriteString(")")
+	}
+}
+
+func formatElements(b *strings.Builder, elems []Node) {
+	b.WriteString("[")
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		format(b, elem)
+	}
+	b.WriteString("]")
+}
+
+func isMatrixLiteral(n *Call) bool {
+	if len(n.Args) == 0 {
+		return false
+	}
+	for _, arg := range n.Args {
+		row, ok := arg.(*Call)
+		if !ok || row.Name != VectorCall || len(row.Args) == 0 || len(row.Args) != len(n.Args[0].(*Call).Args) {
+			return false
+		}
+	}
+	return true
+}
+
+func imaginaryLiteral(n *Call) (*Number, bool) {
+	if n.Name != ComplexCall || len(n.Args) != 2 {
+		return nil, false
+	}
+	re, ok := n.Args[0].(*Number)
+	if !ok || re.Literal != "0" {
+		return nil, false
+	}
+	im, ok := n.Args[1].(*Number)
+	return im, ok
+}
+
+func isQuantity(node Node) bool {
+	call, ok := node.(*Call)
+	return ok && call.Name == UnitCall && len(call.Args) == 2
+}
+
+func formatOperand(b *strings.Builder, node Node, parenthesize bool) {
+	if parenthesize {
+		b.WriteString("(")
+	}
+	format(b, node)
+	if parenthesize {
+		b.WriteString(")")
+	}
+}
+
+func precedence(node Node) int {
+	switch n := node.(type) {
+	case *UnaryOp:
+		return precedenceUnary
+	case *BinaryOp:
+		return precedences[n.Op]
+	case *Call:
+		if n.Name == UnitCall && len(n.Args) == 2 {
+			return precedences["*"]
+		}
+		if n.Name == ConvertCall && len(n.Args) == 2 {
+			return precedenceConvert
+		}
+	}
+	return precedenceAtom
+}
@@ -0,0 +1,82 @@
+package parser
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"1+2", "1 + 2"},
+		{"2*3+4", "2*3 + 4"},
+		{"2*(3+4)", "2*(3 + 4)"},
+		{"(1-2)-3", "1 - 2 - 3"},
+		{"1-(2-3)", "1 - (2 - 3)"},
+		{"a/(b*c)", "a/(b*c)"},
+		{"2^3^2", "2^3^2"},
+		{"(2^3)^2", "(2^3)^2"},
+		{"-x^2", "-x^2"},
+		{"(-x)^2", "(-x)^2"},
+		{"-(a+b)", "-(a + b)"},
+		{"a<b && c>=d || !e", "a < b && c >= d || !e"},
+		{"a & b | c xor d", "a & b | c xor d"},
+		{"x << 2 + 1", "x << 2 + 1"},
+		{"sqrt(x+1)", "sqrt(x + 1)"},
+		{"if(x>0, x, -x)", "if(x > 0, x, -x)"},
+		{"max(1, 2, 3)", "max(1, 2, 3)"},
+		{"1.50e3", "1.50e3"},
+		{"3.2 ± 0.1", "3.2±0.1"},
+		{"(1+2)±0.1*x", "(1 + 2)±0.1*x"},
+		{"[a+1,b]^2", "[a + 1, b]^2"},
+		{"(3+4i)*(1-2.5i)", "(3 + 4i)*(1 - 2.5i)"},
+		{"complex(0, 2)^2", "2i^2"},
+		{"complex(1, 2)", "complex(1, 2)"},
+		{"3m*2 s^-1 + 4 km / h", "(3 m)*(2 s^-1) + 4 km/h"},
+		{"-(2 kg*m/s/s)", "-(2 kg*m/s^2)"},
+		{"(1 m)^2 * x", "(1 m)^2*x"},
+		{"x*(3 m) to km", "x*(3 m) to km"},
+		{"(x to km) + 1", "(x to km) + 1"},
+		{"factorial(n - 1)*n!^2", "(n - 1)!*n!^2"},
+		{"factorial(-3) + factorial(2i)", "(-3)! + 2i!"},
+		{"[1,2,3+x]*2", "[1, 2, 3 + x]*2"},
+		{"[[1,2],[3,4]]", "[[1, 2], [3, 4]]"},
+		{"[[1,2,3]]", "[[1, 2, 3]]"},
+		{"[x]", "[x]"},
+		{"vector(1, 2)", "[1, 2]"},
+		{"interval(1, x)", "[1; x]"},
+		{"[a+1;b]^2", "[a + 1; b]^2"},
+		{"matrix([1, 2], 3)", "matrix([1, 2], 3)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := Format(node)
+			if got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+
+			reparsed, err := Parse(got)
+			if err != nil {
+				t.Fatalf("Failed to parse formatted expression %q: %v", got, err)
+			}
+			if render(reparsed) != render(node) {
+				t.Errorf("Expected round trip to keep %s, got %s", render(node), render(reparsed))
+			}
+		})
+	}
+}
+
+func TestFormatProgram(t *testing.T) {
+	program, err := ParseProgram("x = 2+3; y = x*(x-1); y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "x = 2 + 3; y = x*(x - 1); y"
+	if got := FormatProgram(program); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,34 @@
+package parser
+
+type JSONNode struct {
+	Type    string      `json:"type"`
+	Value   string      `json:"value,omitempty"`
+	Name    string      `json:"name,omitempty"`
+	Op      string      `json:"op,omitempty"`
+	Operand *JSONNode   `json:"operand,omitempty"`
+	Left    *JSONNode   `json:"left,omitempty"`
+	Right   *JSONNode   `json:"right,omitempty"`
+	Args    []*JSONNode `json:"args,omitempty"`
+}
+
+func ToJSON(node Node) *JSONNode {
+	switch n := node.(type) {
+	case *Number:
+		return &JSONNode{Type: "number", Value: n.Literal}
+	case *Ident:
+		return &JSONNode{Type: "ident", Name: n.Name}
+	case *Unit:
+		return &JSONNode{Type: "unit", Name: n.Name}
+	case *UnaryOp:
+		return &JSONNode{Type: "unary", Op: n.Op, Operand: ToJSON(n.Operand)}
+	case *BinaryOp:
+		return &JSONNode{Type: "binary", Op: n.Op, Left: ToJSON(n.Left), Right: ToJSON(n.Right)}
+	case *Call:
+		args := make([]*JSONNode, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = ToJSON(arg)
+		}
+		return &JSONNode{Type: "call", Name: n.Name, Args: args}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"42", `{"type":"number","value":"42"}`},
+		{"x", `{"type":"ident","name":"x"}`},
+		{"-x", `{"type":"unary","op":"-","operand":{"type":"ident","name":"x"}}`},
+		{"x*2", `{"type":"binary","op":"*","left":{"type":"ident","name":"x"},"right":{"type":"number","value":"2"}}`},
+		{"max(x, 1.5)", `{"type":"call","name":"max","args":[{"type":"ident","name":"x"},{"type":"number","value":"1.5"}]}`},
+		{"2 km/h", `{"type":"call","name":"unit","args":[{"type":"number","value":"2"},{"type":"unit","name":"km/h"}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			data, err := json.Marshal(ToJSON(node))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, data)
+			}
+		})
+	}
+}
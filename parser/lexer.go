@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenNumber
+	TokenOperator
+	TokenLParen
+	TokenRParen
+	TokenIdent
+	TokenComma
+	TokenAssign
+	TokenSemicolon
+	TokenLBracket
+	TokenRBracket
+)
+
+var operators = []string{
+	"**", "//", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "^", "%", "&", "|", "<", ">", "!", "±",
+}
+
+var wordOperators = map[string]bool{
+	"xor": true,
+}
+
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+func Tokenize(src string) ([]Token, error) {
+	var tokens []Token
+	i, runes, counted := 0, 0, 0
+	at := func(offset int) int {
+		runes += utf8.RuneCountInString(src[counted:offset])
+		counted = offset
+		return runes
+	}
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '0' && basePrefix(src[i:]) != nil:
+			start := i
+			isBaseDigit := basePrefix(src[i:])
+			i += 2
+			for i < len(src) && isBaseDigit(rune(src[i])) {
+				i++
+			}
+			if i == start+2 {
+				return nil, newParseError(src, CodeInvalidNumber, at(start), src[start:i], "некорректное число %q в позиции %d", src[start:i], at(start)+1)
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: src[start:i], Pos: at(start)})
+		case isDigit(r) || r == '.':
+			start := i
+			for i < len(src) && (isDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			i += exponentLength(src[i:])
+			if strings.HasPrefix(src[i:], "i") && !continuesIdent(src[i+1:]) {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: src[start:i], Pos: at(start)})
+		case operatorAt(src[i:]) != "":
+			op := operatorAt(src[i:])
+			tokens = append(tokens, Token{Kind: TokenOperator, Text: op, Pos: at(i)})
+			i += len(op)
+		case isLetter(r):
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if !isLetter(r) && !isDigit(r) {
+					break
+				}
+				i += size
+			}
+			kind := TokenIdent
+			if wordOperators[src[start:i]] {
+				kind = TokenOperator
+			}
+			tokens = append(tokens, Token{Kind: kind, Text: src[start:i], Pos: at(start)})
+		case r == ',':
+			tokens = append(tokens, Token{Kind: TokenComma, Text: ",", Pos: at(i)})
+			i += size
+		case r == '=':
+			tokens = append(tokens, Token{Kind: TokenAssign, Text: "=", Pos: at(i)})
+			i += size
+		case r == ';':
+			tokens = append(tokens, Token{Kind: TokenSemicolon, Text: ";", Pos: at(i)})
+			i += size
+		case r == '(':
+			tokens = append(tokens, Token{Kind: TokenLParen, Text: "(", Pos: at(i)})
+			i += size
+		case r == ')':
+			tokens = append(tokens, Token{Kind: TokenRParen, Text: ")", Pos: at(i)})
+			i += size
+		case r == '[':
+			tokens = append(tokens, Token{Kind: TokenLBracket, Text: "[", Pos: at(i)})
+			i += size
+		case r == ']':
+			tokens = append(tokens, Token{Kind: TokenRBracket, Text: "]", Pos: at(i)})
+			i += size
+		default:
+			return nil, newParseError(src, CodeInvalidCharacter, at(i), string(r), "недопустимый символ %q в позиции %d", r, at(i)+1)
+		}
+	}
+	tokens = append(tokens, Token{Kind: TokenEOF, Pos: at(len(src))})
+	return tokens, nil
+}
+
+func operatorAt(src string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(src, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func basePrefix(src string) func(rune) bool {
+	if len(src) < 2 {
+		return nil
+	}
+	switch src[1] {
+	case 'x', 'X':
+		return func(r rune) bool {
+			return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+		}
+	case 'b', 'B':
+		return func(r rune) bool { return r == '0' || r == '1' }
+	case 'o', 'O':
+		return func(r rune) bool { return r >= '0' && r <= '7' }
+	}
+	return nil
+}
+
+func exponentLength(src string) int {
+	if len(src) < 2 || (src[0] != 'e' && src[0] != 'E') {
+		return 0
+	}
+	n := 1
+	if src[n] == '+' || src[n] == '-' {
+		n++
+	}
+	digits := n
+	for n < len(src) && isDigit(rune(src[n])) {
+		n++
+	}
+	if n == digits {
+		return 0
+	}
+	return n
+}
+
+func continuesIdent(src string) bool {
+	r, _ := utf8.DecodeRuneInString(src)
+	return isLetter(r) || isDigit(r)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
@@ -0,0 +1,506 @@
+package parser
+
+import (
+	"calculator/units"
+	"strconv"
+	"strings"
+)
+
+const (
+	IntervalCall  = "interval"
+	ComplexCall   = "complex"
+	UnitCall      = "unit"
+	ConvertCall   = "to"
+	FactorialCall = "factorial"
+	VectorCall    = "vector"
+	MatrixCall    = "matrix"
+	imaginaryUnit = "i"
+)
+
+type parser struct {
+	src    string
+	tokens []Token
+	pos    int
+}
+
+func Parse(src string) (Node, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, newParseError(src, CodeEmptyExpression, 0, "", "пустое выражение")
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := p.peek(); tok.Kind {
+	case TokenEOF:
+		return node, nil
+	case TokenRParen:
+		return nil, p.errorAt(CodeUnmatchedParenthesis, tok, "неверно расставлены скобки: лишняя ')' в позиции %d", tok.Pos+1)
+	default:
+		return nil, p.errorAt(CodeUnexpectedToken, tok, "неожиданный токен %q в позиции %d", tok.Text, tok.Pos+1)
+	}
+}
+
+func ParseProgram(src string) (*Program, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, newParseError(src, CodeEmptyExpression, 0, "", "пустое выражение")
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	program := &Program{}
+	for {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		program.Statements = append(program.Statements, stmt)
+
+		switch tok := p.next(); tok.Kind {
+		case TokenSemicolon:
+			if p.peek().Kind == TokenEOF {
+				return program, nil
+			}
+		case TokenEOF:
+			return program, nil
+		case TokenRParen:
+			return nil, p.errorAt(CodeUnmatchedParenthesis, tok, "неверно расставлены скобки: лишняя ')' в позиции %d", tok.Pos+1)
+		default:
+			return nil, p.errorAt(CodeUnexpectedToken, tok, "неожиданный токен %q в позиции %d", tok.Text, tok.Pos+1)
+		}
+	}
+}
+
+func ParseDefinition(src string) (*Definition, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, newParseError(src, CodeEmptyExpression, 0, "", "пустое определение")
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	name := p.next()
+	if name.Kind != TokenIdent {
+		return nil, p.errorAt(CodeInvalidDefinition, name, "ожидалось имя функции в позиции %d, получено %q", name.Pos+1, name.Text)
+	}
+	if open := p.next(); open.Kind != TokenLParen {
+		return nil, p.errorAt(CodeInvalidDefinition, open, "ожидалась '(' после имени функции в позиции %d", open.Pos+1)
+	}
+
+	def := &Definition{Name: name.Text, At: name.Pos}
+	if p.peek().Kind == TokenRParen {
+		p.next()
+	} else {
+		for {
+			param := p.next()
+			if param.Kind != TokenIdent {
+				return nil, p.errorAt(CodeInvalidDefinition, param, "ожидалось имя параметра в позиции %d, получено %q", param.Pos+1, param.Text)
+			}
+			def.Params = append(def.Params, param.Text)
+
+			tok := p.next()
+			if tok.Kind == TokenRParen {
+				break
+			}
+			if tok.Kind != TokenComma {
+				return nil, p.errorAt(CodeInvalidDefinition, tok, "ожидалась ',' или ')' в позиции %d, получено %q", tok.Pos+1, tok.Text)
+			}
+		}
+	}
+
+	if assign := p.next(); assign.Kind != TokenAssign {
+		return nil, p.errorAt(CodeInvalidDefinition, assign, "ожидался '=' в позиции %d, получено %q", assign.Pos+1, assign.Text)
+	}
+	body, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != TokenEOF {
+		return nil, p.errorAt(CodeUnexpectedToken, tok, "неожиданный токен %q в позиции %d", tok.Text, tok.Pos+1)
+	}
+	def.Body = body
+	return def, nil
+}
+
+func (p *parser) parseStatement() (*Statement, error) {
+	tok := p.peek()
+	if tok.Kind == TokenIdent && p.tokens[p.pos+1].Kind == TokenAssign {
+		p.next()
+		p.next()
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &Statement{Name: tok.Text, Value: value, At: tok.Pos}, nil
+	}
+
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{Value: value, At: tok.Pos}, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != TokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseExpr() (Node, error) {
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.Kind == TokenIdent && tok.Text == ConvertCall && p.tokens[p.pos+1].Kind == TokenIdent; tok = p.peek() {
+		p.next()
+		unit, err := p.parseUnit()
+		if err != nil {
+			return nil, err
+		}
+		node = &Call{Name: ConvertCall, Args: []Node{node, unit}, At: tok.Pos}
+	}
+	return node, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	return p.parseBinary(p.parseBitOr, "<", "<=", "==", "!=", ">=", ">")
+}
+
+func (p *parser) parseBitOr() (Node, error) {
+	return p.parseBinary(p.parseBitXor, "|")
+}
+
+func (p *parser) parseBitXor() (Node, error) {
+	return p.parseBinary(p.parseBitAnd, "xor")
+}
+
+func (p *parser) parseBitAnd() (Node, error) {
+	return p.parseBinary(p.parseShift, "&")
+}
+
+func (p *parser) parseShift() (Node, error) {
+	return p.parseBinary(p.parseAdditive, "<<", ">>")
+}
+
+func (p *parser) parseAdditive() (Node, error) {
+	return p.parseBinary(p.parseTerm, "+", "-")
+}
+
+func (p *parser) parseTerm() (Node, error) {
+	return p.parseBinary(p.parseUncertainty, "*", "/", "//", "%")
+}
+
+func (p *parser) parseUncertainty() (Node, error) {
+	return p.parseBinary(p.parseUnary, "±")
+}
+
+func (p *parser) parseBinary(operand func() (Node, error), ops ...string) (Node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(ops...) {
+		op := p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOp{Op: op.Text, Left: left, Right: right, At: op.Pos}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.isOperator("+", "-", "!") {
+		op := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOp{Op: op.Text, Operand: operand, At: op.Pos}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (Node, error) {
+	base, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("!") {
+		op := p.next()
+		base = &Call{Name: FactorialCall, Args: []Node{base}, At: op.Pos}
+	}
+	if !p.isOperator("^", "**") {
+		return base, nil
+	}
+	op := p.next()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryOp{Op: "^", Left: base, Right: exponent, At: op.Pos}, nil
+}
+
+func (p *parser) parseFactor() (Node, error) {
+	tok := p.next()
+	switch tok.Kind {
+	case TokenNumber:
+		number, err := p.parseNumber(tok)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := number.(*Number); !ok || !p.unitAt(p.pos) {
+			return number, nil
+		}
+		unit, err := p.parseUnit()
+		if err != nil {
+			return nil, err
+		}
+		return &Call{Name: UnitCall, Args: []Node{number, unit}, At: tok.Pos}, nil
+	case TokenIdent:
+		if p.peek().Kind != TokenLParen {
+			return &Ident{Name: tok.Text, At: tok.Pos}, nil
+		}
+		return p.parseCall(tok)
+	case TokenLParen:
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.Kind != TokenRParen {
+			return nil, p.errorAt(CodeUnclosedParenthesis, tok, "несоответствие количества открывающих и закрывающих скобок: '(' в позиции %d не закрыта", tok.Pos+1)
+		}
+		return node, nil
+	case TokenLBracket:
+		return p.parseBracket(tok)
+	case TokenEOF:
+		return nil, p.errorAt(CodeUnexpectedEnd, tok, "неожиданный конец выражения")
+	case TokenOperator:
+		return nil, p.errorAt(CodeMisplacedOperator, tok, "неверная расстановка операторов: %q в позиции %d", tok.Text, tok.Pos+1)
+	default:
+		return nil, p.errorAt(CodeUnexpectedToken, tok, "неверная расстановка операторов: %q в позиции %d", tok.Text, tok.Pos+1)
+	}
+}
+
+func (p *parser) parseCall(name Token) (Node, error) {
+	open := p.next()
+	call := &Call{Name: name.Text, At: name.Pos}
+	if p.peek().Kind == TokenRParen {
+		p.next()
+		return call, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		switch tok := p.next(); tok.Kind {
+		case TokenComma:
+			continue
+		case TokenRParen:
+			return call, nil
+		case TokenEOF:
+			return nil, p.errorAt(CodeUnclosedParenthesis, open, "несоответствие количества открывающих и закрывающих скобок: '(' в позиции %d не закрыта", open.Pos+1)
+		default:
+			return nil, p.errorAt(CodeExpectedSeparator, tok, "ожидалась ',' или ')' в позиции %d, получено %q", tok.Pos+1, tok.Text)
+		}
+	}
+}
+
+func (p *parser) parseBracket(open Token) (Node, error) {
+	var elems []Node
+	rows := 0
+	for {
+		start := p.peek()
+		elem, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if row, ok := bracketRow(start, elem); ok {
+			if row.Name == MatrixCall {
+				return nil, p.errorAt(CodeInvalidMatrix, start, "поддерживаются только двумерные матрицы: '[' в позиции %d", start.Pos+1)
+			}
+			elem = row
+			rows++
+		}
+		elems = append(elems, elem)
+
+		tok := p.next()
+		if tok.Kind == TokenRBracket {
+			break
+		}
+		switch tok.Kind {
+		case TokenSemicolon:
+			if rows == 0 && len(elems) == 1 {
+				return p.parseInterval(open, elem)
+			}
+			return nil, p.errorAt(CodeExpectedSeparator, tok, "ожидалась ',' или ']' в позиции %d, получено %q", tok.Pos+1, tok.Text)
+		case TokenComma:
+		case TokenEOF:
+			return nil, p.errorAt(CodeUnclosedBracket, open, "скобка '[' в позиции %d не закрыта", open.Pos+1)
+		default:
+			return nil, p.errorAt(CodeExpectedSeparator, tok, "ожидалась ',' или ']' в позиции %d, получено %q", tok.Pos+1, tok.Text)
+		}
+	}
+
+	switch {
+	case rows == 0:
+		return &Call{Name: VectorCall, Args: elems, At: open.Pos}, nil
+	case rows != len(elems):
+		return nil, p.errorAt(CodeInvalidMatrix, open, "матрица '[' в позиции %d смешивает строки и числа", open.Pos+1)
+	}
+	for _, row := range elems[1:] {
+		if len(row.(*Call).Args) != len(elems[0].(*Call).Args) {
+			return nil, p.errorAt(CodeInvalidMatrix, open, "строки матрицы '[' в позиции %d имеют разную длину", open.Pos+1)
+		}
+	}
+	return &Call{Name: MatrixCall, Args: elems, At: open.Pos}, nil
+}
+
+func (p *parser) parseInterval(open Token, lo Node) (Node, error) {
+	hi, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := p.next(); tok.Kind {
+	case TokenRBracket:
+		return &Call{Name: IntervalCall, Args: []Node{lo, hi}, At: open.Pos}, nil
+	case TokenEOF:
+		return nil, p.errorAt(CodeUnclosedBracket, open, "интервал '[' в позиции %d не закрыт", open.Pos+1)
+	default:
+		return nil, p.errorAt(CodeExpectedSeparator, tok, "ожидалась ']' в позиции %d, получено %q", tok.Pos+1, tok.Text)
+	}
+}
+
+func bracketRow(start Token, node Node) (*Call, bool) {
+	call, ok := node.(*Call)
+	if !ok || start.Kind != TokenLBracket || call.At != start.Pos {
+		return nil, false
+	}
+	switch call.Name {
+	case VectorCall, MatrixCall:
+		return call, true
+	}
+	return nil, false
+}
+
+func (p *parser) parseUnit() (Node, error) {
+	start := p.peek()
+	var unit units.Unit
+	op := ""
+	for {
+		name := p.next()
+		if name.Kind != TokenIdent || !units.IsUnit(name.Text) {
+			return nil, p.errorAt(CodeInvalidUnit, name, "неизвестная единица измерения %q в позиции %d", name.Text, name.Pos+1)
+		}
+		expr := name.Text
+		if p.isOperator("^", "**") {
+			p.next()
+			sign := ""
+			if p.isOperator("+", "-") {
+				sign = strings.TrimPrefix(p.next().Text, "+")
+			}
+			exp := p.next()
+			if _, err := strconv.Atoi(exp.Text); exp.Kind != TokenNumber || err != nil {
+				return nil, p.errorAt(CodeInvalidUnit, exp, "ожидалась целая степень единицы в позиции %d, получено %q", exp.Pos+1, exp.Text)
+			}
+			expr += "^" + sign + exp.Text
+		}
+		term, err := units.Parse(expr)
+		if err != nil {
+			return nil, p.errorAt(CodeInvalidUnit, name, "%s", err.Error())
+		}
+
+		switch op {
+		case "":
+			unit = term
+		case "*":
+			unit = unit.Mul(term)
+		case "/":
+			unit = unit.Div(term)
+		}
+		if !p.isOperator("*", "/") || !p.unitAt(p.pos+1) {
+			break
+		}
+		op = p.next().Text
+	}
+	if unit.Dimensionless() {
+		return nil, p.errorAt(CodeInvalidUnit, start, "единица измерения в позиции %d безразмерна", start.Pos+1)
+	}
+	return &Unit{Name: unit.Name, At: start.Pos}, nil
+}
+
+func (p *parser) unitAt(pos int) bool {
+	tok := p.tokens[pos]
+	return tok.Kind == TokenIdent && units.IsUnit(tok.Text) && p.tokens[pos+1].Kind != TokenLParen
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	tok := p.peek()
+	if tok.Kind != TokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if tok.Text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseNumber(tok Token) (Node, error) {
+	if len(tok.Text) > 2 && tok.Text[0] == '0' && basePrefix(tok.Text) != nil {
+		value, err := strconv.ParseInt(tok.Text, 0, 64)
+		if err != nil {
+			return nil, p.errorAt(CodeNumberOverflow, tok, "число %s в позиции %d не помещается в 64 бита", tok.Text, tok.Pos+1)
+		}
+		return &Number{Value: float64(value), Literal: tok.Text, Integer: true, At: tok.Pos}, nil
+	}
+
+	literal := strings.TrimSuffix(tok.Text, imaginaryUnit)
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, p.errorAt(CodeInvalidNumber, tok, "некорректное число %q в позиции %d", tok.Text, tok.Pos+1)
+	}
+	_, intErr := strconv.ParseInt(literal, 10, 64)
+	number := &Number{Value: value, Literal: literal, Integer: intErr == nil, At: tok.Pos}
+	if literal != tok.Text {
+		zero := &Number{Literal: "0", Integer: true, At: tok.Pos}
+		return &Call{Name: ComplexCall, Args: []Node{zero, number}, At: tok.Pos}, nil
+	}
+	return number, nil
+}
+
+func (p *parser) errorAt(code string, tok Token, format string, args ...interface{}) error {
+	return newParseError(p.src, code, tok.Pos, tok.Text, format, args...)
+}
@@ -0,0 +1,466 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func render(node Node) string {
+	switch n := node.(type) {
+	case *Number:
+		return n.Literal
+	case *Ident:
+		return n.Name
+	case *Unit:
+		return n.Name
+	case *UnaryOp:
+		return fmt.Sprintf("(%s%s)", n.Op, render(n.Operand))
+	case *BinaryOp:
+		return fmt.Sprintf("(%s %s %s)", render(n.Left), n.Op, render(n.Right))
+	case *Call:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = render(arg)
+		}
+		return fmt.Sprintf("%s(%s)", n.Name, strings.Join(args, ", "))
+	}
+	return fmt.Sprintf("<%T>", node)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"2", "2"},
+		{"2+3", "(2 + 3)"},
+		{"2+3*4", "(2 + (3 * 4))"},
+		{"(2+3)*4", "((2 + 3) * 4)"},
+		{"1-2-3", "((1 - 2) - 3)"},
+		{"8/4/2", "((8 / 4) / 2)"},
+		{" 2 * ( 3 + 4 ) ", "(2 * (3 + 4))"},
+		{"((1.5))", "1.5"},
+		{"-5+3", "((-5) + 3)"},
+		{"2*-3", "(2 * (-3))"},
+		{"-(4+1)", "(-(4 + 1))"},
+		{"2++2", "(2 + (+2))"},
+		{"--2", "(-(-2))"},
+		{"-2*3", "((-2) * 3)"},
+		{"2^3", "(2 ^ 3)"},
+		{"2**3", "(2 ^ 3)"},
+		{"2^3^2", "(2 ^ (3 ^ 2))"},
+		{"2**3^2", "(2 ^ (3 ^ 2))"},
+		{"-2^2", "(-(2 ^ 2))"},
+		{"2^-1", "(2 ^ (-1))"},
+		{"3*2^2", "(3 * (2 ^ 2))"},
+		{"(1+0.05)^10", "((1 + 0.05) ^ 10)"},
+		{"sqrt(16)", "sqrt(16)"},
+		{"2*abs(-3+1)", "(2 * abs(((-3) + 1)))"},
+		{"-sin(0)^2", "(-(sin(0) ^ 2))"},
+		{"log10(ceil(2.5))", "log10(ceil(2.5))"},
+		{"f()", "f()"},
+		{"f(1, 2+3)", "f(1, (2 + 3))"},
+		{"pi*2", "(pi * 2)"},
+		{"1.5e-3", "1.5e-3"},
+		{"6.02E23*2", "(6.02E23 * 2)"},
+		{"2e+3-1", "(2e+3 - 1)"},
+		{"e^2", "(e ^ 2)"},
+		{"-tau/phi", "((-tau) / phi)"},
+		{"7//2%3", "((7 // 2) % 3)"},
+		{"1+2*3%4", "(1 + ((2 * 3) % 4))"},
+		{"1<<2+3", "(1 << (2 + 3))"},
+		{"1|2 xor 3&4<<1", "(1 | (2 xor (3 & (4 << 1))))"},
+		{"0xFF&0b1010", "(0xFF & 0b1010)"},
+		{"-0o17>>1", "((-0o17) >> 1)"},
+		{"a xor b", "(a xor b)"},
+		{"1<2==1", "((1 < 2) == 1)"},
+		{"a && b || c", "((a && b) || c)"},
+		{"a || b && c", "(a || (b && c))"},
+		{"!a && b", "((!a) && b)"},
+		{"!!1", "(!(!1))"},
+		{"1+2 < 3|4", "((1 + 2) < (3 | 4))"},
+		{"x >= 0 && x != 5", "((x >= 0) && (x != 5))"},
+		{"1<<2 <= 4>>1", "((1 << 2) <= (4 >> 1))"},
+		{"if(x > 0, x, -x)", "if((x > 0), x, (-x))"},
+		{"3.2±0.1", "(3.2 ± 0.1)"},
+		{"2*3±0.1^2", "(2 * (3 ± (0.1 ^ 2)))"},
+		{"-x±1", "((-x) ± 1)"},
+		{"[1, 2]", "vector(1, 2)"},
+		{"[1; 2]", "interval(1, 2)"},
+		{"[a+1; b]*2", "(interval((a + 1), b) * 2)"},
+		{"[a+1,b]*2", "(vector((a + 1), b) * 2)"},
+		{"[1]", "vector(1)"},
+		{"[1,2,3]", "vector(1, 2, 3)"},
+		{"[[1,2],[3,4]]", "matrix(vector(1, 2), vector(3, 4))"},
+		{"[[1,2,3]]*x", "(matrix(vector(1, 2, 3)) * x)"},
+		{"[[1,2]*2, 3, 4]", "vector((vector(1, 2) * 2), 3, 4)"},
+		{"3+4i", "(3 + complex(0, 4))"},
+		{"2.5e3i^2", "(complex(0, 2.5e3) ^ 2)"},
+		{"x*i", "(x * i)"},
+		{"2in", "unit(2, in)"},
+		{"3 m * 2 s^-1 + 4 km/h", "((unit(3, m) * unit(2, s^-1)) + unit(4, km/h))"},
+		{"9.81 kg*m/s^2", "unit(9.81, kg*m/s^2)"},
+		{"2 m^2 * x", "(unit(2, m^2) * x)"},
+		{"(1 m)^2", "(unit(1, m) ^ 2)"},
+		{"5 min + min(1, 2)", "(unit(5, min) + min(1, 2))"},
+		{"x to mph", "to(x, mph)"},
+		{"1 + 2 km to m to ft", "to(to((1 + unit(2, km)), m), ft)"},
+		{"sqrt(4 m^2 to cm^2)", "sqrt(to(unit(4, m^2), cm^2))"},
+		{"5!", "factorial(5)"},
+		{"-n!^2", "(-(factorial(n) ^ 2))"},
+		{"(n-k)!*k!", "(factorial((n - k)) * factorial(k))"},
+		{"3!!", "factorial(factorial(3))"},
+		{"n! != 1", "(factorial(n) != 1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			node, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+			}
+			if got := render(node); got != tt.expected {
+				t.Errorf("Parse(%q) = %s, expected %s", tt.expr, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"2+*2",
+		"2^",
+		"2***3",
+		"^2",
+		"2+",
+		"-",
+		"*2",
+		"(2+3",
+		"2+3)",
+		"()",
+		"1.2.3",
+		"2 3",
+		"2$3",
+		"sqrt(",
+		"2e",
+		"1e400",
+		"2 pi",
+		"x = 1",
+		"1; 2",
+		"sqrt(1,",
+		"sqrt(1 2)",
+		"sqrt 4",
+		"0x",
+		"0b102",
+		"0xFFFFFFFFFFFFFFFFF",
+		"2 & | 3",
+		"xor 1",
+		"1 <",
+		"1 = 2",
+		"5! 3",
+		"&& 1",
+		"1 ||",
+		"1 ! 2",
+		"1 === 2",
+		"±1",
+		"1±",
+		"[1, 2",
+		"[1 2]",
+		"[1; 2",
+		"[1; 2; 3]",
+		"[1, 2; 3]",
+		"[[1, 2]; 3]",
+		"[1, [2, 3]]",
+		"[[1, 2], [3]]",
+		"[[1, 2]*2, [3, 4]]",
+		"[[[1, 2], [3, 4]], [5, 6]]",
+		"[]",
+		"]",
+		"0x1i",
+		"x to",
+		"x to foo",
+		"x to 2",
+		"2 m^x",
+		"2 m^1.5",
+		"1 m/m",
+		"to mph",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Parse(expr); err == nil {
+				t.Errorf("Parse(%q) expected error", expr)
+			}
+		})
+	}
+}
+
+func TestParse_Positions(t *testing.T) {
+	node, err := Parse("(1 + 2) * 30")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mul, ok := node.(*BinaryOp)
+	if !ok || mul.Op != "*" {
+		t.Fatalf("Expected '*' at the root, got %s", render(node))
+	}
+	if mul.Pos() != 8 {
+		t.Errorf("Expected '*' at offset 8, got %d", mul.Pos())
+	}
+	if add := mul.Left.(*BinaryOp); add.Pos() != 3 {
+		t.Errorf("Expected '+' at offset 3, got %d", add.Pos())
+	}
+	if num := mul.Right.(*Number); num.Pos() != 10 {
+		t.Errorf("Expected 30 at offset 10, got %d", num.Pos())
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize("12.5*(3-1)**2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		kind TokenKind
+		text string
+		pos  int
+	}{
+		{TokenNumber, "12.5", 0},
+		{TokenOperator, "*", 4},
+		{TokenLParen, "(", 5},
+		{TokenNumber, "3", 6},
+		{TokenOperator, "-", 7},
+		{TokenNumber, "1", 8},
+		{TokenRParen, ")", 9},
+		{TokenOperator, "**", 10},
+		{TokenNumber, "2", 12},
+		{TokenEOF, "", 13},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, tok := range tokens {
+		if tok.Kind != expected[i].kind || tok.Text != expected[i].text || tok.Pos != expected[i].pos {
+			t.Errorf("Token %d: expected %+v, got %+v", i, expected[i], tok)
+		}
+	}
+}
+
+func TestTokenize_Operators(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"0xFF&0x0f", []string{"0xFF", "&", "0x0f"}},
+		{"0b1010|0o17", []string{"0b1010", "|", "0o17"}},
+		{"7//2%3", []string{"7", "//", "2", "%", "3"}},
+		{"1<<4>>2", []string{"1", "<<", "4", ">>", "2"}},
+		{"a xor b", []string{"a", "xor", "b"}},
+		{"xored", []string{"xored"}},
+		{"a<=b<c<<1", []string{"a", "<=", "b", "<", "c", "<<", "1"}},
+		{"a==b!=!c", []string{"a", "==", "b", "!=", "!", "c"}},
+		{"a&&b||c&d|e", []string{"a", "&&", "b", "||", "c", "&", "d", "|", "e"}},
+		{"[3.1,3.3]±0.1", []string{"[", "3.1", ",", "3.3", "]", "±", "0.1"}},
+		{"3+4i*i2", []string{"3", "+", "4i", "*", "i2"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.src)
+		if err != nil {
+			t.Errorf("Tokenize(%q) unexpected error: %v", tt.src, err)
+			continue
+		}
+		var texts []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			texts = append(texts, tok.Text)
+		}
+		if strings.Join(texts, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Tokenize(%q) = %v, expected %v", tt.src, texts, tt.expected)
+		}
+	}
+
+	tokens, err := Tokenize("a xor b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens[1].Kind != TokenOperator {
+		t.Errorf("Expected xor to be an operator token, got %+v", tokens[1])
+	}
+}
+
+func TestParse_IntegerLiterals(t *testing.T) {
+	tests := []struct {
+		expr    string
+		value   float64
+		integer bool
+	}{
+		{"0xFF", 255, true},
+		{"0b1010", 10, true},
+		{"0o17", 15, true},
+		{"42", 42, true},
+		{"4.0", 4, false},
+		{"1e3", 1000, false},
+		{"99999999999999999999", 1e20, false},
+	}
+
+	for _, tt := range tests {
+		node, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.expr, err)
+			continue
+		}
+		num, ok := node.(*Number)
+		if !ok {
+			t.Errorf("Parse(%q) expected a number, got %s", tt.expr, render(node))
+			continue
+		}
+		if num.Value != tt.value || num.Integer != tt.integer {
+			t.Errorf("Parse(%q) = %v (integer %v), expected %v (integer %v)", tt.expr, num.Value, num.Integer, tt.value, tt.integer)
+		}
+	}
+}
+
+func TestTokenize_ScientificNotation(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"6.02e23", []string{"6.02e23"}},
+		{"1.5e-3+1", []string{"1.5e-3", "+", "1"}},
+		{"2E+10", []string{"2E+10"}},
+		{"2e", []string{"2", "e"}},
+		{"2e-", []string{"2", "e", "-"}},
+		{"2*e", []string{"2", "*", "e"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.src)
+		if err != nil {
+			t.Errorf("Tokenize(%q) unexpected error: %v", tt.src, err)
+			continue
+		}
+		var texts []string
+		for _, tok := range tokens[:len(tokens)-1] {
+			texts = append(texts, tok.Text)
+		}
+		if strings.Join(texts, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("Tokenize(%q) = %v, expected %v", tt.src, texts, tt.expected)
+		}
+	}
+}
+
+func TestParseProgram(t *testing.T) {
+	program, err := ParseProgram("x = 2+3; y = x*4; y - x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		name  string
+		value string
+	}{
+		{"x", "(2 + 3)"},
+		{"y", "(x * 4)"},
+		{"", "(y - x)"},
+	}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d", len(expected), len(program.Statements))
+	}
+	for i, stmt := range program.Statements {
+		if stmt.Name != expected[i].name || render(stmt.Value) != expected[i].value {
+			t.Errorf("Statement %d: expected %s = %s, got %s = %s", i, expected[i].name, expected[i].value, stmt.Name, render(stmt.Value))
+		}
+		if stmt.IsAssignment() != (expected[i].name != "") {
+			t.Errorf("Statement %d: unexpected IsAssignment() = %v", i, stmt.IsAssignment())
+		}
+	}
+	if program.Statements[1].At != 9 {
+		t.Errorf("Expected second statement at offset 9, got %d", program.Statements[1].At)
+	}
+}
+
+func TestParseProgram_SingleExpression(t *testing.T) {
+	program, err := ParseProgram("2+2;")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(program.Statements) != 1 || program.Statements[0].IsAssignment() {
+		t.Errorf("Expected a single expression statement, got %+v", program.Statements)
+	}
+}
+
+func TestParseProgram_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		";",
+		"x = ",
+		"x = 1;;",
+		"= 1",
+		"2 = 1",
+		"x = y = 1",
+		"x = 1 y = 2",
+		"x = (1; 2)",
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			if _, err := ParseProgram(src); err == nil {
+				t.Errorf("ParseProgram(%q) expected error", src)
+			}
+		})
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	tests := []struct {
+		src    string
+		name   string
+		params string
+		body   string
+	}{
+		{"hyp(a,b) = sqrt(a*a+b*b)", "hyp", "a,b", "sqrt(((a * a) + (b * b)))"},
+		{"double(x)=2*x", "double", "x", "(2 * x)"},
+		{"answer() = 42", "answer", "", "42"},
+	}
+
+	for _, tt := range tests {
+		def, err := ParseDefinition(tt.src)
+		if err != nil {
+			t.Errorf("ParseDefinition(%q) unexpected error: %v", tt.src, err)
+			continue
+		}
+		if def.Name != tt.name || strings.Join(def.Params, ",") != tt.params || render(def.Body) != tt.body {
+			t.Errorf("ParseDefinition(%q) = %s(%v) = %s, want %s(%s) = %s",
+				tt.src, def.Name, def.Params, render(def.Body), tt.name, tt.params, tt.body)
+		}
+	}
+}
+
+func TestParseDefinition_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"hyp = 1",
+		"hyp(a,b)",
+		"hyp(a,b) =",
+		"hyp(a,b = a",
+		"hyp(a,) = a",
+		"hyp(1) = 1",
+		"2(a) = a",
+		"hyp(a) = a; 2",
+		"hyp(a)(b) = a",
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			if _, err := ParseDefinition(src); err == nil {
+				t.Errorf("ParseDefinition(%q) expected error", src)
+			}
+		})
+	}
+}
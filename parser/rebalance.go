@@ -0,0 +1,55 @@
+package parser
+
+var chains = map[string]bool{
+	"+":   true,
+	"*":   true,
+	"&":   true,
+	"|":   true,
+	"xor": true,
+}
+
+func Rebalance(node Node) Node {
+	switch n := node.(type) {
+	case *UnaryOp:
+		return &UnaryOp{Op: n.Op, Operand: Rebalance(n.Operand), At: n.At}
+	case *BinaryOp:
+		if !chains[n.Op] {
+			return &BinaryOp{Op: n.Op, Left: Rebalance(n.Left), Right: Rebalance(n.Right), At: n.At}
+		}
+		var terms []Node
+		flatten(n, n.Op, &terms)
+		return balance(n.Op, terms, n.At)
+	case *Call:
+		args := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = Rebalance(arg)
+		}
+		return &Call{Name: n.Name, Args: args, At: n.At}
+	}
+	return node
+}
+
+func RebalanceProgram(program *Program) *Program {
+	rebalanced := &Program{Statements: make([]*Statement, len(program.Statements))}
+	for i, stmt := range program.Statements {
+		rebalanced.Statements[i] = &Statement{Name: stmt.Name, Value: Rebalance(stmt.Value), At: stmt.At}
+	}
+	return rebalanced
+}
+
+func flatten(node Node, op string, terms *[]Node) {
+	if n, ok := node.(*BinaryOp); ok && n.Op == op {
+		flatten(n.Left, op, terms)
+		flatten(n.Right, op, terms)
+		return
+	}
+	*terms = append(*terms, Rebalance(node))
+}
+
+func balance(op string, terms []Node, at int) Node {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	mid := len(terms) / 2
+	return &BinaryOp{Op: op, Left: balance(op, terms[:mid], at), Right: balance(op, terms[mid:], at), At: at}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import "testing"
+
+func TestRebalance(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"a", "a"},
+		{"a+b", "(a + b)"},
+		{"a-b", "(a - b)"},
+		{"a+b+c+d", "((a + b) + (c + d))"},
+		{"a+b+c", "(a + (b + c))"},
+		{"a-b-c-d", "(((a - b) - c) - d)"},
+		{"a-b+c-d", "(((a - b) + c) - d)"},
+		{"a-(b-c)", "(a - (b - c))"},
+		{"a+b-c+d", "(((a + b) - c) + d)"},
+		{"a+b+c-d-e", "(((a + (b + c)) - d) - e)"},
+		{"a*b*c*d", "((a * b) * (c * d))"},
+		{"a/b/c", "((a / b) / c)"},
+		{"a/b*c/d", "(((a / b) * c) / d)"},
+		{"a*b*c/d", "((a * (b * c)) / d)"},
+		{"a+b*c*d*e+f", "(a + (((b * c) * (d * e)) + f))"},
+		{"a&b&c&d", "((a & b) & (c & d))"},
+		{"a|b&c|d", "(a | ((b & c) | d))"},
+		{"a xor b xor c xor d", "((a xor b) xor (c xor d))"},
+		{"a^b^c^d", "(a ^ (b ^ (c ^ d)))"},
+		{"a//b//c//d", "(((a // b) // c) // d)"},
+		{"a-b < c+d+e+f", "((a - b) < ((c + d) + (e + f)))"},
+		{"-(a+b+c+d)", "(-((a + b) + (c + d)))"},
+		{"sqrt(a+b+c+d)", "sqrt(((a + b) + (c + d)))"},
+		{"if(a, b+c+d+e, f)", "if(a, ((b + c) + (d + e)), f)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := render(Rebalance(node)); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestRebalanceProgram(t *testing.T) {
+	program, err := ParseProgram("x = a+b+c+d; x-1-2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rebalanced := RebalanceProgram(program)
+	if rebalanced.Statements[0].Name != "x" || render(rebalanced.Statements[0].Value) != "((a + b) + (c + d))" {
+		t.Errorf("Unexpected first statement %s = %s", rebalanced.Statements[0].Name, render(rebalanced.Statements[0].Value))
+	}
+	if got := render(rebalanced.Statements[1].Value); got != "((x - 1) - 2)" {
+		t.Errorf("Unexpected second statement %s", got)
+	}
+	if render(program.Statements[0].Value) != "(((a + b) + c) + d)" {
+		t.Errorf("Expected original program to stay untouched")
+	}
+}
@@ -0,0 +1,23 @@
+package parser
+
+func Inspect(node Node, visit func(Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *UnaryOp:
+		Inspect(n.Operand, visit)
+	case *BinaryOp:
+		Inspect(n.Left, visit)
+		Inspect(n.Right, visit)
+	case *Call:
+		for _, arg := range n.Args {
+			Inspect(arg, visit)
+		}
+	}
+}
+
+func IsIdentifier(name string) bool {
+	tokens, err := Tokenize(name)
+	return err == nil && len(tokens) == 2 && tokens[0].Kind == TokenIdent && tokens[0].Text == name
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	node, err := Parse("rate*sqrt(x+1)-max(y, 2)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var idents []string
+	Inspect(node, func(n Node) bool {
+		if ident, ok := n.(*Ident); ok {
+			idents = append(idents, ident.Name)
+		}
+		return true
+	})
+
+	if got := strings.Join(idents, ","); got != "rate,x,y" {
+		t.Errorf("Expected identifiers rate,x,y, got %s", got)
+	}
+}
+
+func TestInspect_SkipChildren(t *testing.T) {
+	node, err := Parse("sqrt(x)+y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	count := 0
+	Inspect(node, func(n Node) bool {
+		count++
+		_, isCall := n.(*Call)
+		return !isCall
+	})
+
+	if count != 3 {
+		t.Errorf("Expected 3 visited nodes when skipping call arguments, got %d", count)
+	}
+}
+
+func TestIsIdentifier(t *testing.T) {
+	valid := []string{"x", "rate", "_tmp", "x1", "скорость"}
+	for _, name := range valid {
+		if !IsIdentifier(name) {
+			t.Errorf("Expected %q to be an identifier", name)
+		}
+	}
+
+	invalid := []string{"", "1x", "x y", "x+1", " x", "f()"}
+	for _, name := range invalid {
+		if IsIdentifier(name) {
+			t.Errorf("Expected %q not to be an identifier", name)
+		}
+	}
+}
@@ -1,135 +1,142 @@
 package services
 
 import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
-	"strconv"
-	"strings"
+	"math"
 )
 
 func Calc(expression string) (float64, error) {
-	expression = strings.ReplaceAll(expression, " ", "")
-	if err := validateExpression(expression); err != nil {
-		return 0, fmt.Errorf("ошибка в выражении: %v", err)
-	}
-	return evaluate(expression)
+	return CalcWithVariables(expression, nil)
 }
 
-func validateExpression(expression string) error {
-	openBrackets := 0
-	for i, char := range expression {
-		switch char {
-		case '(':
-			openBrackets++
-		case ')':
-			openBrackets--
-			if openBrackets < 0 {
-				return fmt.Errorf("неверно расставлены скобки")
-			}
-		case '+', '-', '*', '/':
-			if i == 0 || i == len(expression)-1 || !isDigitOrBracket(expression[i-1]) || !isDigitOrBracket(expression[i+1]) {
-				return fmt.Errorf("неверная расстановка операторов")
-			}
-		}
-	}
-	if openBrackets != 0 {
-		return fmt.Errorf("несоответствие количества открывающих и закрывающих скобок")
+func CalcWithVariables(expression string, variables map[string]float64) (float64, error) {
+	result, err := CalcInMode(expression, variables, "")
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return result.Float(), nil
 }
 
-func isDigitOrBracket(c byte) bool {
-	return (c >= '0' && c <= '9') || c == '.' || c == ')' || c == '('
+func CalcInMode(expression string, variables map[string]float64, mode string) (evaluator.Value, error) {
+	program, err := parser.ParseProgram(expression)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в выражении: %w", err)
+	}
+	result, _, err := evaluateProgram(program, variables, mode)
+	return result, err
 }
 
-func evaluate(expression string) (float64, error) {
-	var values []float64
-	var operators []byte
-
-	applyOperation := func() error {
-		if len(values) < 2 {
-			return fmt.Errorf("неправильное использование оператора")
+func evaluateProgram(program *parser.Program, variables map[string]float64, mode string) (evaluator.Value, map[string]evaluator.Value, error) {
+	scope := make(map[string]evaluator.Value, len(variables))
+	for name, value := range variables {
+		converted, err := evaluator.FromFloat(value, mode)
+		if err != nil {
+			return nil, nil, err
 		}
+		scope[name] = converted
+	}
 
-		right, left := values[len(values)-1], values[len(values)-2]
-		values = values[:len(values)-2]
-		op := operators[len(operators)-1]
-		operators = operators[:len(operators)-1]
-
-		switch op {
-		case '+':
-			values = append(values, left+right)
-		case '-':
-			values = append(values, left-right)
-		case '*':
-			values = append(values, left*right)
-		case '/':
-			if right == 0 {
-				return fmt.Errorf("деление на ноль")
+	var result evaluator.Value
+	var assignments map[string]evaluator.Value
+	for _, stmt := range parser.RebalanceProgram(program).Statements {
+		value, err := evaluator.Evaluate(stmt.Value, scope, mode)
+		if err != nil {
+			return nil, nil, err
+		}
+		if stmt.IsAssignment() {
+			if evaluator.IsReserved(stmt.Name) {
+				return nil, nil, fmt.Errorf("имя %q зарезервировано", stmt.Name)
+			}
+			if assignments == nil {
+				assignments = make(map[string]evaluator.Value)
 			}
-			values = append(values, left/right)
+			scope[stmt.Name] = value
+			assignments[stmt.Name] = value
 		}
-
-		return nil
+		result = value
 	}
+	return result, assignments, nil
+}
 
-	precedence := func(op byte) int {
-		switch op {
-		case '+', '-':
-			return 1
-		case '*', '/':
-			return 2
+func checkProgram(program *parser.Program, variables map[string]float64, mode string) error {
+	scope := make(map[string]evaluator.Type, len(variables))
+	for name := range variables {
+		scope[name] = evaluator.Scalar
+	}
+	for _, stmt := range program.Statements {
+		t, err := evaluator.Check(stmt.Value, scope, mode)
+		if err != nil {
+			return err
+		}
+		if stmt.IsAssignment() {
+			scope[stmt.Name] = t
 		}
-		return 0
 	}
+	return nil
+}
 
-	var number strings.Builder
-	for i := 0; i < len(expression); i++ {
-		char := expression[i]
-
-		if isDigitOrBracket(char) && char != '(' && char != ')' {
-			number.WriteByte(char)
-
-			if i == len(expression)-1 || !isDigitOrBracket(expression[i+1]) || expression[i+1] == '(' || expression[i+1] == ')' {
-				num, err := strconv.ParseFloat(number.String(), 64)
-
-				if err != nil {
-					return 0, fmt.Errorf("некорректное число")
-				}
-
-				values = append(values, num)
-				number.Reset()
-			}
-
-		} else if char == '(' {
-			operators = append(operators, char)
-
-		} else if char == ')' {
-			for len(operators) > 0 && operators[len(operators)-1] != '(' {
-				if err := applyOperation(); err != nil {
-					return 0, err
-				}
-			}
-			operators = operators[:len(operators)-1]
-
-		} else if char == '+' || char == '-' || char == '*' || char == '/' {
-			for len(operators) > 0 && precedence(operators[len(operators)-1]) >= precedence(char) {
-				if err := applyOperation(); err != nil {
-					return 0, err
+func referencedVariables[T any](program *parser.Program, variables map[string]T) map[string]T {
+	var snapshot map[string]T
+	assigned := make(map[string]bool)
+	for _, stmt := range program.Statements {
+		parser.Inspect(stmt.Value, func(node parser.Node) bool {
+			if ident, ok := node.(*parser.Ident); ok && !assigned[ident.Name] {
+				if value, ok := variables[ident.Name]; ok {
+					if snapshot == nil {
+						snapshot = make(map[string]T)
+					}
+					snapshot[ident.Name] = value
 				}
 			}
-			operators = append(operators, char)
+			return true
+		})
+		if stmt.IsAssignment() {
+			assigned[stmt.Name] = true
 		}
 	}
+	return snapshot
+}
 
-	for len(operators) > 0 {
-		if err := applyOperation(); err != nil {
-			return 0, err
+func listDigests(lists map[string][]float64) map[string]string {
+	if len(lists) == 0 {
+		return nil
+	}
+	digests := make(map[string]string, len(lists))
+	for name, values := range lists {
+		hash := sha256.New()
+		var buf [8]byte
+		for _, value := range values {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(value))
+			hash.Write(buf[:])
 		}
+		digests[name] = fmt.Sprintf("sha256:%x", hash.Sum(nil))
 	}
+	return digests
+}
 
-	if len(values) != 1 {
-		return 0, fmt.Errorf("ошибка в выражении")
+func bindLists(program *parser.Program, lists map[string][]float64) (*parser.Program, error) {
+	if len(lists) == 0 {
+		return program, nil
+	}
+	scope := make(map[string]evaluator.Value, len(lists))
+	for name, values := range lists {
+		scope[name] = evaluator.NewVector(values)
 	}
 
-	return values[0], nil
+	bound := &parser.Program{Statements: make([]*parser.Statement, len(program.Statements))}
+	for i, stmt := range program.Statements {
+		value, err := evaluator.Substitute(stmt.Value, scope)
+		if err != nil {
+			return nil, err
+		}
+		bound.Statements[i] = &parser.Statement{Name: stmt.Name, Value: value, At: stmt.At}
+		if stmt.IsAssignment() {
+			delete(scope, stmt.Name)
+		}
+	}
+	return bound, nil
 }
@@ -1,6 +1,11 @@
 package services
 
-import "testing"
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"strings"
+	"testing"
+)
 
 func TestCalc(t *testing.T) {
 	tests := []struct {
@@ -21,9 +26,193 @@ func TestCalc(t *testing.T) {
 			want:    6,
 			wantErr: false,
 		},
+		{
+			name:    "parentheses",
+			expr:    "(2+3)*4",
+			want:    20,
+			wantErr: false,
+		},
+		{
+			name:    "unary minus",
+			expr:    "-5+3",
+			want:    -2,
+			wantErr: false,
+		},
+		{
+			name:    "negative operand",
+			expr:    "2*-3",
+			want:    -6,
+			wantErr: false,
+		},
+		{
+			name:    "negated group",
+			expr:    "-(4+1)",
+			want:    -5,
+			wantErr: false,
+		},
+		{
+			name:    "power is right associative",
+			expr:    "2^3^2",
+			want:    512,
+			wantErr: false,
+		},
+		{
+			name:    "power binds tighter than unary minus",
+			expr:    "-2**2",
+			want:    -4,
+			wantErr: false,
+		},
+		{
+			name:    "function call",
+			expr:    "sqrt(3*3+4*4)",
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name:    "nested functions",
+			expr:    "abs(floor(-2.5))+round(0.4)",
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "variadic aggregates",
+			expr:    "sum(1, 2, 3)+avg(2, 4)*max(1, 5, 2)-min(4, -1)",
+			want:    22,
+			wantErr: false,
+		},
+		{
+			name:    "range reductions",
+			expr:    "sum(i, 1, 100, i) + prod(k, 1, 5, k) + prod(2, 3)",
+			want:    5176,
+			wantErr: false,
+		},
+		{
+			name:    "numeric integration and root finding",
+			expr:    "integrate(2*x, 0, 3) + solve(x - 4, x, 0, 10)",
+			want:    13,
+			wantErr: false,
+		},
+		{
+			name:    "overflow",
+			expr:    "1e308*10",
+			wantErr: true,
+		},
+		{
+			name:    "overflow in sum",
+			expr:    "1e308 + 1e308 - 1e308",
+			wantErr: true,
+		},
+		{
+			name:    "uncertainty midpoint",
+			expr:    "(2±0.5)*[1; 3]",
+			want:    4.5,
+			wantErr: false,
+		},
+		{
+			name:    "constants",
+			expr:    "tau/pi+e*0",
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name:    "scientific notation",
+			expr:    "1.5e-3*2e3",
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "hex and binary literals",
+			expr:    "0xFF & 0b1010 | 0o100",
+			want:    74,
+			wantErr: false,
+		},
+		{
+			name:    "floor division and modulo",
+			expr:    "7 // 2 + -7 % 3",
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name:    "shifts bind looser than addition",
+			expr:    "1 << 2 + 2 >> 1",
+			want:    8,
+			wantErr: false,
+		},
+		{
+			name:    "xor",
+			expr:    "0xF0 xor 0xFF",
+			want:    15,
+			wantErr: false,
+		},
+		{
+			name:    "bitwise on fraction",
+			expr:    "2.5 & 1",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "modulo by zero",
+			expr:    "5 % 0",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "comparisons",
+			expr:    "(1 < 2) + (2 <= 2) + (3 == 3) + (3 != 3) + (1 >= 2) + (2 > 1)",
+			want:    4,
+			wantErr: false,
+		},
+		{
+			name:    "logical operators",
+			expr:    "(1 && 0) + (0 || 5) * 10 + !0 * 100 + !7",
+			want:    110,
+			wantErr: false,
+		},
+		{
+			name:    "conditional",
+			expr:    "if(2 > 1, 10, 20) + if(0, 1, 2)",
+			want:    12,
+			wantErr: false,
+		},
+		{
+			name:    "conditional skips the other branch",
+			expr:    "if(1, 5, 1/0) + if(0, ln(0), 1)",
+			want:    6,
+			wantErr: false,
+		},
+		{
+			name:    "logical operators short-circuit",
+			expr:    "(0 && 1/0) + (1 || ln(0))",
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "conditional arity",
+			expr:    "if(1, 2)",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "unknown identifier",
+			expr:    "x+1",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "unknown function",
+			expr:    "foo(1)",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "function outside domain",
+			expr:    "ln(0)",
+			want:    0,
+			wantErr: true,
+		},
 		{
 			name:    "invalid expression",
-			expr:    "2++2",
+			expr:    "2+*2",
 			want:    0,
 			wantErr: true,
 		},
@@ -48,3 +237,274 @@ func TestCalc(t *testing.T) {
 		})
 	}
 }
+
+func TestCalcWithVariables(t *testing.T) {
+	variables := map[string]float64{"rate": 0.5, "pi": 3}
+
+	got, err := CalcWithVariables("100*rate+pi", variables)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != 53 {
+		t.Errorf("Expected 53, got %v", got)
+	}
+
+	if _, err := CalcWithVariables("100*other", variables); err == nil {
+		t.Error("Expected error for undefined variable")
+	}
+}
+
+func TestReferencedVariables(t *testing.T) {
+	program, err := parser.ParseProgram("a*2+sqrt(b); c = 1; c+d")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := referencedVariables(program, map[string]float64{"a": 1, "b": 4, "c": 9, "d": 16, "e2": 25})
+	if len(snapshot) != 3 || snapshot["a"] != 1 || snapshot["b"] != 4 || snapshot["d"] != 16 {
+		t.Errorf("Expected snapshot of a, b and d only, got %v", snapshot)
+	}
+
+	if snapshot := referencedVariables[float64](program, nil); snapshot != nil {
+		t.Errorf("Expected nil snapshot without variables, got %v", snapshot)
+	}
+}
+
+func TestListDigests(t *testing.T) {
+	digests := listDigests(map[string][]float64{"xs": {1, 2, 6}, "ys": {1, 2, 6}, "zs": {1, 2, 7}, "empty": {}})
+	if len(digests) != 4 || !strings.HasPrefix(digests["xs"], "sha256:") || len(digests["xs"]) != 71 {
+		t.Errorf("Expected sha256 digest per list, got %v", digests)
+	}
+	if digests["xs"] != digests["ys"] {
+		t.Errorf("Expected equal lists to share a digest, got %s and %s", digests["xs"], digests["ys"])
+	}
+	if digests["xs"] == digests["zs"] || digests["xs"] == digests["empty"] {
+		t.Errorf("Expected different lists to have different digests, got %v", digests)
+	}
+	if digests := listDigests(nil); digests != nil {
+		t.Errorf("Expected nil digests without lists, got %v", digests)
+	}
+}
+
+func TestBindLists(t *testing.T) {
+	program, err := parser.ParseProgram("m = mean(xs); xs = 2; sum(i, 1, 2, xs*i) + m + median(ys)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bound, err := bindLists(program, map[string][]float64{"xs": {1, 2, 6}, "ys": {-1, 0.5}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	formatted := make([]string, len(bound.Statements))
+	for i, stmt := range bound.Statements {
+		formatted[i] = parser.Format(stmt.Value)
+	}
+	expected := "mean([1.0, 2.0, 6.0]) | 2 | sum(i, 1, 2, xs*i) + m + median([-1.0, 0.5])"
+	if got := strings.Join(formatted, " | "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if result, _, err := evaluateProgram(bound, nil, ""); err != nil || result.String() != "8.75" {
+		t.Errorf("Expected 8.75, got %v, %v", result, err)
+	}
+
+	if unchanged, _ := bindLists(program, nil); unchanged != program {
+		t.Error("Expected program without lists to be returned unchanged")
+	}
+}
+
+func TestCalc_Program(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    float64
+		wantErr bool
+	}{
+		{"x = 2+3; y = x*4; y - x", 15, false},
+		{"x = 2; x = x*x; x", 4, false},
+		{"x = 7", 7, false},
+		{"rate = 0.5; rate*2;", 1, false},
+		{"y*2; y = 1", 0, true},
+		{"pi = 3; pi", 0, true},
+		{"x = 1/0; 2", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Calc(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Calc(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Calc(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCalcInMode_Integer(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"9007199254740993 + 0", "9007199254740993"},
+		{"0x7FFFFFFFFFFFFFFF - 0xFF", "9223372036854775552"},
+		{"1 << 62 | 1", "4611686018427387905"},
+		{"3 ^ 39", "4052555153018976267"},
+		{"7 / 2", "3.5"},
+		{"0x7FFFFFFFFFFFFFFF + 1", "9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, nil, "")
+		if err != nil {
+			t.Errorf("CalcInMode(%q) unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	if got, err := CalcInMode("7 // 2 + 0.1 % 1", nil, evaluator.ModeExact); err != nil || got.String() != "rat:31/10" {
+		t.Errorf("Expected exact integer division, got %v, %v", got, err)
+	}
+}
+
+func TestCalcInMode_Exact(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"0.1+0.2", "rat:3/10", false},
+		{"1/3 + 1/6", "rat:1/2", false},
+		{"(2/3)^-2", "rat:9/4", false},
+		{"x = 1/7; x*7", "rat:1", false},
+		{"avg(1, 2) - floor(7/2)", "rat:-3/2", false},
+		{"rate*100", "rat:7", false},
+		{"pi*2", "", true},
+		{"sqrt(4)", "", true},
+		{"2^0.5", "", true},
+		{"1/(1-1)", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, map[string]float64{"rate": 0.07}, evaluator.ModeExact)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CalcInMode(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	if got, err := CalcInMode("0.1+0.2", nil, ""); err != nil || got.String() != "0.30000000000000004" {
+		t.Errorf("Expected float mode to keep float64 semantics, got %v, %v", got, err)
+	}
+}
+
+func TestCalcInMode_Interval(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"3.2±0.1", "iv:3.1,3.3000000000000003", false},
+		{"r = 2±0.1; 4*r^2", "iv:14.44,17.64", false},
+		{"[1; 2]*x", "iv:3,6", false},
+		{"[1, 2]*x", "vec:3,6", false},
+		{"sqrt([x; 16])", "iv:1.7320508075688772,4", false},
+		{"[1; 2] < [1.5; 3]", "", true},
+		{"interval(1, 2)*x", "iv:3,6", false},
+		{"1/[-x; x]", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, map[string]float64{"x": 3}, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CalcInMode(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCalcInMode_Matrix(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"[1, 2, x]*2", "vec:2,4,6", false},
+		{"A = [[2, 1], [0, 4]]; matmul(A, inv(A))", "mat:2x2:1,0,0,1", false},
+		{"det([[x, 1], [1, x]])", "8", false},
+		{"v = [1, 2, 2]; norm(v)", "3", false},
+		{"[1, 2, x] + [1, 2]", "", true},
+		{"det([[1, 2, 3]])", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, map[string]float64{"x": 3}, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CalcInMode(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCalcInMode_Complex(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"(3+4i)*(1-2i)", "cx:11,-2", false},
+		{"sqrt(-4)", "cx:0,2", false},
+		{"sqrt(x)", "cx:0,3", false},
+		{"z = 1 + i; z*conj(z)", "2", false},
+		{"abs(3 - 4i)", "5", false},
+		{"(1+i) > 1", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, map[string]float64{"x": -9}, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CalcInMode(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCalcInMode_Quantity(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"3 m * 2 s^-1 + 4 km/h to km/h", "q:7.111111111111111,0.2777777777777778,m/s,km/h", false},
+		{"d = 42 km; t = 2 h; d/t to mph", "q:5.833333333333333,0.44704,m/s,mph", false},
+		{"x*1 kg + 500 g", "q:2.5,1,kg,kg", false},
+		{"1 h / 15 min", "4", false},
+		{"3 m + 2 kg", "", true},
+		{"3 m to s", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CalcInMode(tt.expr, map[string]float64{"x": 2}, "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CalcInMode(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("CalcInMode(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"calculator/models"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -41,6 +43,26 @@ func (ds *DatabaseService) createTables() error {
 			expression TEXT NOT NULL,
 			status TEXT NOT NULL DEFAULT 'pending',
 			result REAL,
+			mode TEXT,
+			exact TEXT,
+			decimal TEXT,
+			lower REAL,
+			upper REAL,
+			imag REAL,
+			unit TEXT,
+			vector TEXT,
+			matrix TEXT,
+			error TEXT,
+			variables TEXT,
+			lists TEXT,
+			result_ref TEXT,
+			bindings TEXT,
+			assignments TEXT,
+			folded INTEGER NOT NULL DEFAULT 0,
+			dispatched INTEGER NOT NULL DEFAULT 0,
+			depth INTEGER NOT NULL DEFAULT 0,
+			critical_path INTEGER NOT NULL DEFAULT 0,
+			numerics TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users (id)
@@ -49,15 +71,42 @@ func (ds *DatabaseService) createTables() error {
 			id TEXT PRIMARY KEY,
 			expression_id TEXT NOT NULL,
 			arg1 TEXT NOT NULL,
-			arg2 TEXT NOT NULL,
+			arg2 TEXT,
+			arg3 TEXT,
 			operation TEXT NOT NULL,
 			operation_time INTEGER NOT NULL,
 			status TEXT NOT NULL DEFAULT 'pending',
 			result REAL,
+			value TEXT,
+			condition TEXT,
+			guard TEXT,
+			range_index TEXT,
+			body TEXT,
+			error_estimate REAL,
+			iterations INTEGER NOT NULL DEFAULT 0,
+			error TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (expression_id) REFERENCES expressions (id)
 		)`,
+		`CREATE TABLE IF NOT EXISTS variables (
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			value REAL NOT NULL,
+			vals TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS functions (
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			params TEXT NOT NULL,
+			definition TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)`,
 	}
 
 	for _, query := range queries {
@@ -66,6 +115,49 @@ func (ds *DatabaseService) createTables() error {
 		}
 	}
 
+	return ds.addMissingColumns()
+}
+
+func (ds *DatabaseService) addMissingColumns() error {
+	columns := []string{
+		`ALTER TABLE expressions ADD COLUMN variables TEXT`,
+		`ALTER TABLE expressions ADD COLUMN result_ref TEXT`,
+		`ALTER TABLE expressions ADD COLUMN bindings TEXT`,
+		`ALTER TABLE expressions ADD COLUMN assignments TEXT`,
+		`ALTER TABLE expressions ADD COLUMN mode TEXT`,
+		`ALTER TABLE expressions ADD COLUMN exact TEXT`,
+		`ALTER TABLE expressions ADD COLUMN decimal TEXT`,
+		`ALTER TABLE expressions ADD COLUMN folded INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE expressions ADD COLUMN dispatched INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE expressions ADD COLUMN depth INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE expressions ADD COLUMN critical_path INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE expressions ADD COLUMN numerics TEXT`,
+		`ALTER TABLE expressions ADD COLUMN lower REAL`,
+		`ALTER TABLE expressions ADD COLUMN upper REAL`,
+		`ALTER TABLE expressions ADD COLUMN imag REAL`,
+		`ALTER TABLE expressions ADD COLUMN unit TEXT`,
+		`ALTER TABLE expressions ADD COLUMN vector TEXT`,
+		`ALTER TABLE expressions ADD COLUMN matrix TEXT`,
+		`ALTER TABLE expressions ADD COLUMN error TEXT`,
+		`ALTER TABLE expressions ADD COLUMN lists TEXT`,
+		`ALTER TABLE tasks ADD COLUMN value TEXT`,
+		`ALTER TABLE tasks ADD COLUMN condition TEXT`,
+		`ALTER TABLE tasks ADD COLUMN guard TEXT`,
+		`ALTER TABLE tasks ADD COLUMN range_index TEXT`,
+		`ALTER TABLE tasks ADD COLUMN body TEXT`,
+		`ALTER TABLE tasks ADD COLUMN error_estimate REAL`,
+		`ALTER TABLE tasks ADD COLUMN iterations INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN arg3 TEXT`,
+		`ALTER TABLE tasks ADD COLUMN error TEXT`,
+		`ALTER TABLE variables ADD COLUMN vals TEXT`,
+	}
+
+	for _, query := range columns {
+		if _, err := ds.db.Exec(query); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("failed to add column: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -106,10 +198,52 @@ func (ds *DatabaseService) GetUserByLogin(login string) (*models.User, error) {
 }
 
 func (ds *DatabaseService) CreateExpression(expr *models.Expression) error {
-	query := `INSERT INTO expressions (id, user_id, expression, status, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := ds.db.Exec(query, expr.ID, expr.UserID, expr.Expression, expr.Status,
-		expr.CreatedAt, expr.UpdatedAt)
+	return createExpression(ds.db, expr)
+}
+
+func (ds *DatabaseService) CreateExpressionWithTasks(expr *models.Expression, tasks []*models.Task) error {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if err := createExpression(tx, expr); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, task := range tasks {
+		if err := createTask(tx, task); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func createExpression(db execer, expr *models.Expression) error {
+	variables, err := encodeJSON(expr.Variables)
+	if err != nil {
+		return fmt.Errorf("failed to encode variables: %v", err)
+	}
+	lists, err := encodeJSON(expr.Lists)
+	if err != nil {
+		return fmt.Errorf("failed to encode lists: %v", err)
+	}
+	bindings, err := encodeJSON(expr.Bindings)
+	if err != nil {
+		return fmt.Errorf("failed to encode bindings: %v", err)
+	}
+	numerics, err := encodeJSON(expr.Numerics)
+	if err != nil {
+		return fmt.Errorf("failed to encode numerics: %v", err)
+	}
+
+	query := `INSERT INTO expressions (id, user_id, expression, status, mode, variables, lists, result_ref, bindings, folded, dispatched, depth, critical_path, numerics, created_at, updated_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = db.Exec(query, expr.ID, expr.UserID, expr.Expression, expr.Status, nullableString(expr.Mode), variables, lists,
+		nullableString(expr.ResultRef), bindings, expr.Folded, expr.Dispatched, expr.Depth, expr.CriticalPath, numerics, expr.CreatedAt, expr.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create expression: %v", err)
 	}
@@ -121,20 +255,14 @@ func (ds *DatabaseService) GetExpression(id string, userID int) (*models.Express
 	var args []interface{}
 
 	if userID == 0 {
-		query = `SELECT id, user_id, expression, status, result, created_at, updated_at 
-				 FROM expressions WHERE id = ?`
+		query = `SELECT ` + expressionColumns + ` FROM expressions WHERE id = ?`
 		args = []interface{}{id}
 	} else {
-		query = `SELECT id, user_id, expression, status, result, created_at, updated_at 
-				 FROM expressions WHERE id = ? AND user_id = ?`
+		query = `SELECT ` + expressionColumns + ` FROM expressions WHERE id = ? AND user_id = ?`
 		args = []interface{}{id, userID}
 	}
 
-	row := ds.db.QueryRow(query, args...)
-
-	var expr models.Expression
-	err := row.Scan(&expr.ID, &expr.UserID, &expr.Expression, &expr.Status,
-		&expr.Result, &expr.CreatedAt, &expr.UpdatedAt)
+	expr, err := scanExpression(ds.db.QueryRow(query, args...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("expression not found")
@@ -142,12 +270,29 @@ func (ds *DatabaseService) GetExpression(id string, userID int) (*models.Express
 		return nil, fmt.Errorf("failed to get expression: %v", err)
 	}
 
-	return &expr, nil
+	return expr, nil
 }
 
 func (ds *DatabaseService) UpdateExpression(expr *models.Expression) error {
-	query := `UPDATE expressions SET status = ?, result = ?, updated_at = ? WHERE id = ?`
-	_, err := ds.db.Exec(query, expr.Status, expr.Result, time.Now(), expr.ID)
+	assignments, err := encodeJSON(expr.Assignments)
+	if err != nil {
+		return fmt.Errorf("failed to encode assignments: %v", err)
+	}
+	numerics, err := encodeJSON(expr.Numerics)
+	if err != nil {
+		return fmt.Errorf("failed to encode numerics: %v", err)
+	}
+	vector, err := encodeJSON(expr.Vector)
+	if err != nil {
+		return fmt.Errorf("failed to encode vector: %v", err)
+	}
+	matrix, err := encodeJSON(expr.Matrix)
+	if err != nil {
+		return fmt.Errorf("failed to encode matrix: %v", err)
+	}
+
+	query := `UPDATE expressions SET status = ?, result = ?, exact = ?, decimal = ?, lower = ?, upper = ?, imag = ?, unit = ?, vector = ?, matrix = ?, error = ?, assignments = ?, dispatched = ?, numerics = ?, updated_at = ? WHERE id = ?`
+	_, err = ds.db.Exec(query, expr.Status, expr.Result, expr.Exact, expr.Decimal, expr.Lower, expr.Upper, expr.Imag, expr.Unit, vector, matrix, expr.Error, assignments, expr.Dispatched, numerics, time.Now(), expr.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update expression: %v", err)
 	}
@@ -155,8 +300,7 @@ func (ds *DatabaseService) UpdateExpression(expr *models.Expression) error {
 }
 
 func (ds *DatabaseService) GetUserExpressions(userID int) ([]*models.Expression, error) {
-	query := `SELECT id, user_id, expression, status, result, created_at, updated_at 
-			  FROM expressions WHERE user_id = ? ORDER BY created_at DESC`
+	query := `SELECT ` + expressionColumns + ` FROM expressions WHERE user_id = ? ORDER BY created_at DESC`
 	rows, err := ds.db.Query(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expressions: %v", err)
@@ -165,23 +309,139 @@ func (ds *DatabaseService) GetUserExpressions(userID int) ([]*models.Expression,
 
 	var expressions []*models.Expression
 	for rows.Next() {
-		var expr models.Expression
-		err := rows.Scan(&expr.ID, &expr.UserID, &expr.Expression, &expr.Status,
-			&expr.Result, &expr.CreatedAt, &expr.UpdatedAt)
+		expr, err := scanExpression(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan expression: %v", err)
 		}
-		expressions = append(expressions, &expr)
+		expressions = append(expressions, expr)
 	}
 
 	return expressions, nil
 }
 
+func (ds *DatabaseService) SetVariable(userID int, name string, value float64) (*models.Variable, error) {
+	now := time.Now()
+	query := `INSERT INTO variables (user_id, name, value, vals, updated_at) VALUES (?, ?, ?, NULL, ?)
+			  ON CONFLICT (user_id, name) DO UPDATE SET value = excluded.value, vals = excluded.vals, updated_at = excluded.updated_at`
+	if _, err := ds.db.Exec(query, userID, name, value, now); err != nil {
+		return nil, fmt.Errorf("failed to save variable: %v", err)
+	}
+
+	return &models.Variable{UserID: userID, Name: name, Value: value, UpdatedAt: now}, nil
+}
+
+func (ds *DatabaseService) SetList(userID int, name string, values []float64) (*models.Variable, error) {
+	return setList(ds.db, userID, name, values)
+}
+
+func (ds *DatabaseService) SetLists(userID int, names []string, lists [][]float64) ([]*models.Variable, error) {
+	tx, err := ds.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	variables := make([]*models.Variable, len(names))
+	for i, name := range names {
+		if variables[i], err = setList(tx, userID, name, lists[i]); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return variables, nil
+}
+
+func setList(db execer, userID int, name string, values []float64) (*models.Variable, error) {
+	encoded, err := encodeJSON(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode values: %v", err)
+	}
+
+	now := time.Now()
+	query := `INSERT INTO variables (user_id, name, value, vals, updated_at) VALUES (?, ?, 0, ?, ?)
+			  ON CONFLICT (user_id, name) DO UPDATE SET value = excluded.value, vals = excluded.vals, updated_at = excluded.updated_at`
+	if _, err := db.Exec(query, userID, name, encoded, now); err != nil {
+		return nil, fmt.Errorf("failed to save variable: %v", err)
+	}
+
+	return &models.Variable{UserID: userID, Name: name, Values: values, UpdatedAt: now}, nil
+}
+
+func (ds *DatabaseService) GetUserVariables(userID int) ([]*models.Variable, error) {
+	query := `SELECT user_id, name, value, vals, updated_at FROM variables WHERE user_id = ? ORDER BY name ASC`
+	rows, err := ds.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variables: %v", err)
+	}
+	defer rows.Close()
+
+	var variables []*models.Variable
+	for rows.Next() {
+		var variable models.Variable
+		var values sql.NullString
+		if err := rows.Scan(&variable.UserID, &variable.Name, &variable.Value, &values, &variable.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan variable: %v", err)
+		}
+		if err := decodeJSON(values, &variable.Values); err != nil {
+			return nil, fmt.Errorf("failed to decode values: %v", err)
+		}
+		variables = append(variables, &variable)
+	}
+
+	return variables, nil
+}
+
+func (ds *DatabaseService) SetFunction(userID int, name string, params []string, definition string) (*models.Function, error) {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %v", err)
+	}
+
+	now := time.Now()
+	query := `INSERT INTO functions (user_id, name, params, definition, updated_at) VALUES (?, ?, ?, ?, ?)
+			  ON CONFLICT (user_id, name) DO UPDATE SET params = excluded.params, definition = excluded.definition, updated_at = excluded.updated_at`
+	if _, err := ds.db.Exec(query, userID, name, string(encodedParams), definition, now); err != nil {
+		return nil, fmt.Errorf("failed to save function: %v", err)
+	}
+
+	return &models.Function{UserID: userID, Name: name, Params: params, Definition: definition, UpdatedAt: now}, nil
+}
+
+func (ds *DatabaseService) GetUserFunctions(userID int) ([]*models.Function, error) {
+	query := `SELECT user_id, name, params, definition, updated_at FROM functions WHERE user_id = ? ORDER BY name ASC`
+	rows, err := ds.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions: %v", err)
+	}
+	defer rows.Close()
+
+	var functions []*models.Function
+	for rows.Next() {
+		var function models.Function
+		var params sql.NullString
+		if err := rows.Scan(&function.UserID, &function.Name, &params, &function.Definition, &function.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan function: %v", err)
+		}
+		if err := decodeJSON(params, &function.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode params: %v", err)
+		}
+		functions = append(functions, &function)
+	}
+
+	return functions, nil
+}
+
 func (ds *DatabaseService) CreateTask(task *models.Task) error {
-	query := `INSERT INTO tasks (id, expression_id, arg1, arg2, operation, operation_time, status, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := ds.db.Exec(query, task.ID, task.ExpressionID, task.Arg1, task.Arg2,
-		task.Operation, task.OperationTime, task.Status, task.CreatedAt, task.UpdatedAt)
+	return createTask(ds.db, task)
+}
+
+func createTask(db execer, task *models.Task) error {
+	query := `INSERT INTO tasks (id, expression_id, arg1, arg2, arg3, operation, operation_time, status, condition, guard, range_index, body, created_at, updated_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, task.ID, task.ExpressionID, task.Arg1, task.Arg2, nullableString(task.Arg3),
+		task.Operation, task.OperationTime, task.Status, nullableString(task.Condition), nullableString(task.Guard),
+		nullableString(task.Index), nullableString(task.Body), task.CreatedAt, task.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create task: %v", err)
 	}
@@ -189,14 +449,8 @@ func (ds *DatabaseService) CreateTask(task *models.Task) error {
 }
 
 func (ds *DatabaseService) GetTask(id string) (*models.Task, error) {
-	query := `SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at 
-			  FROM tasks WHERE id = ?`
-	row := ds.db.QueryRow(query, id)
-
-	var task models.Task
-	err := row.Scan(&task.ID, &task.ExpressionID, &task.Arg1, &task.Arg2,
-		&task.Operation, &task.OperationTime, &task.Status, &task.Result,
-		&task.CreatedAt, &task.UpdatedAt)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ?`
+	task, err := scanTask(ds.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("task not found")
@@ -204,12 +458,12 @@ func (ds *DatabaseService) GetTask(id string) (*models.Task, error) {
 		return nil, fmt.Errorf("failed to get task: %v", err)
 	}
 
-	return &task, nil
+	return task, nil
 }
 
 func (ds *DatabaseService) UpdateTask(task *models.Task) error {
-	query := `UPDATE tasks SET status = ?, result = ?, updated_at = ? WHERE id = ?`
-	_, err := ds.db.Exec(query, task.Status, task.Result, time.Now(), task.ID)
+	query := `UPDATE tasks SET status = ?, result = ?, value = ?, error_estimate = ?, iterations = ?, error = ?, updated_at = ? WHERE id = ?`
+	_, err := ds.db.Exec(query, task.Status, task.Result, task.Value, task.ErrorEstimate, task.Iterations, task.Error, time.Now(), task.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update task: %v", err)
 	}
@@ -217,8 +471,7 @@ func (ds *DatabaseService) UpdateTask(task *models.Task) error {
 }
 
 func (ds *DatabaseService) GetPendingTasks() ([]*models.Task, error) {
-	query := `SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at 
-			  FROM tasks WHERE status = 'pending' ORDER BY created_at ASC`
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE status = 'pending' ORDER BY created_at ASC`
 	rows, err := ds.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending tasks: %v", err)
@@ -227,22 +480,18 @@ func (ds *DatabaseService) GetPendingTasks() ([]*models.Task, error) {
 
 	var tasks []*models.Task
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.ID, &task.ExpressionID, &task.Arg1, &task.Arg2,
-			&task.Operation, &task.OperationTime, &task.Status, &task.Result,
-			&task.CreatedAt, &task.UpdatedAt)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %v", err)
 		}
-		tasks = append(tasks, &task)
+		tasks = append(tasks, task)
 	}
 
 	return tasks, nil
 }
 
 func (ds *DatabaseService) GetTasksByExpressionID(expressionID string) ([]*models.Task, error) {
-	query := `SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at 
-			  FROM tasks WHERE expression_id = ? ORDER BY created_at ASC`
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE expression_id = ? ORDER BY created_at ASC`
 	rows, err := ds.db.Query(query, expressionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tasks: %v", err)
@@ -251,19 +500,116 @@ func (ds *DatabaseService) GetTasksByExpressionID(expressionID string) ([]*model
 
 	var tasks []*models.Task
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.ID, &task.ExpressionID, &task.Arg1, &task.Arg2,
-			&task.Operation, &task.OperationTime, &task.Status, &task.Result,
-			&task.CreatedAt, &task.UpdatedAt)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %v", err)
 		}
-		tasks = append(tasks, &task)
+		tasks = append(tasks, task)
 	}
 
 	return tasks, nil
 }
 
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const expressionColumns = `id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at`
+
+const taskColumns = `id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at`
+
+func scanExpression(row rowScanner) (*models.Expression, error) {
+	var expr models.Expression
+	var mode, vector, matrix, variables, lists, resultRef, bindings, assignments, numerics sql.NullString
+	err := row.Scan(&expr.ID, &expr.UserID, &expr.Expression, &expr.Status,
+		&expr.Result, &mode, &expr.Exact, &expr.Decimal, &expr.Lower, &expr.Upper, &expr.Imag, &expr.Unit, &vector, &matrix, &expr.Error, &variables, &lists, &resultRef, &bindings, &assignments,
+		&expr.Folded, &expr.Dispatched, &expr.Depth, &expr.CriticalPath, &numerics, &expr.CreatedAt, &expr.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	expr.Mode = mode.String
+	expr.ResultRef = resultRef.String
+	if err := decodeJSON(vector, &expr.Vector); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(matrix, &expr.Matrix); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(variables, &expr.Variables); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(lists, &expr.Lists); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(bindings, &expr.Bindings); err != nil {
+		return nil, err
+	}
+	if err := decodeAssignments(assignments, &expr.Assignments); err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(numerics, &expr.Numerics); err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	var task models.Task
+	var arg2, arg3, condition, guard, index, body sql.NullString
+	err := row.Scan(&task.ID, &task.ExpressionID, &task.Arg1, &arg2, &arg3,
+		&task.Operation, &task.OperationTime, &task.Status, &task.Result, &task.Value,
+		&condition, &guard, &index, &body, &task.ErrorEstimate, &task.Iterations, &task.Error, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	task.Arg2 = arg2.String
+	task.Arg3 = arg3.String
+	task.Condition = condition.String
+	task.Guard = guard.String
+	task.Index = index.String
+	task.Body = body.String
+	return &task, nil
+}
+
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func encodeJSON(value interface{}) (sql.NullString, error) {
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func decodeJSON(column sql.NullString, dest interface{}) error {
+	if !column.Valid || column.String == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(column.String), dest)
+}
+
+func decodeAssignments(column sql.NullString, dest *map[string]models.Value) error {
+	if err := decodeJSON(column, dest); err == nil {
+		return nil
+	}
+	var legacy map[string]float64
+	if err := decodeJSON(column, &legacy); err != nil {
+		return err
+	}
+	*dest = make(map[string]models.Value, len(legacy))
+	for name, value := range legacy {
+		value := value
+		(*dest)[name] = models.Value{Result: &value}
+	}
+	return nil
+}
+
 func (ds *DatabaseService) Close() error {
 	return ds.db.Close()
 }
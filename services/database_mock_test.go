@@ -107,7 +107,7 @@ func TestDatabaseService_CreateExpression_Mock(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO expressions").
-		WithArgs(expr.ID, expr.UserID, expr.Expression, expr.Status, expr.CreatedAt, expr.UpdatedAt).
+		WithArgs(expr.ID, expr.UserID, expr.Expression, expr.Status, nil, nil, nil, nil, nil, 0, 0, 0, 0, nil, expr.CreatedAt, expr.UpdatedAt).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = service.CreateExpression(expr)
@@ -116,7 +116,7 @@ func TestDatabaseService_CreateExpression_Mock(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO expressions").
-		WithArgs(expr.ID, expr.UserID, expr.Expression, expr.Status, expr.CreatedAt, expr.UpdatedAt).
+		WithArgs(expr.ID, expr.UserID, expr.Expression, expr.Status, nil, nil, nil, nil, nil, 0, 0, 0, 0, nil, expr.CreatedAt, expr.UpdatedAt).
 		WillReturnError(errors.New("database error"))
 
 	err = service.CreateExpression(expr)
@@ -138,10 +138,10 @@ func TestDatabaseService_GetExpression_Mock(t *testing.T) {
 
 	service := &DatabaseService{db: db}
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "created_at", "updated_at"}).
-		AddRow("test-id", 1, "2+2", "pending", nil, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "mode", "exact", "decimal", "lower", "upper", "imag", "unit", "vector", "matrix", "error", "variables", "lists", "result_ref", "bindings", "assignments", "folded", "dispatched", "depth", "critical_path", "numerics", "created_at", "updated_at"}).
+		AddRow("test-id", 1, "2+2", "pending", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, 0, 0, nil, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, user_id, expression, status, result, created_at, updated_at FROM expressions WHERE id = \\? AND user_id = \\?").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at FROM expressions WHERE id = \\? AND user_id = \\?").
 		WithArgs("test-id", 1).
 		WillReturnRows(rows)
 
@@ -154,10 +154,10 @@ func TestDatabaseService_GetExpression_Mock(t *testing.T) {
 		t.Errorf("Expected ID 'test-id', got '%s'", expr.ID)
 	}
 
-	rows2 := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "created_at", "updated_at"}).
-		AddRow("test-id", 1, "2+2", "pending", nil, time.Now(), time.Now())
+	rows2 := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "mode", "exact", "decimal", "lower", "upper", "imag", "unit", "vector", "matrix", "error", "variables", "lists", "result_ref", "bindings", "assignments", "folded", "dispatched", "depth", "critical_path", "numerics", "created_at", "updated_at"}).
+		AddRow("test-id", 1, "2+2", "pending", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, 0, 0, nil, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, user_id, expression, status, result, created_at, updated_at FROM expressions WHERE id = \\?").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at FROM expressions WHERE id = \\?").
 		WithArgs("test-id").
 		WillReturnRows(rows2)
 
@@ -170,7 +170,7 @@ func TestDatabaseService_GetExpression_Mock(t *testing.T) {
 		t.Errorf("Expected ID 'test-id', got '%s'", expr2.ID)
 	}
 
-	mock.ExpectQuery("SELECT id, user_id, expression, status, result, created_at, updated_at FROM expressions WHERE id = \\? AND user_id = \\?").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at FROM expressions WHERE id = \\? AND user_id = \\?").
 		WithArgs("nonexistent", 1).
 		WillReturnError(sql.ErrNoRows)
 
@@ -196,11 +196,11 @@ func TestDatabaseService_UpdateExpression_Mock(t *testing.T) {
 	expr := &models.Expression{
 		ID:     "test-id",
 		Status: models.StatusComputing,
-		Result: &[]float64{4.0}[0],
+		Value:  models.Value{Result: &[]float64{4.0}[0]},
 	}
 
-	mock.ExpectExec("UPDATE expressions SET status = \\?, result = \\?, updated_at = \\? WHERE id = \\?").
-		WithArgs(expr.Status, expr.Result, sqlmock.AnyArg(), expr.ID).
+	mock.ExpectExec("UPDATE expressions SET status = \\?, result = \\?, exact = \\?, decimal = \\?, lower = \\?, upper = \\?, imag = \\?, unit = \\?, vector = \\?, matrix = \\?, error = \\?, assignments = \\?, dispatched = \\?, numerics = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs(expr.Status, expr.Result, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, nil, sqlmock.AnyArg(), expr.ID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = service.UpdateExpression(expr)
@@ -208,8 +208,8 @@ func TestDatabaseService_UpdateExpression_Mock(t *testing.T) {
 		t.Fatalf("Failed to update expression: %v", err)
 	}
 
-	mock.ExpectExec("UPDATE expressions SET status = \\?, result = \\?, updated_at = \\? WHERE id = \\?").
-		WithArgs(expr.Status, expr.Result, sqlmock.AnyArg(), expr.ID).
+	mock.ExpectExec("UPDATE expressions SET status = \\?, result = \\?, exact = \\?, decimal = \\?, lower = \\?, upper = \\?, imag = \\?, unit = \\?, vector = \\?, matrix = \\?, error = \\?, assignments = \\?, dispatched = \\?, numerics = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs(expr.Status, expr.Result, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, nil, sqlmock.AnyArg(), expr.ID).
 		WillReturnError(errors.New("database error"))
 
 	err = service.UpdateExpression(expr)
@@ -231,11 +231,11 @@ func TestDatabaseService_GetUserExpressions_Mock(t *testing.T) {
 
 	service := &DatabaseService{db: db}
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "created_at", "updated_at"}).
-		AddRow("test-id-1", 1, "2+2", "pending", nil, time.Now(), time.Now()).
-		AddRow("test-id-2", 1, "3+3", "pending", nil, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "user_id", "expression", "status", "result", "mode", "exact", "decimal", "lower", "upper", "imag", "unit", "vector", "matrix", "error", "variables", "lists", "result_ref", "bindings", "assignments", "folded", "dispatched", "depth", "critical_path", "numerics", "created_at", "updated_at"}).
+		AddRow("test-id-1", 1, "2+2", "pending", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, 0, 0, 0, nil, time.Now(), time.Now()).
+		AddRow("test-id-2", 1, "3+3", "done", 3.0, "exact", "3", "3", 2.5, 3.5, -1.5, "m/s", `[1,2]`, `[[1,0],[0,1]]`, nil, `{"x":1.5}`, `{"xs":"sha256:ab"}`, "$t2", `{"y":"$t1"}`, `{"y":1.5}`, 2, 1, 3, 4000, `[{"function":"integrate","call":"integrate(x^2, 0, 1)","result":0.3333333333333333,"iterations":80,"tasks":["$t1"]}]`, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, user_id, expression, status, result, created_at, updated_at FROM expressions WHERE user_id = \\? ORDER BY created_at DESC").
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at FROM expressions WHERE user_id = \\? ORDER BY created_at DESC").
 		WithArgs(1).
 		WillReturnRows(rows)
 
@@ -246,9 +246,27 @@ func TestDatabaseService_GetUserExpressions_Mock(t *testing.T) {
 
 	if len(expressions) != 2 {
 		t.Errorf("Expected 2 expressions, got %d", len(expressions))
-	}
-
-	mock.ExpectQuery("SELECT id, user_id, expression, status, result, created_at, updated_at FROM expressions WHERE user_id = \\? ORDER BY created_at DESC").
+	} else if expressions[1].Variables["x"] != 1.5 || expressions[1].Lists["xs"] != "sha256:ab" {
+		t.Errorf("Expected variables snapshot to be decoded, got %v %v", expressions[1].Variables, expressions[1].Lists)
+	} else if expressions[1].ResultRef != "$t2" || expressions[1].Bindings["y"] != "$t1" || assigned(expressions[1].Assignments, "y") != 1.5 {
+		t.Errorf("Expected program columns to be decoded, got %+v", expressions[1])
+	} else if expressions[1].Mode != "exact" || expressions[1].Exact == nil || *expressions[1].Exact != "3" {
+		t.Errorf("Expected exact columns to be decoded, got %+v", expressions[1])
+	} else if expressions[1].Lower == nil || *expressions[1].Lower != 2.5 || expressions[1].Upper == nil || *expressions[1].Upper != 3.5 || expressions[1].Imag == nil || *expressions[1].Imag != -1.5 {
+		t.Errorf("Expected interval bounds and imaginary part to be decoded, got %+v", expressions[1])
+	} else if expressions[1].Unit == nil || *expressions[1].Unit != "m/s" {
+		t.Errorf("Expected unit to be decoded, got %+v", expressions[1])
+	} else if len(expressions[1].Vector) != 2 || expressions[1].Vector[1] != 2 || len(expressions[1].Matrix) != 2 || expressions[1].Matrix[1][1] != 1 {
+		t.Errorf("Expected vector and matrix results to be decoded, got %+v", expressions[1])
+	} else if expressions[1].Folded != 2 || expressions[1].Dispatched != 1 {
+		t.Errorf("Expected operation counts to be decoded, got %+v", expressions[1])
+	} else if expressions[1].Depth != 3 || expressions[1].CriticalPath != 4000 {
+		t.Errorf("Expected plan shape to be decoded, got %+v", expressions[1])
+	} else if len(expressions[1].Numerics) != 1 || expressions[1].Numerics[0].Iterations != 80 || expressions[1].Numerics[0].Tasks[0] != "$t1" {
+		t.Errorf("Expected numerics to be decoded, got %+v", expressions[1].Numerics)
+	}
+
+	mock.ExpectQuery("SELECT id, user_id, expression, status, result, mode, exact, decimal, lower, upper, imag, unit, vector, matrix, error, variables, lists, result_ref, bindings, assignments, folded, dispatched, depth, critical_path, numerics, created_at, updated_at FROM expressions WHERE user_id = \\? ORDER BY created_at DESC").
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
@@ -262,6 +280,198 @@ func TestDatabaseService_GetUserExpressions_Mock(t *testing.T) {
 	}
 }
 
+func TestDatabaseService_CreateExpressionWithVariables_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	expr := &models.Expression{
+		ID:         "test-id",
+		UserID:     1,
+		Expression: "rate*2",
+		Status:     models.StatusPending,
+		Variables:  map[string]float64{"rate": 0.07},
+		Lists:      map[string]string{"prices": "sha256:ab"},
+		ResultRef:  "$t1",
+		Bindings:   map[string]string{"x": "$t1"},
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO expressions").
+		WithArgs(expr.ID, expr.UserID, expr.Expression, expr.Status, nil, `{"rate":0.07}`, `{"prices":"sha256:ab"}`, "$t1", `{"x":"$t1"}`, 0, 0, 0, 0, nil, expr.CreatedAt, expr.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := service.CreateExpression(expr); err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_SetVariable_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "rate", 0.07, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	variable, err := service.SetVariable(1, "rate", 0.07)
+	if err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+	if variable.Name != "rate" || variable.Value != 0.07 {
+		t.Errorf("Unexpected variable %+v", variable)
+	}
+
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "rate", 0.08, sqlmock.AnyArg()).
+		WillReturnError(errors.New("database error"))
+
+	if _, err := service.SetVariable(1, "rate", 0.08); err == nil {
+		t.Error("Expected error for database failure")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_SetList_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "prices", "[1.5,2,4]", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	variable, err := service.SetList(1, "prices", []float64{1.5, 2, 4})
+	if err != nil {
+		t.Fatalf("Failed to set list: %v", err)
+	}
+	if variable.Name != "prices" || len(variable.Values) != 3 {
+		t.Errorf("Unexpected variable %+v", variable)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_GetUserVariables_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	rows := sqlmock.NewRows([]string{"user_id", "name", "value", "vals", "updated_at"}).
+		AddRow(1, "prices", 0.0, "[1.5,2,4]", time.Now()).
+		AddRow(1, "rate", 0.07, nil, time.Now()).
+		AddRow(1, "years", 10.0, nil, time.Now())
+
+	mock.ExpectQuery("SELECT user_id, name, value, vals, updated_at FROM variables WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	variables, err := service.GetUserVariables(1)
+	if err != nil {
+		t.Fatalf("Failed to get variables: %v", err)
+	}
+	if len(variables) != 3 || variables[2].Name != "years" || variables[2].Values != nil {
+		t.Errorf("Unexpected variables %+v", variables)
+	}
+	if values := variables[0].Values; len(values) != 3 || values[2] != 4 {
+		t.Errorf("Expected list values to be decoded, got %v", values)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_SetFunction_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "hyp", `["a","b"]`, "hyp(a,b) = sqrt(a*a+b*b)", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	function, err := service.SetFunction(1, "hyp", []string{"a", "b"}, "hyp(a,b) = sqrt(a*a+b*b)")
+	if err != nil {
+		t.Fatalf("Failed to set function: %v", err)
+	}
+	if function.Name != "hyp" || len(function.Params) != 2 {
+		t.Errorf("Unexpected function %+v", function)
+	}
+
+	mock.ExpectExec("INSERT INTO functions").
+		WillReturnError(errors.New("database error"))
+
+	if _, err := service.SetFunction(1, "sq", []string{"x"}, "sq(x) = x*x"); err == nil {
+		t.Error("Expected error for database failure")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_GetUserFunctions_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	rows := sqlmock.NewRows([]string{"user_id", "name", "params", "definition", "updated_at"}).
+		AddRow(1, "hyp", `["a","b"]`, "hyp(a,b) = sqrt(a*a+b*b)", time.Now()).
+		AddRow(1, "sq", `["x"]`, "sq(x) = x*x", time.Now())
+
+	mock.ExpectQuery("SELECT user_id, name, params, definition, updated_at FROM functions WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	functions, err := service.GetUserFunctions(1)
+	if err != nil {
+		t.Fatalf("Failed to get functions: %v", err)
+	}
+	if len(functions) != 2 || functions[0].Params[1] != "b" || functions[1].Name != "sq" {
+		t.Errorf("Unexpected functions %+v", functions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestDatabaseService_CreateTask_Mock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -284,7 +494,7 @@ func TestDatabaseService_CreateTask_Mock(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO tasks").
-		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, task.Operation, task.OperationTime, task.Status, task.CreatedAt, task.UpdatedAt).
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, nil, task.Operation, task.OperationTime, task.Status, nil, nil, nil, nil, task.CreatedAt, task.UpdatedAt).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = service.CreateTask(task)
@@ -293,7 +503,7 @@ func TestDatabaseService_CreateTask_Mock(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO tasks").
-		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, task.Operation, task.OperationTime, task.Status, task.CreatedAt, task.UpdatedAt).
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, nil, task.Operation, task.OperationTime, task.Status, nil, nil, nil, nil, task.CreatedAt, task.UpdatedAt).
 		WillReturnError(errors.New("database error"))
 
 	err = service.CreateTask(task)
@@ -306,6 +516,41 @@ func TestDatabaseService_CreateTask_Mock(t *testing.T) {
 	}
 }
 
+func TestDatabaseService_CreateExpressionWithTasks_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	expr := &models.Expression{ID: "expr-id", UserID: 1, Expression: "-2", Status: models.StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	task := &models.Task{ID: "task-id", ExpressionID: "expr-id", Arg1: "2", Operation: "neg", Status: "pending", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO expressions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO tasks").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := service.CreateExpressionWithTasks(expr, []*models.Task{task}); err != nil {
+		t.Fatalf("Failed to create expression with tasks: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO expressions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO tasks").WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	if err := service.CreateExpressionWithTasks(expr, []*models.Task{task}); err == nil {
+		t.Error("Expected error when a task cannot be saved")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestDatabaseService_GetTask_Mock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -315,10 +560,10 @@ func TestDatabaseService_GetTask_Mock(t *testing.T) {
 
 	service := &DatabaseService{db: db}
 
-	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "operation", "operation_time", "status", "result", "created_at", "updated_at"}).
-		AddRow("task-id", "expr-id", "2", "2", "+", 1000, "pending", nil, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id", "expr-id", "2", "2", nil, "+", 1000, "pending", nil, nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE id = \\?").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
 		WithArgs("task-id").
 		WillReturnRows(rows)
 
@@ -331,7 +576,7 @@ func TestDatabaseService_GetTask_Mock(t *testing.T) {
 		t.Errorf("Expected ID 'task-id', got '%s'", task.ID)
 	}
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE id = \\?").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
 		WithArgs("nonexistent").
 		WillReturnError(sql.ErrNoRows)
 
@@ -345,6 +590,204 @@ func TestDatabaseService_GetTask_Mock(t *testing.T) {
 	}
 }
 
+func TestDatabaseService_UnaryTask_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	task := &models.Task{
+		ID:            "task-id",
+		ExpressionID:  "expr-id",
+		Arg1:          "16",
+		Operation:     "sqrt",
+		OperationTime: 1000,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, "", nil, task.Operation, task.OperationTime, task.Status, nil, nil, nil, nil, task.CreatedAt, task.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := service.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id", "expr-id", "16", nil, nil, "sqrt", 1000, "pending", nil, nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now())
+
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
+		WithArgs("task-id").
+		WillReturnRows(rows)
+
+	loaded, err := service.GetTask("task-id")
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if loaded.Arg2 != "" {
+		t.Errorf("Expected empty Arg2 for unary task, got '%s'", loaded.Arg2)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_ThreeArgumentTask_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	task := &models.Task{
+		ID:            "task-id",
+		ExpressionID:  "expr-id",
+		Arg1:          "2",
+		Arg2:          "$t1",
+		Arg3:          "1000",
+		Operation:     "modpow",
+		OperationTime: 1000,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, task.Arg3, task.Operation, task.OperationTime, task.Status, nil, nil, nil, nil, task.CreatedAt, task.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := service.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id", "expr-id", "2", "$t1", "1000", "modpow", 1000, "done", 376.0, nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now())
+
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
+		WithArgs("task-id").
+		WillReturnRows(rows)
+
+	loaded, err := service.GetTask("task-id")
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if loaded.Arg3 != "1000" || loaded.Arg2 != "$t1" {
+		t.Errorf("Expected all three arguments to be loaded, got %+v", loaded)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_ConditionalTask_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	task := &models.Task{
+		ID:           "task-id",
+		ExpressionID: "expr-id",
+		Arg1:         "$then",
+		Arg2:         "0",
+		Operation:    "if",
+		Status:       "waiting",
+		Condition:    "$cond",
+		Guard:        "!$outer",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, nil, task.Operation, task.OperationTime, task.Status, "$cond", "!$outer", nil, nil, task.CreatedAt, task.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := service.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id", "expr-id", "$then", "0", nil, "if", 0, "waiting", nil, nil, "$cond", "!$outer", nil, nil, nil, 0, nil, time.Now(), time.Now())
+
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
+		WithArgs("task-id").
+		WillReturnRows(rows)
+
+	loaded, err := service.GetTask("task-id")
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if loaded.Condition != "$cond" || loaded.Guard != "!$outer" {
+		t.Errorf("Expected condition and guard to round-trip, got %+v", loaded)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDatabaseService_ChunkTask_Mock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := &DatabaseService{db: db}
+
+	task := &models.Task{
+		ID:           "task-id",
+		ExpressionID: "expr-id",
+		Arg1:         "1",
+		Arg2:         "10000",
+		Operation:    "sum",
+		Status:       "pending",
+		Index:        "i",
+		Body:         "i^2",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO tasks").
+		WithArgs(task.ID, task.ExpressionID, task.Arg1, task.Arg2, nil, task.Operation, task.OperationTime, task.Status, nil, nil, "i", "i^2", task.CreatedAt, task.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := service.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id", "expr-id", "1", "10000", nil, "sum", 1000, "pending", nil, nil, nil, nil, "i", "i^2", nil, 0, nil, time.Now(), time.Now())
+
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE id = \\?").
+		WithArgs("task-id").
+		WillReturnRows(rows)
+
+	loaded, err := service.GetTask("task-id")
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if loaded.Index != "i" || loaded.Body != "i^2" {
+		t.Errorf("Expected index and body to round-trip, got %+v", loaded)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestDatabaseService_UpdateTask_Mock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -360,8 +803,8 @@ func TestDatabaseService_UpdateTask_Mock(t *testing.T) {
 		Result: &[]float64{4.0}[0],
 	}
 
-	mock.ExpectExec("UPDATE tasks SET status = \\?, result = \\?, updated_at = \\? WHERE id = \\?").
-		WithArgs(task.Status, task.Result, sqlmock.AnyArg(), task.ID).
+	mock.ExpectExec("UPDATE tasks SET status = \\?, result = \\?, value = \\?, error_estimate = \\?, iterations = \\?, error = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs(task.Status, task.Result, task.Value, task.ErrorEstimate, task.Iterations, task.Error, sqlmock.AnyArg(), task.ID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = service.UpdateTask(task)
@@ -369,8 +812,8 @@ func TestDatabaseService_UpdateTask_Mock(t *testing.T) {
 		t.Fatalf("Failed to update task: %v", err)
 	}
 
-	mock.ExpectExec("UPDATE tasks SET status = \\?, result = \\?, updated_at = \\? WHERE id = \\?").
-		WithArgs(task.Status, task.Result, sqlmock.AnyArg(), task.ID).
+	mock.ExpectExec("UPDATE tasks SET status = \\?, result = \\?, value = \\?, error_estimate = \\?, iterations = \\?, error = \\?, updated_at = \\? WHERE id = \\?").
+		WithArgs(task.Status, task.Result, task.Value, task.ErrorEstimate, task.Iterations, task.Error, sqlmock.AnyArg(), task.ID).
 		WillReturnError(errors.New("database error"))
 
 	err = service.UpdateTask(task)
@@ -392,10 +835,10 @@ func TestDatabaseService_GetPendingTasks_Mock(t *testing.T) {
 
 	service := &DatabaseService{db: db}
 
-	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "operation", "operation_time", "status", "result", "created_at", "updated_at"}).
-		AddRow("task-id-1", "expr-id", "2", "2", "+", 1000, "pending", nil, time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id-1", "expr-id", "2", "2", nil, "+", 1000, "pending", nil, nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE status = 'pending' ORDER BY created_at ASC").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE status = 'pending' ORDER BY created_at ASC").
 		WillReturnRows(rows)
 
 	tasks, err := service.GetPendingTasks()
@@ -411,7 +854,7 @@ func TestDatabaseService_GetPendingTasks_Mock(t *testing.T) {
 		t.Errorf("Expected task ID 'task-id-1', got '%s'", tasks[0].ID)
 	}
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE status = 'pending' ORDER BY created_at ASC").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE status = 'pending' ORDER BY created_at ASC").
 		WillReturnError(errors.New("database error"))
 
 	_, err = service.GetPendingTasks()
@@ -433,11 +876,11 @@ func TestDatabaseService_GetTasksByExpressionID_Mock(t *testing.T) {
 
 	service := &DatabaseService{db: db}
 
-	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "operation", "operation_time", "status", "result", "created_at", "updated_at"}).
-		AddRow("task-id-1", "expr-id", "2", "2", "+", 1000, "pending", nil, time.Now(), time.Now()).
-		AddRow("task-id-2", "expr-id", "3", "3", "+", 1000, "completed", &[]float64{6.0}[0], time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "expression_id", "arg1", "arg2", "arg3", "operation", "operation_time", "status", "result", "value", "condition", "guard", "range_index", "body", "error_estimate", "iterations", "error", "created_at", "updated_at"}).
+		AddRow("task-id-1", "expr-id", "2", "2", nil, "+", 1000, "pending", nil, nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now()).
+		AddRow("task-id-2", "expr-id", "3", "3", nil, "+", 1000, "completed", &[]float64{6.0}[0], nil, nil, nil, nil, nil, nil, 0, nil, time.Now(), time.Now())
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE expression_id = \\? ORDER BY created_at ASC").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE expression_id = \\? ORDER BY created_at ASC").
 		WithArgs("expr-id").
 		WillReturnRows(rows)
 
@@ -450,7 +893,7 @@ func TestDatabaseService_GetTasksByExpressionID_Mock(t *testing.T) {
 		t.Errorf("Expected 2 tasks, got %d", len(tasks))
 	}
 
-	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, operation, operation_time, status, result, created_at, updated_at FROM tasks WHERE expression_id = \\? ORDER BY created_at ASC").
+	mock.ExpectQuery("SELECT id, expression_id, arg1, arg2, arg3, operation, operation_time, status, result, value, condition, guard, range_index, body, error_estimate, iterations, error, created_at, updated_at FROM tasks WHERE expression_id = \\? ORDER BY created_at ASC").
 		WithArgs("expr-id").
 		WillReturnError(errors.New("database error"))
 
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"calculator/evaluator"
 	"calculator/models"
+	"calculator/parser"
+	"calculator/symbolic"
 	"fmt"
 	"strconv"
 	"time"
@@ -16,86 +19,125 @@ func NewExpressionService(db *DatabaseService) *ExpressionService {
 }
 
 func (es *ExpressionService) CreateExpression(userID int, expr string) (*models.Expression, error) {
-	if _, err := Calc(expr); err != nil {
+	return es.CreateExpressionInMode(userID, expr, "")
+}
+
+func (es *ExpressionService) CreateExpressionInMode(userID int, expr string, mode string) (*models.Expression, error) {
+	return es.CreateExpressionFromRequest(userID, &models.RequestBody{Expression: expr, Mode: mode})
+}
+
+func (es *ExpressionService) CreateExpressionFromRequest(userID int, req *models.RequestBody) (*models.Expression, error) {
+	expr := req.Expression
+	mode, err := normalizeMode(req.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := parser.ParseProgram(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: ошибка в выражении: %w", err)
+	}
+
+	functions, err := loadUserFunctions(es.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	program, err = parser.ExpandProgram(program, functions, maxExpansionNodes())
+	if err != nil {
 		return nil, fmt.Errorf("invalid expression: %v", err)
 	}
+	if req.Simplify {
+		program, err = symbolic.SimplifyProgram(program)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression: %v", err)
+		}
+	}
+
+	userVariables, lists, err := es.userVariables(userID)
+	if err != nil {
+		return nil, err
+	}
+	listHashes := listDigests(referencedVariables(program, lists))
+	if program, err = bindLists(program, lists); err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	variables := referencedVariables(program, userVariables)
+	if err := checkProgram(program, variables, mode); err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
 
 	id := strconv.FormatInt(time.Now().UnixNano(), 10)
-	expression := &models.Expression{
-		ID:         id,
-		UserID:     userID,
-		Expression: expr,
-		Status:     models.StatusPending,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	plan, err := planTasks(id, program, variables, mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
 	}
 
-	if err := es.db.CreateExpression(expression); err != nil {
+	expression := &models.Expression{
+		ID:           id,
+		UserID:       userID,
+		Expression:   expr,
+		Status:       models.StatusPending,
+		Mode:         mode,
+		Variables:    variables,
+		Lists:        listHashes,
+		ResultRef:    plan.Result,
+		Bindings:     plan.Bindings,
+		Folded:       plan.Folded,
+		Dispatched:   plan.Dispatched(),
+		Depth:        plan.Depth,
+		CriticalPath: plan.CriticalPath,
+		Numerics:     plan.Numerics,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := es.db.CreateExpressionWithTasks(expression, plan.Tasks); err != nil {
 		return nil, fmt.Errorf("error saving expression: %v", err)
 	}
 
-	if err := es.splitExpressionIntoTasks(expression); err != nil {
-		return nil, fmt.Errorf("error creating tasks: %v", err)
+	if len(plan.Tasks) == 0 {
+		if err := es.checkExpressionCompletion(expression.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	return expression, nil
 }
 
-func (es *ExpressionService) GetExpression(id string, userID int) (*models.Expression, error) {
-	return es.db.GetExpression(id, userID)
-}
-
-func (es *ExpressionService) GetUserExpressions(userID int) ([]*models.Expression, error) {
-	return es.db.GetUserExpressions(userID)
+func normalizeMode(mode string) (string, error) {
+	switch mode {
+	case "", "float":
+		return "", nil
+	case evaluator.ModeExact:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported mode %q", mode)
 }
 
-func (es *ExpressionService) splitExpressionIntoTasks(exp *models.Expression) error {
-	tree, err := parseExpression(exp.Expression)
+func (es *ExpressionService) userVariables(userID int) (map[string]float64, map[string][]float64, error) {
+	stored, err := es.db.GetUserVariables(userID)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("error loading variables: %v", err)
 	}
 
-	taskCounter := 1
-	var createTasks func(*Operation) (string, error)
-	createTasks = func(op *Operation) (string, error) {
-		if op.IsValue {
-			return fmt.Sprintf("%v", op.Value), nil
-		}
-
-		leftArg, err := createTasks(op.Left)
-		if err != nil {
-			return "", err
-		}
-
-		rightArg, err := createTasks(op.Right)
-		if err != nil {
-			return "", err
+	variables := make(map[string]float64, len(stored))
+	lists := make(map[string][]float64)
+	for _, variable := range stored {
+		if variable.Values != nil {
+			lists[variable.Name] = variable.Values
+			continue
 		}
-
-		taskID := fmt.Sprintf("%s_task%d", exp.ID, taskCounter)
-		taskCounter++
-
-		task := &models.Task{
-			ID:            taskID,
-			ExpressionID:  exp.ID,
-			Arg1:          leftArg,
-			Arg2:          rightArg,
-			Operation:     op.Type,
-			OperationTime: getOperationTime(op.Type),
-			Status:        "pending",
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
-		}
-
-		if err := es.db.CreateTask(task); err != nil {
-			return "", fmt.Errorf("error saving task: %v", err)
-		}
-
-		return fmt.Sprintf("$%s", taskID), nil
+		variables[variable.Name] = variable.Value
 	}
+	return variables, lists, nil
+}
 
-	_, err = createTasks(tree)
-	return err
+func (es *ExpressionService) GetExpression(id string, userID int) (*models.Expression, error) {
+	return es.db.GetExpression(id, userID)
+}
+
+func (es *ExpressionService) GetUserExpressions(userID int) ([]*models.Expression, error) {
+	return es.db.GetUserExpressions(userID)
 }
 
 func (es *ExpressionService) GetNextTask() (*models.Task, error) {
@@ -130,6 +172,14 @@ func (es *ExpressionService) GetTaskByID(taskID string) (*models.Task, error) {
 }
 
 func (es *ExpressionService) SubmitTaskResult(taskID string, result float64) error {
+	return es.SubmitTaskValue(taskID, result, nil)
+}
+
+func (es *ExpressionService) SubmitTaskValue(taskID string, result float64, value *string) error {
+	return es.SubmitTaskReport(taskID, &models.TaskResult{Result: result, Value: value})
+}
+
+func (es *ExpressionService) SubmitTaskReport(taskID string, report *models.TaskResult) error {
 	task, err := es.db.GetTask(taskID)
 	if err != nil {
 		return fmt.Errorf("task not found: %v", err)
@@ -139,8 +189,25 @@ func (es *ExpressionService) SubmitTaskResult(taskID string, result float64) err
 		return fmt.Errorf("invalid task status: %s", task.Status)
 	}
 
-	task.Result = &result
-	task.Status = "done"
+	if report.Error != nil {
+		task.Error = report.Error
+		task.Status = "failed"
+	} else {
+		result := report.Result
+		if report.Value != nil {
+			parsed, err := evaluator.ParseValue(*report.Value)
+			if err != nil {
+				return fmt.Errorf("invalid task value: %v", err)
+			}
+			result = parsed.Float()
+		}
+
+		task.Result = &result
+		task.Value = report.Value
+		task.ErrorEstimate = report.ErrorEstimate
+		task.Iterations = report.Iterations
+		task.Status = "done"
+	}
 	task.UpdatedAt = time.Now()
 
 	if err := es.db.UpdateTask(task); err != nil {
@@ -161,22 +228,18 @@ func (es *ExpressionService) checkExpressionCompletion(expressionID string) erro
 		return fmt.Errorf("error getting tasks: %v", err)
 	}
 
-	allDone := true
-	var lastResult float64
-	for _, task := range exprTasks {
-		if task.Status != "done" {
-			allDone = false
-			break
-		}
-		if task.Result != nil {
-			lastResult = *task.Result
+	for _, task := range advanceTasks(exprTasks) {
+		task.UpdatedAt = time.Now()
+		if err := es.db.UpdateTask(task); err != nil {
+			return fmt.Errorf("error updating task: %v", err)
 		}
 	}
 
-	if allDone {
-		expr.Status = models.StatusDone
-		expr.Result = &lastResult
-	} else {
+	done, err := completeExpression(expr, exprTasks)
+	if err != nil {
+		return fmt.Errorf("error resolving result: %v", err)
+	}
+	if !done {
 		expr.Status = models.StatusComputing
 	}
 
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"calculator/evaluator"
+	"calculator/models"
+	"calculator/parser"
+	"fmt"
+	"strings"
+)
+
+type FunctionService struct {
+	db *DatabaseService
+}
+
+func NewFunctionService(db *DatabaseService) *FunctionService {
+	return &FunctionService{db: db}
+}
+
+func (fs *FunctionService) DefineFunction(userID int, req *models.FunctionRequest) (*models.Function, error) {
+	def, err := parser.ParseDefinition(req.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid definition: %w", err)
+	}
+	if evaluator.IsReserved(def.Name) {
+		return nil, fmt.Errorf("name %q is reserved", def.Name)
+	}
+
+	params := make(map[string]bool, len(def.Params))
+	for _, param := range def.Params {
+		if evaluator.IsReserved(param) {
+			return nil, fmt.Errorf("parameter name %q is reserved", param)
+		}
+		if params[param] {
+			return nil, fmt.Errorf("duplicate parameter %q", param)
+		}
+		params[param] = true
+	}
+
+	functions, err := loadUserFunctions(fs.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	functions[def.Name] = def
+
+	if err := checkFunctionBody(def, params, functions); err != nil {
+		return nil, err
+	}
+
+	call := &parser.Call{Name: def.Name, At: def.At}
+	for _, param := range def.Params {
+		call.Args = append(call.Args, &parser.Ident{Name: param, At: def.At})
+	}
+	if _, err := parser.Expand(call, functions, maxExpansionNodes()); err != nil {
+		return nil, fmt.Errorf("invalid definition: %v", err)
+	}
+
+	return fs.db.SetFunction(userID, def.Name, def.Params, strings.TrimSpace(req.Definition))
+}
+
+func (fs *FunctionService) GetFunctions(userID int) ([]*models.Function, error) {
+	return fs.db.GetUserFunctions(userID)
+}
+
+func checkFunctionBody(def *parser.Definition, params map[string]bool, functions map[string]*parser.Definition) error {
+	return checkBody(def.Body, params, functions)
+}
+
+func checkBody(body parser.Node, params map[string]bool, functions map[string]*parser.Definition) error {
+	var err error
+	parser.Inspect(body, func(node parser.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := node.(type) {
+		case *parser.Ident:
+			if _, constant := evaluator.Constant(n.Name); !params[n.Name] && !constant && n.Name != evaluator.ImaginaryUnit {
+				err = fmt.Errorf("unknown variable %q in function body", n.Name)
+			}
+		case *parser.Call:
+			if index, ok := evaluator.RangeIndex(n); ok {
+				bound := map[string]bool{index: true}
+				for name := range params {
+					bound[name] = true
+				}
+				if err = checkBody(&parser.Call{Name: n.Name, Args: n.Args[1:3]}, params, functions); err == nil {
+					err = checkBody(n.Args[3], bound, functions)
+				}
+				return false
+			}
+			if evaluator.IsNumeric(n.Name) {
+				nc, parseErr := evaluator.ParseNumeric(n)
+				if parseErr != nil {
+					err = parseErr
+					return false
+				}
+				bound := map[string]bool{nc.Variable: true}
+				for name := range params {
+					bound[name] = true
+				}
+				for _, limit := range []parser.Node{nc.From, nc.To} {
+					if err = checkBody(limit, params, functions); err != nil {
+						return false
+					}
+				}
+				err = checkBody(nc.Body, bound, functions)
+				return false
+			}
+			if _, defined := functions[n.Name]; !defined && !evaluator.IsFunction(n.Name) && !evaluator.IsAggregate(n.Name) && !evaluator.IsNumberTheory(n.Name) && !evaluator.IsMatrixOperation(n.Name) && !evaluator.IsStatistic(n.Name) && n.Name != evaluator.Conditional && n.Name != evaluator.IntervalCall && n.Name != evaluator.ComplexCall && n.Name != evaluator.UnitCall && n.Name != evaluator.ConvertCall {
+				err = fmt.Errorf("unknown function %q in function body", n.Name)
+			}
+		}
+		return true
+	})
+	return err
+}
+
+func loadUserFunctions(db *DatabaseService, userID int) (map[string]*parser.Definition, error) {
+	stored, err := db.GetUserFunctions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading functions: %v", err)
+	}
+
+	functions := make(map[string]*parser.Definition, len(stored))
+	for _, function := range stored {
+		def, err := parser.ParseDefinition(function.Definition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stored function %q: %v", function.Name, err)
+		}
+		functions[def.Name] = def
+	}
+	return functions, nil
+}
+
+func maxExpansionNodes() int {
+	return int(getEnvInt64("MAX_EXPANSION_NODES", 10000))
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"calculator/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectFunctions(mock sqlmock.Sqlmock, userID int, definitions map[string]string) {
+	rows := sqlmock.NewRows([]string{"user_id", "name", "params", "definition", "updated_at"})
+	for name, definition := range definitions {
+		rows.AddRow(userID, name, "[]", definition, time.Now())
+	}
+	mock.ExpectQuery("SELECT user_id, name, params, definition, updated_at FROM functions WHERE user_id = \\?").
+		WithArgs(userID).
+		WillReturnRows(rows)
+}
+
+func TestFunctionService_DefineFunction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, map[string]string{"sq": "sq(x) = x*x"})
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "hyp", `["a","b"]`, "hyp(a,b) = sqrt(sq(a)+sq(b))", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	function, err := service.DefineFunction(1, &models.FunctionRequest{Definition: " hyp(a,b) = sqrt(sq(a)+sq(b)) "})
+	if err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+	if function.Name != "hyp" || len(function.Params) != 2 {
+		t.Errorf("Unexpected function %+v", function)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_Conditional(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, nil)
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "sign", `["x"]`, "sign(x) = if(x < 0, -1, x > 0)", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "sign(x) = if(x < 0, -1, x > 0)"}); err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_Range(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, nil)
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "squares", `["n"]`, "squares(n) = sum(i, 1, n, i^2)", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "squares(n) = sum(i, 1, n, i^2)"}); err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_Complex(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, nil)
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "rot", `["z"]`, "rot(z) = z*i + complex(z, 2i)", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "rot(z) = z*i + complex(z, 2i)"}); err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_Units(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, nil)
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "pace", `["n"]`, "pace(n) = n*1 km/(30 min) to km/h", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "pace(n) = n*1 km/(30 min) to km/h"}); err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_NumberTheory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, nil)
+	mock.ExpectExec("INSERT INTO functions").
+		WithArgs(1, "choose", `["n","k"]`, "choose(n, k) = n!/(k!*(n-k)!) + 0*gcd(n, k)", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "choose(n, k) = n!/(k!*(n-k)!) + 0*gcd(n, k)"}); err != nil {
+		t.Fatalf("Failed to define function: %v", err)
+	}
+
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "gcd(a, b) = a*b"}); err == nil {
+		t.Error("Expected error for redefining a built-in number theory function")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_Invalid(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	rejectedBeforeLoading := []string{
+		"",
+		"hyp = 1",
+		"sqrt(x) = x",
+		"pi() = 3",
+		"f(pi) = pi",
+		"f(a, a) = a",
+		"if(a, b, c) = a",
+	}
+	for _, definition := range rejectedBeforeLoading {
+		if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: definition}); err == nil {
+			t.Errorf("Expected error for definition %q", definition)
+		}
+	}
+
+	existing := map[string]string{
+		"ping": "ping(x) = x+1",
+		"d1":   "d1(x) = x+x",
+	}
+	rejected := []string{
+		"f(x) = x + rate",
+		"f(x) = g(x)",
+		"f(n) = sum(i, 1, i, i)",
+		"f(n) = integrate(t, 0, n)",
+		"f(n) = integrate(n, x, 0)",
+		"f(x) = f(x-1)",
+		"f(x) = if(x > 0, x * f(x-1), 1)",
+		"pong(x) = ping(x)*pong(x)",
+		"d2(x) = d1(d1(d1(d1(d1(d1(d1(d1(d1(d1(d1(d1(d1(d1(x))))))))))))))",
+	}
+	for _, definition := range rejected {
+		expectFunctions(mock, 1, existing)
+		if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: definition}); err == nil {
+			t.Errorf("Expected error for definition %q", definition)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestFunctionService_DefineFunction_MutualRecursion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewFunctionService(&DatabaseService{db: db})
+
+	expectFunctions(mock, 1, map[string]string{
+		"ping": "ping(x) = pong(x)+1",
+		"pong": "pong(x) = x*2",
+	})
+	if _, err := service.DefineFunction(1, &models.FunctionRequest{Definition: "pong(x) = ping(x)"}); err == nil {
+		t.Error("Expected redefinition creating a cycle to be rejected")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+)
+
+const defaultFoldThreshold = 1000
+
+type foldCost struct {
+	operations int
+	time       int64
+	constant   bool
+}
+
+func foldThreshold() int64 {
+	return getEnvInt64("FOLD_THRESHOLD_MS", defaultFoldThreshold)
+}
+
+func (tp *taskPlanner) fold(node parser.Node) (string, bool) {
+	if tp.threshold <= 0 {
+		return "", false
+	}
+	cost := tp.cost(node)
+	if !cost.constant || cost.operations == 0 || cost.time > tp.threshold {
+		return "", false
+	}
+
+	scope := make(map[string]evaluator.Value, len(tp.scope)+len(tp.plan.Bindings))
+	for name, value := range tp.scope {
+		scope[name] = value
+	}
+	for name, arg := range tp.plan.Bindings {
+		if isTaskRef(arg) {
+			delete(scope, name)
+			continue
+		}
+		value, err := evaluator.ParseValue(arg)
+		if err != nil {
+			return "", false
+		}
+		scope[name] = value
+	}
+
+	value, err := evaluator.Evaluate(node, scope, tp.mode)
+	if err != nil {
+		return "", false
+	}
+	tp.plan.Folded += cost.operations
+	return value.String(), true
+}
+
+func (tp *taskPlanner) cost(node parser.Node) foldCost {
+	if cost, ok := tp.costs[node]; ok {
+		return cost
+	}
+	cost := tp.measure(node)
+	if tp.costs == nil {
+		tp.costs = make(map[parser.Node]foldCost)
+	}
+	tp.costs[node] = cost
+	return cost
+}
+
+func (tp *taskPlanner) measure(node parser.Node) foldCost {
+	switch n := node.(type) {
+	case *parser.Number:
+		return foldCost{constant: true}
+	case *parser.Ident:
+		arg, ok := tp.plan.Bindings[n.Name]
+		return foldCost{constant: !ok || !isTaskRef(arg)}
+	case *parser.UnaryOp:
+		cost := tp.cost(n.Operand)
+		if n.Op == "+" {
+			return cost
+		}
+		return cost.add(1, getOperationTime(evaluator.UnaryOperation(n.Op)))
+	case *parser.BinaryOp:
+		cost := tp.cost(n.Left).join(tp.cost(n.Right))
+		if n.Op == evaluator.PlusMinus {
+			return cost
+		}
+		if !evaluator.IsLogical(n.Op) {
+			return cost.add(1, getOperationTime(n.Op))
+		}
+		if !isBoolean(n.Right) {
+			return cost.add(1, getOperationTime("!="))
+		}
+		return cost
+	case *parser.Call:
+		if n.Name == evaluator.Conditional && len(n.Args) == 3 {
+			cond, then, otherwise := tp.cost(n.Args[0]), tp.cost(n.Args[1]), tp.cost(n.Args[2])
+			cost := cond.join(then).join(otherwise)
+			cost.time = cond.time + then.time
+			if otherwise.time > then.time {
+				cost.time = cond.time + otherwise.time
+			}
+			return cost
+		}
+		if _, ok := evaluator.RangeIndex(n); ok || evaluator.IsNumeric(n.Name) || len(n.Args) == 0 {
+			return foldCost{}
+		}
+		if evaluator.IsNumberTheory(n.Name) || evaluator.IsStatistic(n.Name) || (evaluator.IsMatrixOperation(n.Name) && n.Name != evaluator.VectorCall && n.Name != evaluator.MatrixCall) {
+			return foldCost{}
+		}
+		if n.Name == evaluator.UnitCall || n.Name == evaluator.ConvertCall {
+			return tp.cost(n.Args[0])
+		}
+		cost := foldCost{constant: true}
+		for _, arg := range n.Args {
+			cost = cost.join(tp.cost(arg))
+		}
+		if n.Name == evaluator.IntervalCall || n.Name == evaluator.ComplexCall || n.Name == evaluator.VectorCall || n.Name == evaluator.MatrixCall {
+			return cost
+		}
+		if evaluator.IsFunction(n.Name) {
+			return cost.add(1, getOperationTime(n.Name))
+		}
+		op, err := evaluator.AggregateOperation(n.Name)
+		if err != nil {
+			return foldCost{}
+		}
+		steps := len(n.Args) - 1
+		cost = cost.add(steps, int64(steps)*getOperationTime(op))
+		if n.Name == "avg" {
+			cost = cost.add(1, getOperationTime("/"))
+		}
+		return cost
+	}
+	return foldCost{}
+}
+
+func (c foldCost) add(operations int, time int64) foldCost {
+	c.operations += operations
+	c.time += time
+	return c
+}
+
+func (c foldCost) join(other foldCost) foldCost {
+	return foldCost{
+		operations: c.operations + other.operations,
+		time:       c.time + other.time,
+		constant:   c.constant && other.constant,
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"calculator/models"
+	"os"
+	"testing"
+)
+
+func TestPlanTasks_FoldsByDefault(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "")
+	os.Unsetenv("FOLD_THRESHOLD_MS")
+
+	compiled, err := compile("1+1", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 0 || compiled.Folded != 1 {
+		t.Errorf("Expected nothing dispatched and 1 folded, got %d tasks and %d folded", len(compiled.Tasks), compiled.Folded)
+	}
+}
+
+func TestPlanTasks_FoldingDisabled(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "0")
+
+	compiled, err := compile("1+1", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 1 || compiled.Folded != 0 {
+		t.Errorf("Expected 1 task and nothing folded, got %d tasks and %d folded", len(compiled.Tasks), compiled.Folded)
+	}
+}
+
+func TestPlanTasks_FoldsCheapSubtrees(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "1500")
+
+	compiled, err := compile("(1+2)*(3+4)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(compiled.Tasks))
+	}
+	mul := compiled.Tasks[0]
+	if mul.Operation != "*" || mul.Arg1 != "3" || mul.Arg2 != "7" {
+		t.Errorf("Expected task 3*7, got %s %s %s", mul.Arg1, mul.Operation, mul.Arg2)
+	}
+	if compiled.Folded != 2 || compiled.Dispatched() != 1 {
+		t.Errorf("Expected 2 folded and 1 dispatched, got %d and %d", compiled.Folded, compiled.Dispatched())
+	}
+}
+
+func TestPlanTasks_FoldThreshold(t *testing.T) {
+	tests := []struct {
+		threshold  string
+		expression string
+		folded     int
+		tasks      int
+	}{
+		{"1000", "1+1", 1, 0},
+		{"999", "1+1", 0, 1},
+		{"4000", "(1+2)*(3+4)", 3, 0},
+		{"3000", "sum(1, 2, 3, 4)", 3, 0},
+		{"2000", "sum(1+1, 2, 3)", 1, 2},
+		{"2000", "x*2", 1, 0},
+		{"1000", "sqrt(16) + 2*3", 1, 2},
+		{"3000", "if(1 < 2, 2*3, 2^10)", 3, 0},
+		{"100000", "1/0 + 2", 0, 2},
+		{"100000", "sum(i, 1, 3, i) + 2*3", 1, 2},
+		{"100000", "solve(x - 1, x, 0, 2) + 2*3", 1, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.threshold+"/"+test.expression, func(t *testing.T) {
+			t.Setenv("FOLD_THRESHOLD_MS", test.threshold)
+			compiled, err := compile(test.expression, map[string]float64{"x": 3})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if compiled.Folded != test.folded {
+				t.Errorf("Expected %d folded operations, got %d", test.folded, compiled.Folded)
+			}
+			if len(compiled.Tasks) != test.tasks {
+				t.Errorf("Expected %d tasks, got %d", test.tasks, len(compiled.Tasks))
+			}
+		})
+	}
+}
+
+func TestPlanTasks_DefaultThresholdDispatchesHeavyCalls(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "")
+	os.Unsetenv("FOLD_THRESHOLD_MS")
+
+	tests := []string{
+		"factor(2305843009213693951)",
+		"isprime(2305843009213693951)",
+		"100000!",
+		"gcd(12, 18)",
+		"det([[1, 2], [3, 4]])",
+		"matmul([[1, 2], [3, 4]], [1, 1])",
+		"stddev([1, 2, 3, 4])",
+	}
+
+	for _, expression := range tests {
+		t.Run(expression, func(t *testing.T) {
+			compiled, err := compile(expression, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) == 0 {
+				t.Errorf("Expected %s to be dispatched, got %d folded and no tasks", expression, compiled.Folded)
+			}
+		})
+	}
+}
+
+func TestPlanTasks_FoldingMatchesCalc(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "1000000")
+
+	tests := []struct {
+		expression string
+		mode       string
+	}{
+		{"2+3*4", ""},
+		{"0.1+0.2", ""},
+		{"-(4+1)^2", ""},
+		{"avg(1, 2, 4)", ""},
+		{"0xFF & 0x0F | 1 << 4", ""},
+		{"7 // 2 + 7 % 2", ""},
+		{"1 < 2 && 3 > 4 || !0", ""},
+		{"x = 2*pi; y = x/2; y*x", ""},
+		{"1/3 + 1/6", "exact"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expected, err := CalcInMode(test.expression, nil, test.mode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			compiled, err := compileInMode(test.expression, nil, test.mode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != 0 {
+				t.Errorf("Expected everything to be folded, got %d tasks", len(compiled.Tasks))
+			}
+			if compiled.Result != expected.String() {
+				t.Errorf("Expected %s, got %s", expected.String(), compiled.Result)
+			}
+		})
+	}
+}
+
+func TestPlanTasks_FoldingRespectsTaskBindings(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "1500")
+
+	compiled, err := compile("x = 2*3; y = 1+1; x + y", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 2 || compiled.Folded != 1 {
+		t.Fatalf("Expected 2 tasks and 1 folded, got %d tasks and %d folded", len(compiled.Tasks), compiled.Folded)
+	}
+	if compiled.Bindings["y"] != "2" {
+		t.Errorf("Expected y to be folded to 2, got %q", compiled.Bindings["y"])
+	}
+	if result := executePlan(t, compiled); result != 8 {
+		t.Errorf("Expected 8, got %v", result)
+	}
+}
+
+func TestPlanTasks_FoldingKeepsFailingBranch(t *testing.T) {
+	t.Setenv("FOLD_THRESHOLD_MS", "1500")
+
+	compiled, err := compile("if(2*3 > 5, 1+1, 1/0)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if compiled.Folded != 1 {
+		t.Errorf("Expected 1 folded operation, got %d", compiled.Folded)
+	}
+	if compiled.Dispatched() != 3 {
+		t.Errorf("Expected 3 dispatched operations, got %d", compiled.Dispatched())
+	}
+	if result := executePlan(t, compiled); result != 2 {
+		t.Errorf("Expected 2, got %v", result)
+	}
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Dispatched != 2 {
+		t.Errorf("Expected skipped branch not to count as dispatched, got %d", expr.Dispatched)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"calculator/models"
+	"calculator/parser"
+	"calculator/symbolic"
+	"fmt"
+)
+
+type SymbolicService struct {
+	db *DatabaseService
+}
+
+func NewSymbolicService(db *DatabaseService) *SymbolicService {
+	return &SymbolicService{db: db}
+}
+
+func (ss *SymbolicService) Transform(userID int, req *models.SymbolicRequest) (parser.Node, error) {
+	node, err := parser.Parse(req.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: ошибка в выражении: %w", err)
+	}
+
+	functions, err := loadUserFunctions(ss.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	node, err = parser.Expand(node, functions, maxExpansionNodes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+
+	var result parser.Node
+	switch req.Operation {
+	case "", symbolic.OpSimplify:
+		result, err = symbolic.Simplify(node)
+	case symbolic.OpExpand:
+		result, err = symbolic.Expand(node)
+	case symbolic.OpDiff:
+		if !parser.IsIdentifier(req.Variable) {
+			return nil, fmt.Errorf("invalid variable name %q", req.Variable)
+		}
+		result, err = symbolic.Diff(node, req.Variable)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", req.Operation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"calculator/models"
+	"calculator/parser"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSymbolicService_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  models.SymbolicRequest
+		expected string
+	}{
+		{"default operation", models.SymbolicRequest{Expression: "x + x"}, "2*x"},
+		{"simplify", models.SymbolicRequest{Expression: "(x+1)^2 - (x+1)^2 + x", Operation: "simplify"}, "x"},
+		{"expand", models.SymbolicRequest{Expression: "(x+1)^2", Operation: "expand"}, "x^2 + 2*x + 1"},
+		{"diff", models.SymbolicRequest{Expression: "x^3 + y*x", Operation: "diff", Variable: "x"}, "3*x^2 + y"},
+		{"user function", models.SymbolicRequest{Expression: "sq(x+1)", Operation: "expand"}, "x^2 + 2*x + 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock database: %v", err)
+			}
+			defer db.Close()
+
+			service := NewSymbolicService(&DatabaseService{db: db})
+			expectFunctions(mock, 1, map[string]string{"sq": "sq(a) = a*a"})
+
+			result, err := service.Transform(1, &test.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := parser.Format(result); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSymbolicService_Transform_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request models.SymbolicRequest
+	}{
+		{"parse error", models.SymbolicRequest{Expression: "x +"}},
+		{"unknown operation", models.SymbolicRequest{Expression: "x", Operation: "integrate"}},
+		{"missing variable", models.SymbolicRequest{Expression: "x", Operation: "diff"}},
+		{"invalid variable", models.SymbolicRequest{Expression: "x", Operation: "diff", Variable: "2x"}},
+		{"not differentiable", models.SymbolicRequest{Expression: "x % 2", Operation: "diff", Variable: "x"}},
+		{"division by zero", models.SymbolicRequest{Expression: "x/(x-x)"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create mock database: %v", err)
+			}
+			defer db.Close()
+
+			service := NewSymbolicService(&DatabaseService{db: db})
+			expectFunctions(mock, 1, nil)
+
+			if _, err := service.Transform(1, &test.request); err == nil {
+				t.Errorf("Expected error for %+v", test.request)
+			}
+		})
+	}
+}
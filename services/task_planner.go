@@ -0,0 +1,1063 @@
+package services
+
+import (
+	"calculator/evaluator"
+	"calculator/models"
+	"calculator/parser"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type taskPlan struct {
+	Tasks        []*models.Task
+	Bindings     map[string]string
+	Result       string
+	Folded       int
+	Depth        int
+	CriticalPath int64
+	Numerics     []models.NumericResult
+}
+
+const maxTrialChunks = 64
+
+type taskPlanner struct {
+	expressionID string
+	mode         string
+	scope        map[string]evaluator.Value
+	guard        string
+	threshold    int64
+	costs        map[parser.Node]foldCost
+	shared       map[string]string
+	assigned     []*parser.Statement
+	budget       *evaluator.Budget
+	plan         *taskPlan
+}
+
+func planTasks(expressionID string, program *parser.Program, variables map[string]float64, mode string) (*taskPlan, error) {
+	tp := &taskPlanner{
+		expressionID: expressionID,
+		mode:         mode,
+		scope:        make(map[string]evaluator.Value, len(variables)),
+		threshold:    foldThreshold(),
+		budget:       evaluator.NewBudget(planningBudget()),
+		plan:         &taskPlan{},
+	}
+	for name, value := range variables {
+		converted, err := evaluator.FromFloat(value, mode)
+		if err != nil {
+			return nil, err
+		}
+		tp.scope[name] = converted
+	}
+
+	for _, stmt := range parser.RebalanceProgram(program).Statements {
+		arg, err := tp.createTasks(stmt.Value)
+		if err != nil {
+			return nil, err
+		}
+		if stmt.IsAssignment() {
+			if evaluator.IsReserved(stmt.Name) {
+				return nil, fmt.Errorf("имя %q зарезервировано", stmt.Name)
+			}
+			if tp.plan.Bindings == nil {
+				tp.plan.Bindings = make(map[string]string)
+			}
+			tp.plan.Bindings[stmt.Name] = arg
+			tp.assigned = append(tp.assigned, stmt)
+		}
+		tp.plan.Result = arg
+	}
+	tp.plan.Depth, tp.plan.CriticalPath = criticalPath(tp.plan.Tasks)
+	return tp.plan, nil
+}
+
+func (p *taskPlan) Dispatched() int {
+	return countDispatched(p.Tasks)
+}
+
+func countDispatched(exprTasks []*models.Task) int {
+	count := 0
+	for _, task := range exprTasks {
+		if task.Operation == evaluator.Conditional || task.Status == "skipped" {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func (tp *taskPlanner) addTask(op, arg1, arg2 string) string {
+	return tp.addConditionalTask(op, arg1, arg2, "")
+}
+
+func (tp *taskPlanner) addConditionalTask(op, arg1, arg2, condition string) string {
+	return tp.add(&models.Task{Operation: op, Arg1: arg1, Arg2: arg2, Condition: condition})
+}
+
+func (tp *taskPlanner) addBodyTask(op, from, to, index, body string) string {
+	return tp.add(&models.Task{Operation: op, Arg1: from, Arg2: to, Index: index, Body: body})
+}
+
+func (tp *taskPlanner) add(task *models.Task) string {
+	if ref, ok := tp.shared[taskKey(task, tp.guard)]; ok {
+		return ref
+	}
+	if ref, ok := tp.shared[taskKey(task, "")]; ok {
+		return ref
+	}
+
+	now := time.Now()
+	task.ID = fmt.Sprintf("%s_task%d", tp.expressionID, len(tp.plan.Tasks)+1)
+	task.ExpressionID = tp.expressionID
+	task.OperationTime = getOperationTime(task.Operation)
+	task.Status = "pending"
+	if tp.guard != "" || task.Operation == evaluator.Conditional {
+		task.Status = "waiting"
+	}
+	task.Guard = tp.guard
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	tp.plan.Tasks = append(tp.plan.Tasks, task)
+
+	ref := fmt.Sprintf("$%s", task.ID)
+	if tp.shared == nil {
+		tp.shared = make(map[string]string)
+	}
+	tp.shared[taskKey(task, tp.guard)] = ref
+	return ref
+}
+
+func criticalPath(planned []*models.Task) (int, int64) {
+	depths := make(map[string]int, len(planned))
+	times := make(map[string]int64, len(planned))
+	var depth int
+	var total int64
+	for _, task := range planned {
+		var taskDepth int
+		var taskTime int64
+		for _, arg := range []string{task.Arg1, task.Arg2, task.Arg3, task.Condition, strings.TrimPrefix(task.Guard, "!")} {
+			id := strings.TrimPrefix(arg, "$")
+			if !isTaskRef(arg) {
+				continue
+			}
+			if depths[id] > taskDepth {
+				taskDepth = depths[id]
+			}
+			if times[id] > taskTime {
+				taskTime = times[id]
+			}
+		}
+		if task.Operation != evaluator.Conditional {
+			taskDepth++
+			taskTime += task.OperationTime
+		}
+		depths[task.ID], times[task.ID] = taskDepth, taskTime
+		if taskDepth > depth {
+			depth = taskDepth
+		}
+		if taskTime > total {
+			total = taskTime
+		}
+	}
+	return depth, total
+}
+
+func taskKey(task *models.Task, guard string) string {
+	arg1, arg2 := task.Arg1, task.Arg2
+	if evaluator.IsCommutative(task.Operation) && arg2 < arg1 {
+		arg1, arg2 = arg2, arg1
+	}
+	return strings.Join([]string{task.Operation, arg1, arg2, task.Arg3, task.Condition, task.Index, task.Body, guard}, "\x00")
+}
+
+func (tp *taskPlanner) createTasks(node parser.Node) (string, error) {
+	if arg, ok := tp.fold(node); ok {
+		return arg, nil
+	}
+	switch n := node.(type) {
+	case *parser.Number:
+		value, err := evaluator.Literal(n.Literal, tp.mode)
+		if err != nil {
+			return "", err
+		}
+		return value.String(), nil
+	case *parser.Ident:
+		if arg, ok := tp.plan.Bindings[n.Name]; ok {
+			return arg, nil
+		}
+		value, err := evaluator.ResolveIdent(n.Name, tp.scope, tp.mode)
+		if err != nil {
+			return "", err
+		}
+		return value.String(), nil
+	case *parser.UnaryOp:
+		arg, err := tp.createTasks(n.Operand)
+		if err != nil {
+			return "", err
+		}
+		if n.Op == "+" {
+			return arg, nil
+		}
+		op := evaluator.UnaryOperation(n.Op)
+		if !isTaskRef(arg) {
+			value, err := evaluator.ParseValue(arg)
+			if err != nil {
+				return "", err
+			}
+			folded, err := evaluator.ApplyValue(op, value)
+			if err != nil {
+				return "", err
+			}
+			return folded.String(), nil
+		}
+		return tp.addTask(op, arg, ""), nil
+	case *parser.BinaryOp:
+		if evaluator.IsLogical(n.Op) {
+			return tp.createConditionalTasks(logicalConditional(n))
+		}
+		leftArg, err := tp.createTasks(n.Left)
+		if err != nil {
+			return "", err
+		}
+		rightArg, err := tp.createTasks(n.Right)
+		if err != nil {
+			return "", err
+		}
+		if n.Op == evaluator.PlusMinus {
+			return tp.createConstructorTask(n.Op, leftArg, rightArg)
+		}
+		return tp.addTask(n.Op, leftArg, rightArg), nil
+	case *parser.Call:
+		if n.Name == evaluator.Conditional {
+			return tp.createConditionalTasks(n)
+		}
+		if _, ok := evaluator.RangeIndex(n); ok {
+			return tp.createRangeTasks(n)
+		}
+		if evaluator.IsNumeric(n.Name) {
+			return tp.createNumericTasks(n)
+		}
+		if evaluator.IsAggregate(n.Name) {
+			return tp.createAggregateTasks(n)
+		}
+		if n.Name == evaluator.UnitCall || n.Name == evaluator.ConvertCall {
+			return tp.createQuantityTask(n)
+		}
+		if n.Name == evaluator.FactorCall {
+			return tp.createFactorTasks(n)
+		}
+		if evaluator.IsNumberTheory(n.Name) {
+			return tp.createNumberTheoryTask(n)
+		}
+		if evaluator.IsStatistic(n.Name) {
+			return tp.createStatisticTasks(n)
+		}
+		if evaluator.IsMatrixOperation(n.Name) {
+			return tp.createMatrixTasks(n)
+		}
+		if n.Name == evaluator.IntervalCall || n.Name == evaluator.ComplexCall {
+			if len(n.Args) != 2 {
+				return "", fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", n.Name, len(n.Args))
+			}
+			lo, err := tp.createTasks(n.Args[0])
+			if err != nil {
+				return "", err
+			}
+			hi, err := tp.createTasks(n.Args[1])
+			if err != nil {
+				return "", err
+			}
+			return tp.createConstructorTask(n.Name, lo, hi)
+		}
+		if !evaluator.IsFunction(n.Name) {
+			return "", fmt.Errorf("неизвестная функция %q", n.Name)
+		}
+		if len(n.Args) != 1 {
+			return "", fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", n.Name, len(n.Args))
+		}
+		arg, err := tp.createTasks(n.Args[0])
+		if err != nil {
+			return "", err
+		}
+		return tp.addTask(n.Name, arg, ""), nil
+	}
+	return "", fmt.Errorf("неподдерживаемый узел выражения %T", node)
+}
+
+func (tp *taskPlanner) createConstructorTask(op, left, right string) (string, error) {
+	if isTaskRef(left) || isTaskRef(right) {
+		return tp.addTask(op, left, right), nil
+	}
+	args := make([]evaluator.Value, 2)
+	for i, arg := range []string{left, right} {
+		value, err := evaluator.ParseValue(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = value
+	}
+	interval, err := evaluator.ApplyValue(op, args...)
+	if err != nil {
+		return "", err
+	}
+	return interval.String(), nil
+}
+
+func (tp *taskPlanner) createQuantityTask(call *parser.Call) (string, error) {
+	if len(call.Args) != 2 {
+		return "", fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", call.Name, len(call.Args))
+	}
+	unit, ok := call.Args[1].(*parser.Unit)
+	if !ok {
+		return "", fmt.Errorf("функция %s ожидает единицу измерения вторым аргументом", call.Name)
+	}
+	target, err := evaluator.UnitValue(unit.Name)
+	if err != nil {
+		return "", err
+	}
+	arg, err := tp.createTasks(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	return tp.createConstructorTask(evaluator.QuantityOperation(call.Name), arg, target.String())
+}
+
+func (tp *taskPlanner) createNumberTheoryTask(call *parser.Call) (string, error) {
+	arity := evaluator.NumberTheoryArity(call.Name)
+	if len(call.Args) != arity {
+		return "", fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", call.Name, arity, len(call.Args))
+	}
+	args := make([]string, 3)
+	for i, arg := range call.Args {
+		var err error
+		if args[i], err = tp.createTasks(arg); err != nil {
+			return "", err
+		}
+	}
+	return tp.add(&models.Task{Operation: call.Name, Arg1: args[0], Arg2: args[1], Arg3: args[2]}), nil
+}
+
+func (tp *taskPlanner) createFactorTasks(call *parser.Call) (string, error) {
+	if len(call.Args) != 1 {
+		return "", fmt.Errorf("функция %s ожидает 1 аргумент(а), получено %d", call.Name, len(call.Args))
+	}
+	arg, err := tp.createTasks(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	if isTaskRef(arg) {
+		return tp.addTask(call.Name, arg, ""), nil
+	}
+	value, err := evaluator.ParseValue(arg)
+	if err != nil {
+		return "", err
+	}
+	limit, err := evaluator.TrialLimit(value)
+	if err != nil {
+		return "", err
+	}
+	size := trialChunkSize()
+	if limit <= size {
+		return tp.addTask(call.Name, arg, ""), nil
+	}
+
+	var chunks []string
+	for lo := int64(2); lo <= limit && len(chunks) < maxTrialChunks; lo += size {
+		hi := limit
+		if limit-lo >= size {
+			hi = lo + size - 1
+		}
+		chunks = append(chunks, tp.add(&models.Task{Operation: evaluator.TrialDivision, Arg1: arg, Arg2: evaluator.Int(lo).String(), Arg3: evaluator.Int(hi).String()}))
+	}
+	found, err := evaluator.Reduce(chunks, func(left, right string) (string, error) {
+		return tp.addTask("*", left, right), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return tp.addTask(call.Name, arg, found), nil
+}
+
+func (tp *taskPlanner) createMatrixTasks(call *parser.Call) (string, error) {
+	if call.Name == evaluator.VectorCall || call.Name == evaluator.MatrixCall {
+		return tp.createStackTasks(call)
+	}
+	arity := evaluator.MatrixArity(call.Name)
+	if len(call.Args) != arity {
+		return "", fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", call.Name, arity, len(call.Args))
+	}
+	args := make([]string, 2)
+	for i, arg := range call.Args {
+		var err error
+		if args[i], err = tp.createTasks(arg); err != nil {
+			return "", err
+		}
+	}
+	if call.Name == evaluator.MatMul {
+		return tp.createMatMulTasks(args[0], args[1])
+	}
+	return tp.addTask(call.Name, args[0], args[1]), nil
+}
+
+func (tp *taskPlanner) createStackTasks(call *parser.Call) (string, error) {
+	if len(call.Args) == 0 {
+		return "", fmt.Errorf("функция %s ожидает хотя бы один аргумент", call.Name)
+	}
+	var parts []string
+	var constants []evaluator.Value
+	flush := func() error {
+		if len(constants) == 0 {
+			return nil
+		}
+		value, err := evaluator.ApplyValue(call.Name, constants...)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, value.String())
+		constants = nil
+		return nil
+	}
+	for _, node := range call.Args {
+		arg, err := tp.createTasks(node)
+		if err != nil {
+			return "", err
+		}
+		if !isTaskRef(arg) {
+			value, err := evaluator.ParseValue(arg)
+			if err != nil {
+				return "", err
+			}
+			constants = append(constants, value)
+			continue
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+		parts = append(parts, arg)
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	if len(parts) == 1 {
+		if !isTaskRef(parts[0]) {
+			return parts[0], nil
+		}
+		return tp.addTask(call.Name, parts[0], ""), nil
+	}
+	return evaluator.Reduce(parts, func(left, right string) (string, error) {
+		return tp.addTask(call.Name, left, right), nil
+	})
+}
+
+func (tp *taskPlanner) createMatMulTasks(left, right string) (string, error) {
+	if isTaskRef(left) || isTaskRef(right) {
+		return tp.addTask(evaluator.MatMul, left, right), nil
+	}
+	a, err := evaluator.ParseValue(left)
+	if err != nil {
+		return "", err
+	}
+	b, err := evaluator.ParseValue(right)
+	if err != nil {
+		return "", err
+	}
+	m, ok := a.(evaluator.Matrix)
+	size := matmulBlockRows()
+	if !ok || m.Vector || m.Rows <= size {
+		return tp.addTask(evaluator.MatMul, left, right), nil
+	}
+
+	combine := evaluator.MatrixCall
+	if v, ok := b.(evaluator.Matrix); ok && v.Vector {
+		combine = evaluator.VectorCall
+	}
+	var blocks []string
+	for from := 0; from < m.Rows; from += size {
+		to := from + size
+		if to > m.Rows {
+			to = m.Rows
+		}
+		blocks = append(blocks, tp.addTask(evaluator.MatMul, m.RowBlock(from, to).String(), right))
+	}
+	return evaluator.Reduce(blocks, func(left, right string) (string, error) {
+		return tp.addTask(combine, left, right), nil
+	})
+}
+
+func (tp *taskPlanner) createStatisticTasks(call *parser.Call) (string, error) {
+	arity := evaluator.StatisticArity(call.Name)
+	if len(call.Args) != arity {
+		return "", fmt.Errorf("функция %s ожидает %d аргумент(а), получено %d", call.Name, arity, len(call.Args))
+	}
+	args := make([]string, 2)
+	for i, arg := range call.Args {
+		var err error
+		if args[i], err = tp.createTasks(arg); err != nil {
+			return "", err
+		}
+	}
+	if !evaluator.IsMomentStatistic(call.Name) || isTaskRef(args[0]) || isTaskRef(args[1]) {
+		return tp.addTask(call.Name, args[0], args[1]), nil
+	}
+
+	lists := make([]evaluator.Matrix, 0, 2)
+	for _, arg := range args[:arity] {
+		value, err := evaluator.ParseValue(arg)
+		if err != nil {
+			return "", err
+		}
+		list, ok := value.(evaluator.Matrix)
+		if !ok {
+			return tp.addTask(call.Name, args[0], args[1]), nil
+		}
+		lists = append(lists, list)
+	}
+	size := statisticChunkSize()
+	n := len(lists[0].Data)
+	if n <= size || (len(lists) == 2 && len(lists[1].Data) != n) {
+		return tp.addTask(call.Name, args[0], args[1]), nil
+	}
+
+	var chunks []string
+	for lo := 0; lo < n; lo += size {
+		hi := lo + size
+		if hi > n {
+			hi = n
+		}
+		parts := make([]string, 2)
+		for i, list := range lists {
+			parts[i] = evaluator.NewVector(list.Data[lo:hi]).String()
+		}
+		chunks = append(chunks, tp.addTask(evaluator.MomentsCall, parts[0], parts[1]))
+	}
+	total, err := evaluator.Reduce(chunks, func(left, right string) (string, error) {
+		return tp.addTask("+", left, right), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return tp.addTask(call.Name, total, ""), nil
+}
+
+func (tp *taskPlanner) createAggregateTasks(call *parser.Call) (string, error) {
+	op, err := evaluator.AggregateOperation(call.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(call.Args) == 0 {
+		return "", fmt.Errorf("функция %s ожидает хотя бы один аргумент", call.Name)
+	}
+
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		if args[i], err = tp.createTasks(arg); err != nil {
+			return "", err
+		}
+	}
+
+	total, err := evaluator.Reduce(args, func(left, right string) (string, error) {
+		return tp.addTask(op, left, right), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if call.Name == "avg" {
+		count, err := evaluator.Literal(strconv.Itoa(len(args)), tp.mode)
+		if err != nil {
+			return "", err
+		}
+		return tp.addTask("/", total, count.String()), nil
+	}
+	return total, nil
+}
+
+func (tp *taskPlanner) createRangeTasks(call *parser.Call) (string, error) {
+	scope, err := tp.localScope(call)
+	if err != nil {
+		return "", err
+	}
+	from, to, err := evaluator.RangeBounds(call, scope, tp.mode, tp.budget)
+	if err != nil {
+		return "", err
+	}
+	if from > to {
+		empty, err := evaluator.ReduceRange(call.Name, "", from, to, nil, nil, tp.mode)
+		if err != nil {
+			return "", err
+		}
+		return empty.String(), nil
+	}
+
+	index, _ := evaluator.RangeIndex(call)
+	delete(scope, index)
+	body, err := evaluator.Substitute(call.Args[3], scope)
+	if err != nil {
+		return "", err
+	}
+	if err := evaluator.CheckBody(body, index, tp.mode); err != nil {
+		return "", err
+	}
+	op, err := evaluator.AggregateOperation(call.Name)
+	if err != nil {
+		return "", err
+	}
+
+	size := rangeChunkSize()
+	var chunks []string
+	for lo := from; ; lo += size {
+		hi := to
+		if to-lo >= size {
+			hi = lo + size - 1
+		}
+		first := evaluator.IndexValue(lo, tp.mode).String()
+		last := evaluator.IndexValue(hi, tp.mode).String()
+		chunks = append(chunks, tp.addBodyTask(call.Name, first, last, index, parser.Format(body)))
+		if hi == to {
+			break
+		}
+	}
+	return evaluator.Reduce(chunks, func(left, right string) (string, error) {
+		return tp.addTask(op, left, right), nil
+	})
+}
+
+func (tp *taskPlanner) createNumericTasks(call *parser.Call) (string, error) {
+	nc, err := evaluator.ParseNumeric(call)
+	if err != nil {
+		return "", err
+	}
+	scope, err := tp.localScope(call)
+	if err != nil {
+		return "", err
+	}
+	from, to, err := nc.Bounds(scope, tp.mode, tp.budget)
+	if err != nil {
+		return "", err
+	}
+	delete(scope, nc.Variable)
+	node, err := evaluator.Substitute(nc.Body, scope)
+	if err != nil {
+		return "", err
+	}
+	if err := evaluator.CheckBody(node, nc.Variable, tp.mode); err != nil {
+		return "", err
+	}
+	body := parser.Format(node)
+
+	var refs []string
+	if nc.Name == evaluator.Solve {
+		refs = append(refs, tp.addBodyTask(nc.Name, evaluator.Float(from).String(), evaluator.Float(to).String(), nc.Variable, body))
+	} else {
+		points := evaluator.Segments(from, to, evaluator.IntegrationSegments)
+		for i := 0; i < evaluator.IntegrationSegments; i++ {
+			refs = append(refs, tp.addBodyTask(nc.Name, evaluator.Float(points[i]).String(), evaluator.Float(points[i+1]).String(), nc.Variable, body))
+		}
+	}
+
+	ids := make([]string, len(refs))
+	for i, ref := range refs {
+		ids[i] = strings.TrimPrefix(ref, "$")
+	}
+	tp.plan.Numerics = append(tp.plan.Numerics, models.NumericResult{Function: nc.Name, Call: parser.Format(call), Tasks: ids})
+
+	return evaluator.Reduce(refs, func(left, right string) (string, error) {
+		return tp.addTask("+", left, right), nil
+	})
+}
+
+func (tp *taskPlanner) localScope(call *parser.Call) (map[string]evaluator.Value, error) {
+	scope := make(map[string]evaluator.Value, len(tp.scope)+len(tp.assigned))
+	for name, value := range tp.scope {
+		scope[name] = value
+	}
+
+	needed := make(map[string]bool)
+	mark := func(node parser.Node) {
+		parser.Inspect(node, func(node parser.Node) bool {
+			if ident, ok := node.(*parser.Ident); ok {
+				needed[ident.Name] = true
+			}
+			return true
+		})
+	}
+	mark(call)
+	used := make([]bool, len(tp.assigned))
+	for i := len(tp.assigned) - 1; i >= 0; i-- {
+		if stmt := tp.assigned[i]; needed[stmt.Name] {
+			used[i] = true
+			mark(stmt.Value)
+		}
+	}
+
+	for i, stmt := range tp.assigned {
+		if !used[i] {
+			continue
+		}
+		value, err := evaluator.EvaluateWithin(stmt.Value, scope, tp.mode, tp.budget)
+		if err != nil {
+			return nil, err
+		}
+		scope[stmt.Name] = value
+	}
+	return scope, nil
+}
+
+func planningBudget() int64 {
+	size := getEnvInt64("PLANNING_BUDGET", 1000000)
+	if size <= 0 {
+		return 1000000
+	}
+	return size
+}
+
+func rangeChunkSize() int64 {
+	size := getEnvInt64("RANGE_CHUNK_SIZE", 10000)
+	if size <= 0 {
+		return 10000
+	}
+	return size
+}
+
+func trialChunkSize() int64 {
+	size := getEnvInt64("TRIAL_DIVISION_CHUNK", 100000)
+	if size <= 0 {
+		return 100000
+	}
+	return size
+}
+
+func matmulBlockRows() int {
+	size := getEnvInt64("MATMUL_BLOCK_ROWS", 64)
+	if size <= 0 {
+		return 64
+	}
+	return int(size)
+}
+
+func statisticChunkSize() int {
+	size := getEnvInt64("STATS_CHUNK_SIZE", 1000)
+	if size <= 0 {
+		return 1000
+	}
+	return int(size)
+}
+
+func (tp *taskPlanner) createConditionalTasks(call *parser.Call) (string, error) {
+	if len(call.Args) != 3 {
+		return "", fmt.Errorf("функция if ожидает 3 аргумента, получено %d", len(call.Args))
+	}
+	cond, err := tp.createTasks(call.Args[0])
+	if err != nil {
+		return "", err
+	}
+	if !isTaskRef(cond) {
+		value, err := evaluator.ParseValue(cond)
+		if err != nil {
+			return "", err
+		}
+		if evaluator.Truthy(value) {
+			return tp.createTasks(call.Args[1])
+		}
+		return tp.createTasks(call.Args[2])
+	}
+
+	guard := tp.guard
+	tp.guard = cond
+	thenArg, err := tp.createTasks(call.Args[1])
+	if err != nil {
+		return "", err
+	}
+	tp.guard = "!" + cond
+	elseArg, err := tp.createTasks(call.Args[2])
+	if err != nil {
+		return "", err
+	}
+	tp.guard = guard
+
+	return tp.addConditionalTask(evaluator.Conditional, thenArg, elseArg, cond), nil
+}
+
+func logicalConditional(n *parser.BinaryOp) *parser.Call {
+	right := n.Right
+	if !isBoolean(right) {
+		right = &parser.BinaryOp{Op: "!=", Left: right, Right: &parser.Number{Literal: "0", Integer: true, At: n.At}, At: n.At}
+	}
+	if n.Op == "&&" {
+		return &parser.Call{Name: evaluator.Conditional, Args: []parser.Node{n.Left, right, &parser.Number{Literal: "0", Integer: true, At: n.At}}, At: n.At}
+	}
+	return &parser.Call{Name: evaluator.Conditional, Args: []parser.Node{n.Left, &parser.Number{Value: 1, Literal: "1", Integer: true, At: n.At}, right}, At: n.At}
+}
+
+func isBoolean(node parser.Node) bool {
+	switch n := node.(type) {
+	case *parser.BinaryOp:
+		return evaluator.IsComparison(n.Op) || evaluator.IsLogical(n.Op)
+	case *parser.UnaryOp:
+		return n.Op == "!"
+	}
+	return false
+}
+
+func isTaskRef(arg string) bool {
+	return strings.HasPrefix(arg, "$")
+}
+
+func resolveTaskArg(arg string, results map[string]evaluator.Value) (evaluator.Value, error) {
+	if isTaskRef(arg) {
+		value, ok := results[strings.TrimPrefix(arg, "$")]
+		if !ok {
+			return nil, fmt.Errorf("no result for task %s", strings.TrimPrefix(arg, "$"))
+		}
+		return value, nil
+	}
+	return evaluator.ParseValue(arg)
+}
+
+func taskValue(task *models.Task) (evaluator.Value, error) {
+	if task.Value != nil {
+		return evaluator.ParseValue(*task.Value)
+	}
+	if task.Result != nil {
+		return evaluator.Float(*task.Result), nil
+	}
+	return nil, fmt.Errorf("no result for task %s", task.ID)
+}
+
+func advanceTasks(exprTasks []*models.Task) []*models.Task {
+	byID := make(map[string]*models.Task, len(exprTasks))
+	for _, task := range exprTasks {
+		byID[task.ID] = task
+	}
+	resolve := func(arg string) (evaluator.Value, bool) {
+		if !isTaskRef(arg) {
+			value, err := evaluator.ParseValue(arg)
+			return value, err == nil
+		}
+		task, ok := byID[strings.TrimPrefix(arg, "$")]
+		if !ok || task.Status != "done" {
+			return nil, false
+		}
+		value, err := taskValue(task)
+		return value, err == nil
+	}
+
+	var changed []*models.Task
+	if failedTask(exprTasks) != nil {
+		for _, task := range exprTasks {
+			if task.Status == "waiting" || task.Status == "pending" {
+				task.Status = "skipped"
+				changed = append(changed, task)
+			}
+		}
+		return changed
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for _, task := range exprTasks {
+			if task.Status != "waiting" {
+				continue
+			}
+
+			if task.Guard != "" {
+				ref := strings.TrimPrefix(task.Guard, "!")
+				if guard, ok := byID[strings.TrimPrefix(ref, "$")]; ok && guard.Status == "skipped" {
+					task.Status = "skipped"
+					changed, progress = append(changed, task), true
+					continue
+				}
+				cond, ok := resolve(ref)
+				if !ok {
+					continue
+				}
+				if evaluator.Truthy(cond) == strings.HasPrefix(task.Guard, "!") {
+					task.Status = "skipped"
+					changed, progress = append(changed, task), true
+					continue
+				}
+			}
+
+			if task.Operation != evaluator.Conditional {
+				task.Status = "pending"
+				changed, progress = append(changed, task), true
+				continue
+			}
+
+			cond, ok := resolve(task.Condition)
+			if !ok {
+				continue
+			}
+			branch := task.Arg2
+			if evaluator.Truthy(cond) {
+				branch = task.Arg1
+			}
+			value, ok := resolve(branch)
+			if !ok {
+				continue
+			}
+			result := value.Float()
+			task.Result = &result
+			if _, float := value.(evaluator.Float); !float {
+				encoded := value.String()
+				task.Value = &encoded
+			}
+			task.Status = "done"
+			changed, progress = append(changed, task), true
+		}
+	}
+	return changed
+}
+
+func failedTask(exprTasks []*models.Task) *models.Task {
+	for _, task := range exprTasks {
+		if task.Status == "failed" {
+			return task
+		}
+	}
+	return nil
+}
+
+func completeExpression(expr *models.Expression, exprTasks []*models.Task) (bool, error) {
+	if failed := failedTask(exprTasks); failed != nil {
+		expr.Status = models.StatusFailed
+		expr.Error = failed.Error
+		expr.Dispatched = countDispatched(exprTasks)
+		return true, nil
+	}
+
+	results := make(map[string]evaluator.Value, len(exprTasks))
+	var lastResult evaluator.Value = evaluator.Float(0)
+	for _, task := range exprTasks {
+		if task.Status == "skipped" {
+			continue
+		}
+		if task.Status != "done" {
+			return false, nil
+		}
+		value, err := taskValue(task)
+		if err != nil {
+			continue
+		}
+		results[task.ID] = value
+		lastResult = value
+	}
+
+	result := lastResult
+	if expr.ResultRef != "" {
+		value, err := resolveTaskArg(expr.ResultRef, results)
+		if err != nil {
+			return false, err
+		}
+		result = value
+	}
+
+	var assignments map[string]models.Value
+	for name, arg := range expr.Bindings {
+		value, err := resolveTaskArg(arg, results)
+		if err != nil {
+			return false, err
+		}
+		if assignments == nil {
+			assignments = make(map[string]models.Value, len(expr.Bindings))
+		}
+		assignments[name] = resultValue(value)
+	}
+
+	if err := completeNumerics(expr.Numerics, exprTasks, results); err != nil {
+		return false, err
+	}
+
+	expr.Status = models.StatusDone
+	expr.Value = resultValue(result)
+	expr.Assignments = assignments
+	expr.Dispatched = countDispatched(exprTasks)
+	return true, nil
+}
+
+func resultValue(value evaluator.Value) models.Value {
+	approximation := value.Float()
+	result := models.Value{Result: &approximation}
+	switch exact := value.(type) {
+	case evaluator.Rat:
+		fraction := exact.RatString()
+		decimal := evaluator.DecimalString(exact.Rat)
+		result.Exact = &fraction
+		result.Decimal = &decimal
+	case evaluator.Int:
+		integer := exact.String()
+		result.Exact = &integer
+	case evaluator.Interval:
+		result.Lower = &exact.Lo
+		result.Upper = &exact.Hi
+	case evaluator.Complex:
+		imag := exact.Imag()
+		result.Imag = &imag
+	case evaluator.Quantity:
+		unit := exact.Unit.Name
+		result.Unit = &unit
+	case evaluator.BigInt:
+		integer := exact.String()
+		result.Exact = &integer
+		if exact.Overflows() {
+			result.Result = nil
+		}
+	case evaluator.Factors:
+		factors := string(exact)
+		result.Exact = &factors
+		if exact.Overflows() {
+			result.Result = nil
+		}
+	case evaluator.Matrix:
+		result.Result = nil
+		if exact.Vector {
+			result.Vector = exact.Data
+		} else {
+			result.Matrix = exact.Grid()
+		}
+	}
+	return result
+}
+
+func completeNumerics(numerics []models.NumericResult, exprTasks []*models.Task, results map[string]evaluator.Value) error {
+	byID := make(map[string]*models.Task, len(exprTasks))
+	for _, task := range exprTasks {
+		byID[task.ID] = task
+	}
+
+	for i := range numerics {
+		numeric := &numerics[i]
+		values := make([]float64, 0, len(numeric.Tasks))
+		var estimate float64
+		var iterations int
+		for _, id := range numeric.Tasks {
+			value, ok := results[id]
+			if !ok {
+				break
+			}
+			values = append(values, value.Float())
+			if task := byID[id]; task != nil {
+				if task.ErrorEstimate != nil {
+					estimate += *task.ErrorEstimate
+				}
+				iterations += task.Iterations
+			}
+		}
+		if len(values) == 0 || len(values) != len(numeric.Tasks) {
+			continue
+		}
+
+		total, err := evaluator.Reduce(values, func(left, right float64) (float64, error) {
+			return evaluator.Apply("+", left, right)
+		})
+		if err != nil {
+			return err
+		}
+		numeric.Result = &total
+		numeric.ErrorEstimate = &estimate
+		numeric.Iterations = iterations
+	}
+	return nil
+}
@@ -0,0 +1,1543 @@
+package services
+
+import (
+	"calculator/evaluator"
+	"calculator/models"
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("FOLD_THRESHOLD_MS", "0")
+	os.Exit(m.Run())
+}
+
+func compile(expression string, variables map[string]float64) (*taskPlan, error) {
+	return compileInMode(expression, variables, "")
+}
+
+func compileInMode(expression string, variables map[string]float64, mode string) (*taskPlan, error) {
+	program, err := parser.ParseProgram(expression)
+	if err != nil {
+		return nil, err
+	}
+	return planTasks("expr", program, variables, mode)
+}
+
+func plan(expression string) ([]*models.Task, error) {
+	compiled, err := compile(expression, nil)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Tasks, nil
+}
+
+func runTasks(t *testing.T, planned []*models.Task) map[string]evaluator.Value {
+	t.Helper()
+	results := map[string]evaluator.Value{}
+	for progress := true; progress; {
+		progress = false
+		for _, task := range advanceTasks(planned) {
+			if task.Status == "done" {
+				results[task.ID], _ = taskValue(task)
+			}
+		}
+		for _, task := range planned {
+			if task.Status != "pending" {
+				continue
+			}
+			refs := []string{task.Arg1}
+			if task.Arg2 != "" {
+				refs = append(refs, task.Arg2)
+			}
+			if task.Arg3 != "" {
+				refs = append(refs, task.Arg3)
+			}
+			var args []evaluator.Value
+			for _, arg := range refs {
+				if value, err := resolveTaskArg(arg, results); err == nil {
+					args = append(args, value)
+				}
+			}
+			if len(args) != len(refs) {
+				continue
+			}
+
+			result, err := executeTask(task, args)
+			if err != nil {
+				t.Fatalf("Task %s failed: %v", task.ID, err)
+			}
+			approximation := result.Float()
+			task.Result = &approximation
+			if _, float := result.(evaluator.Float); !float {
+				value := result.String()
+				task.Value = &value
+			}
+			task.Status = "done"
+			results[task.ID] = result
+			progress = true
+		}
+	}
+
+	for _, task := range planned {
+		if task.Status != "done" && task.Status != "skipped" {
+			t.Fatalf("Task %s is stuck in status %q", task.ID, task.Status)
+		}
+	}
+	return results
+}
+
+func executeTask(task *models.Task, args []evaluator.Value) (evaluator.Value, error) {
+	if task.Body == "" {
+		return evaluator.ApplyValue(task.Operation, args...)
+	}
+	body, err := parser.Parse(task.Body)
+	if err != nil {
+		return nil, err
+	}
+	if evaluator.IsNumeric(task.Operation) {
+		estimate, err := evaluator.EstimateNumeric(task.Operation, task.Index, args[0].Float(), args[1].Float(), body, nil)
+		if err != nil {
+			return nil, err
+		}
+		return evaluator.Float(estimate.Value), nil
+	}
+	from, err := evaluator.RangeBound(args[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := evaluator.RangeBound(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.ReduceRange(task.Operation, task.Index, from, to, body, nil, evaluator.ModeOf(args[0]))
+}
+
+func finishTasks(t *testing.T, planned []*models.Task) {
+	t.Helper()
+	runTasks(t, planned)
+}
+
+func executePlan(t *testing.T, compiled *taskPlan) float64 {
+	t.Helper()
+	return executePlanValue(t, compiled).Float()
+}
+
+func executePlanValue(t *testing.T, compiled *taskPlan) evaluator.Value {
+	t.Helper()
+	result, err := resolveTaskArg(compiled.Result, runTasks(t, compiled.Tasks))
+	if err != nil {
+		t.Fatalf("Cannot resolve result %q: %v", compiled.Result, err)
+	}
+	return result
+}
+
+func TestPlanTasks_RespectsParentheses(t *testing.T) {
+	planned, err := plan("(2+3)*4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(planned) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(planned))
+	}
+
+	add, mul := planned[0], planned[1]
+	if add.Operation != "+" || add.Arg1 != "2" || add.Arg2 != "3" {
+		t.Errorf("Expected first task 2+3, got %s %s %s", add.Arg1, add.Operation, add.Arg2)
+	}
+	if mul.Operation != "*" || mul.Arg1 != "$"+add.ID || mul.Arg2 != "4" {
+		t.Errorf("Expected second task $%s*4, got %s %s %s", add.ID, mul.Arg1, mul.Operation, mul.Arg2)
+	}
+	if mul.ID != "expr_task2" {
+		t.Errorf("Expected root task id expr_task2, got %s", mul.ID)
+	}
+}
+
+func TestPlanTasks_InvalidExpression(t *testing.T) {
+	if _, err := plan("2+*3"); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+}
+
+func TestPlanTasks_UnaryMinus(t *testing.T) {
+	planned, err := plan("2*-3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(planned) != 1 || planned[0].Arg2 != "-3" {
+		t.Fatalf("Expected negative literal to be folded into a single task, got %+v", planned)
+	}
+
+	planned, err = plan("-(4+1)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(planned) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(planned))
+	}
+	neg := planned[1]
+	if neg.Operation != "neg" || neg.Arg1 != "$"+planned[0].ID || neg.Arg2 != "" {
+		t.Errorf("Expected negate task over $%s, got %+v", planned[0].ID, neg)
+	}
+}
+
+func TestPlanTasks_Function(t *testing.T) {
+	planned, err := plan("sqrt(9+16)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(planned) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(planned))
+	}
+	sqrt := planned[1]
+	if sqrt.Operation != "sqrt" || sqrt.Arg1 != "$"+planned[0].ID || sqrt.Arg2 != "" {
+		t.Errorf("Expected unary sqrt task over $%s, got %+v", planned[0].ID, sqrt)
+	}
+
+	if _, err := plan("foo(1)"); err == nil {
+		t.Error("Expected error for unknown function")
+	}
+	if _, err := plan("sqrt(1, 2)"); err == nil {
+		t.Error("Expected error for wrong argument count")
+	}
+}
+
+func TestPlanTasks_AggregateIsBalanced(t *testing.T) {
+	planned, err := plan("sum(1, 2, 3, 4, 5, 6, 7, 8)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(planned) != 7 {
+		t.Fatalf("Expected 7 addition tasks, got %d", len(planned))
+	}
+
+	depth := map[string]int{}
+	for _, task := range planned {
+		if task.Operation != "+" {
+			t.Errorf("Expected '+' task, got %s", task.Operation)
+		}
+		level := 0
+		for _, arg := range []string{task.Arg1, task.Arg2} {
+			if d, ok := depth[arg]; ok && d > level {
+				level = d
+			}
+		}
+		depth["$"+task.ID] = level + 1
+	}
+	if root := depth["$"+planned[len(planned)-1].ID]; root != 3 {
+		t.Errorf("Expected reduction depth 3 for 8 arguments, got %d", root)
+	}
+}
+
+func TestPlanTasks_PlanningBudget(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"x = sum(i, 1, 9999999, i); sum(j, 1, 3, j*x)", false},
+		{"sum(j, 1, sum(i, 1, 3000000, 1 + i*0)//1000000, j)", false},
+		{"n = sum(i, 1, 2000000, 1); integrate(x, 0, n)", false},
+		{"x = sum(i, 1, 100, i); sum(j, 1, 3, j*x)", true},
+		{"sum(j, 1, sum(i, 1, 3, i), j)", true},
+	}
+
+	for _, tt := range tests {
+		started := time.Now()
+		_, err := compile(tt.expr, nil)
+		if tt.valid && err != nil {
+			t.Errorf("compile(%s) failed: %v", tt.expr, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Expected %s to exceed the planning budget", tt.expr)
+		}
+		if elapsed := time.Since(started); elapsed > 2*time.Second {
+			t.Errorf("Expected %s to be planned or rejected quickly, took %v", tt.expr, elapsed)
+		}
+	}
+
+	t.Setenv("PLANNING_BUDGET", "5")
+	if _, err := compile("sum(j, 1, sum(i, 1, 3, i), j)", nil); err == nil {
+		t.Error("Expected the bound to exceed a budget of 5 steps")
+	}
+}
+
+func TestPlanTasks_RangeChunks(t *testing.T) {
+	t.Setenv("RANGE_CHUNK_SIZE", "10000")
+
+	compiled, err := compile("sum(i, 1, 35000, i^2)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var chunks []string
+	for _, task := range compiled.Tasks {
+		if task.Body == "" {
+			if task.Operation != "+" {
+				t.Errorf("Expected '+' reduction task, got %s", task.Operation)
+			}
+			continue
+		}
+		if task.Operation != "sum" || task.Index != "i" || task.Body != "i^2" {
+			t.Errorf("Unexpected chunk task %+v", task)
+		}
+		chunks = append(chunks, task.Arg1+".."+task.Arg2)
+	}
+	expected := "1..10000 10001..20000 20001..30000 30001..35000"
+	if got := strings.Join(chunks, " "); got != expected {
+		t.Errorf("Expected chunks %s, got %s", expected, got)
+	}
+	if len(compiled.Tasks) != 7 || compiled.Depth != 3 {
+		t.Errorf("Expected 4 chunks reduced by 3 additions at depth 3, got %d tasks at depth %d", len(compiled.Tasks), compiled.Depth)
+	}
+	if result := executePlan(t, compiled); result != 14292279172500 {
+		t.Errorf("Expected 14292279172500, got %v", result)
+	}
+}
+
+func TestPlanTasks_Range(t *testing.T) {
+	t.Setenv("RANGE_CHUNK_SIZE", "4")
+
+	tests := []struct {
+		expr      string
+		variables map[string]float64
+		mode      string
+		body      string
+		expected  string
+	}{
+		{"prod(k, 1, 10, k)", nil, "", "k", "3628800"},
+		{"sum(i, 1, 10, i*x)", map[string]float64{"x": 0.5}, "", "i*0.5", "27.5"},
+		{"n = 2+3; a = n*2; sum(i, 1, a, i*n)", nil, "", "i*5", "275"},
+		{"sum(i, 1, 10, 1/i) - 1", nil, evaluator.ModeExact, "1/i", "rat:4861/2520"},
+		{"x = 2; sum(i, 1, 5, sum(j, 1, i, j*x))", nil, "", "sum(j, 1, i, j*2)", "70"},
+		{"sum(i, 1, 10, i) + sum(i, 1, 10, i)", nil, "", "i", "110"},
+		{"sum(i, 5, 1, i) + prod(i, 5, 1, i)", nil, "", "", "1"},
+		{"sum(k, 1, 10, 1)", nil, "", "1", "10"},
+		{"sum(i, 1, 3, 2)", nil, "", "2", "6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compileInMode(tt.expr, tt.variables, tt.mode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, task := range compiled.Tasks {
+				if task.Body != "" && task.Body != tt.body {
+					t.Errorf("Expected body %s, got %s", tt.body, task.Body)
+				}
+			}
+			results := runTasks(t, compiled.Tasks)
+			result, err := resolveTaskArg(compiled.Result, results)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.String())
+			}
+		})
+	}
+}
+
+func TestPlanTasks_RangeSharesChunks(t *testing.T) {
+	planned, err := plan("sum(i, 1, 10, i) * sum(i, 1, 10, i) + sum(j, 1, 10, j^2)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chunks := 0
+	for _, task := range planned {
+		if task.Body != "" {
+			chunks++
+		}
+	}
+	if chunks != 2 {
+		t.Errorf("Expected identical ranges to share a chunk, got %d chunks", chunks)
+	}
+}
+
+func TestPlanTasks_RangeErrors(t *testing.T) {
+	tests := []string{
+		"sum(i, 1.5, 10, i)",
+		"sum(i, 1, 100000000, i)",
+		"sum(pi, 1, 10, pi)",
+		"x = sqrt(2); sum(i, 1, x, i)",
+	}
+
+	for _, expr := range tests {
+		if _, err := plan(expr); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+}
+
+func TestPlanTasks_Numeric(t *testing.T) {
+	tests := []struct {
+		expr      string
+		variables map[string]float64
+		body      string
+		segments  int
+		expected  float64
+	}{
+		{"integrate(x^2, 0, 1)", nil, "x^2", evaluator.IntegrationSegments, 1.0 / 3},
+		{"integrate(x*a, 0, 2)", map[string]float64{"a": 3, "x": 100}, "x*3.0", evaluator.IntegrationSegments, 6},
+		{"b = 1+1; integrate(t^b, t, 0, b)", nil, "t^2", evaluator.IntegrationSegments, 8.0 / 3},
+		{"solve(x^2 - 2, x, 0, 2) * 2", nil, "x^2 - 2", 1, 2 * math.Sqrt2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, tt.variables)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			segments := 0
+			for _, task := range compiled.Tasks {
+				if task.Body == "" {
+					continue
+				}
+				segments++
+				if task.Body != tt.body {
+					t.Errorf("Expected body %s, got %s", tt.body, task.Body)
+				}
+			}
+			if segments != tt.segments || len(compiled.Numerics) != 1 || len(compiled.Numerics[0].Tasks) != tt.segments {
+				t.Errorf("Expected %d numeric tasks, got %d and numerics %+v", tt.segments, segments, compiled.Numerics)
+			}
+			if result := executePlan(t, compiled); math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPlanTasks_NumericErrors(t *testing.T) {
+	tests := []string{
+		"integrate(x, 0)",
+		"integrate(x, 0, 1/0)",
+		"integrate(pi, pi, 0, 1)",
+	}
+
+	for _, expr := range tests {
+		if _, err := plan(expr); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+	if _, err := compileInMode("integrate(x, 0, 1)", nil, evaluator.ModeExact); err == nil {
+		t.Error("Expected error for integrate in exact mode")
+	}
+}
+
+func TestCompleteExpression_Numerics(t *testing.T) {
+	compiled, err := compile("integrate(x^3, 0, 2)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+	for _, task := range compiled.Tasks {
+		if task.Body != "" {
+			estimate := 1e-12
+			task.ErrorEstimate = &estimate
+			task.Iterations = 5
+		}
+	}
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result, Numerics: compiled.Numerics}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	numeric := expr.Numerics[0]
+	if numeric.Function != evaluator.Integrate || numeric.Call != "integrate(x^3, 0, 2)" || numeric.Result == nil || math.Abs(*numeric.Result-4) > 1e-9 {
+		t.Errorf("Unexpected numeric result %+v", numeric)
+	}
+	if numeric.ErrorEstimate == nil || math.Abs(*numeric.ErrorEstimate-16e-12) > 1e-18 || numeric.Iterations != 5*evaluator.IntegrationSegments {
+		t.Errorf("Expected summed error estimate and iterations, got %+v", numeric)
+	}
+}
+
+func TestPlanTasks_Interval(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tasks  int
+		result string
+	}{
+		{"3.2±0.1", 0, "iv:3.1,3.3000000000000003"},
+		{"(1+2)±0.1*2", 3, "iv:5.8,6.2"},
+		{"[x; x+1]^2", 3, "iv:4,9"},
+		{"sum(i, 1, 3, i*[1; 2])", 1, "iv:6,12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, map[string]float64{"x": 2})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != tt.tasks {
+				t.Errorf("Expected %d tasks, got %d", tt.tasks, len(compiled.Tasks))
+			}
+			if result := executePlanValue(t, compiled); result.String() != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, result)
+			}
+		})
+	}
+
+	for _, expr := range []string{"1±-1", "[2; 1]", "[[1, 2], 3]"} {
+		if _, err := plan(expr); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+}
+
+func TestCompleteExpression_Interval(t *testing.T) {
+	compiled, err := compile("(x+1)±0.5", map[string]float64{"x": 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Result == nil || *expr.Result != 3 {
+		t.Errorf("Expected midpoint 3, got %v", expr.Result)
+	}
+	if expr.Lower == nil || expr.Upper == nil || *expr.Lower != 2.5 || *expr.Upper != 3.5 {
+		t.Errorf("Expected bounds 2.5 and 3.5, got %v and %v", expr.Lower, expr.Upper)
+	}
+}
+
+func TestPlanTasks_Complex(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tasks  int
+		result string
+	}{
+		{"2+3i", 1, "cx:2,3"},
+		{"(3+4i)*(1-2i)", 3, "cx:11,-2"},
+		{"sqrt(x)*2", 2, "cx:0,6"},
+		{"x^0.5 + i", 2, "cx:0,4"},
+		{"complex(x, x+1)", 2, "cx:-9,-8"},
+		{"sum(k, 1, 3, k*i)", 1, "cx:0,6"},
+		{"(1+i)*(1-i)", 3, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, map[string]float64{"x": -9})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != tt.tasks {
+				t.Errorf("Expected %d tasks, got %d", tt.tasks, len(compiled.Tasks))
+			}
+			if result := executePlanValue(t, compiled); result.String() != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, result)
+			}
+		})
+	}
+
+	if _, err := compileInMode("1 + 2i", nil, evaluator.ModeExact); err == nil {
+		t.Error("Expected error for complex numbers in exact mode")
+	}
+}
+
+func TestCompleteExpression_Complex(t *testing.T) {
+	compiled, err := compile("(3+4i)*(1-2i)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Result == nil || *expr.Result != 11 || expr.Imag == nil || *expr.Imag != -2 {
+		t.Errorf("Expected 11-2i, got %v and %v", expr.Result, expr.Imag)
+	}
+
+	compiled, err = compile("(1+i)*(1-i)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+	expr = &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Result == nil || *expr.Result != 2 || expr.Imag != nil {
+		t.Errorf("Expected a real result without imaginary part, got %v and %v", expr.Result, expr.Imag)
+	}
+}
+
+func TestPlanTasks_Quantity(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tasks  int
+		result string
+	}{
+		{"3 m * 2 s^-1 + 4 km/h", 2, "q:7.111111111111111,1,m/s,m/s"},
+		{"(x*1 km + 500 m) to mi", 3, "q:3000,1609.344,m,mi"},
+		{"2 km to m", 0, "q:2000,1,m,m"},
+		{"10 km / (5 m)", 1, "2000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, map[string]float64{"x": 2.5})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != tt.tasks {
+				t.Errorf("Expected %d tasks, got %d", tt.tasks, len(compiled.Tasks))
+			}
+			if result := executePlanValue(t, compiled); result.String() != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, result)
+			}
+		})
+	}
+
+	if _, err := compile("2 km to kg", nil); err == nil {
+		t.Error("Expected error for conversion between incompatible units")
+	}
+	if _, err := compileInMode("2 km", nil, evaluator.ModeExact); err == nil {
+		t.Error("Expected error for units in exact mode")
+	}
+}
+
+func TestCompleteExpression_Quantity(t *testing.T) {
+	compiled, err := compile("3 m * 2 s^-1 + 4 km/h to km/h", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Result == nil || math.Abs(*expr.Result-25.6) > 1e-9 || expr.Unit == nil || *expr.Unit != "km/h" {
+		t.Errorf("Expected 25.6 km/h, got %v and %v", expr.Result, expr.Unit)
+	}
+}
+
+func TestPlanTasks_NumberTheory(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tasks  int
+		result string
+	}{
+		{"100!/98!", 3, "9900"},
+		{"30!", 1, "265252859812191058636308480000000"},
+		{"binom(x, 3) + gcd(12, 18)", 3, "90"},
+		{"modpow(2, x*10, 1000)", 2, "224"},
+		{"factor(360)", 1, "fac:2^3*3^2*5"},
+		{"factor(x + 1)", 2, "fac:2*5"},
+		{"isprime(97) && lcm(4, 6) == 12", 4, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, map[string]float64{"x": 9})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != tt.tasks {
+				t.Errorf("Expected %d tasks, got %d", tt.tasks, len(compiled.Tasks))
+			}
+			if result := executePlanValue(t, compiled); result.String() != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, result)
+			}
+		})
+	}
+
+	for _, expr := range []string{"modpow(2, 3)", "factor(0)", "factor(1, 2)"} {
+		if _, err := compile(expr, nil); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+}
+
+func TestPlanTasks_FactorChunks(t *testing.T) {
+	t.Setenv("TRIAL_DIVISION_CHUNK", "300")
+
+	compiled, err := compile("factor(1022117)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var chunks []string
+	for _, task := range compiled.Tasks {
+		if task.Operation == evaluator.TrialDivision {
+			if task.Arg1 != "1022117" {
+				t.Errorf("Unexpected trial division task %+v", task)
+			}
+			chunks = append(chunks, task.Arg2+".."+task.Arg3)
+		}
+	}
+	expected := "2..301 302..601 602..901 902..1010"
+	if got := strings.Join(chunks, " "); got != expected {
+		t.Errorf("Expected chunks %s, got %s", expected, got)
+	}
+	last := compiled.Tasks[len(compiled.Tasks)-1]
+	if len(compiled.Tasks) != 8 || last.Operation != evaluator.FactorCall || last.Arg1 != "1022117" || !isTaskRef(last.Arg2) {
+		t.Errorf("Expected 4 chunks, 3 merges and a final factor task, got %d tasks ending with %+v", len(compiled.Tasks), last)
+	}
+	if result := executePlanValue(t, compiled); result.String() != "fac:1009*1013" {
+		t.Errorf("Expected fac:1009*1013, got %s", result)
+	}
+
+	t.Setenv("TRIAL_DIVISION_CHUNK", "2")
+	compiled, err = compile("factor(1048579145728)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 2*maxTrialChunks {
+		t.Errorf("Expected chunk count to be capped at %d, got %d tasks", maxTrialChunks, len(compiled.Tasks))
+	}
+	if result := executePlanValue(t, compiled); result.String() != "fac:2^20*1000003" {
+		t.Errorf("Expected fac:2^20*1000003, got %s", result)
+	}
+}
+
+func useDefaultFolding(t *testing.T) {
+	t.Helper()
+	t.Setenv("FOLD_THRESHOLD_MS", "")
+	os.Unsetenv("FOLD_THRESHOLD_MS")
+}
+
+func TestPlanTasks_FactorChunksWithDefaultFolding(t *testing.T) {
+	useDefaultFolding(t)
+
+	compiled, err := compile("factor(1000036000099)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	trials := 0
+	for _, task := range compiled.Tasks {
+		if task.Operation == evaluator.TrialDivision {
+			trials++
+		}
+	}
+	if trials != 11 {
+		t.Errorf("Expected 11 trial division tasks, got %d", trials)
+	}
+	if result := executePlanValue(t, compiled); result.String() != "fac:1000003*1000033" {
+		t.Errorf("Expected fac:1000003*1000033, got %s", result)
+	}
+}
+
+func TestCompleteExpression_NumberTheory(t *testing.T) {
+	tests := []struct {
+		expr  string
+		exact string
+		fits  bool
+	}{
+		{"25!", "15511210043330985984000000", true},
+		{"factor(2^10*3*97)", "2^10*3*97", true},
+		{"200!", new(big.Int).MulRange(1, 200).String(), false},
+		{"factor(2^1100)", "2^1100", false},
+	}
+
+	for _, tt := range tests {
+		compiled, err := compile(tt.expr, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		finishTasks(t, compiled.Tasks)
+
+		expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+		if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+			t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+		}
+		if expr.Exact == nil || *expr.Exact != tt.exact {
+			t.Errorf("Expected exact result %s for %s, got %v", tt.exact, tt.expr, expr.Exact)
+		}
+		if (expr.Result != nil) != tt.fits {
+			t.Errorf("Expected float result only when %s fits in float64, got %v", tt.expr, expr.Result)
+		}
+	}
+}
+
+func TestPlanTasks_Matrix(t *testing.T) {
+	tests := []struct {
+		expr   string
+		tasks  int
+		result string
+	}{
+		{"[1, 2, 3]", 0, "vec:1,2,3"},
+		{"[[1, 2], [3, 4]]", 0, "mat:2x2:1,2,3,4"},
+		{"[1, x+1, 3, 4]", 3, "vec:1,3,3,4"},
+		{"[[1, 2], [x, x*x], [5, 6]]", 4, "mat:3x2:1,2,2,4,5,6"},
+		{"[x*x]", 2, "vec:4"},
+		{"[1, 2, 3]*x + 1", 2, "vec:3,5,7"},
+		{"dot([1, 2], [x, 4])", 1, "10"},
+		{"det([[x, 1], [2, 3]])", 1, "4"},
+		{"det([[x*x, 1], [2, 3]])", 4, "10"},
+		{"inv([[2, 0], [0, 4]])", 1, "mat:2x2:0.5,0,0,0.25"},
+		{"transpose([[1, 2, 3]])", 1, "mat:3x1:1,2,3"},
+		{"cross([1, 0, 0], [0, 1, 0])", 1, "vec:0,0,1"},
+		{"matmul([[1, 2], [3, 4]], [[5, 6], [7, 8]])", 1, "mat:2x2:19,22,43,50"},
+		{"A = [[1, 2], [3, 4]]; matmul(A, [x, 1])", 1, "vec:4,10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			compiled, err := compile(tt.expr, map[string]float64{"x": 2})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(compiled.Tasks) != tt.tasks {
+				t.Errorf("Expected %d tasks, got %d", tt.tasks, len(compiled.Tasks))
+			}
+			if result := executePlanValue(t, compiled); result.String() != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, result)
+			}
+		})
+	}
+
+	for _, expr := range []string{"[1, 2, 3]", "det(1, 2)", "vector()"} {
+		if _, err := compileInMode(expr, nil, evaluator.ModeExact); err == nil {
+			t.Errorf("Expected error for %s", expr)
+		}
+	}
+}
+
+func TestPlanTasks_MatMulBlocks(t *testing.T) {
+	t.Setenv("MATMUL_BLOCK_ROWS", "2")
+
+	compiled, err := compile("matmul([[1, 0], [0, 1], [1, 1], [2, 2], [3, 3]], [[1, 2], [x, 4]])", map[string]float64{"x": 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var blocks []string
+	for _, task := range compiled.Tasks {
+		if task.Operation == evaluator.MatMul {
+			blocks = append(blocks, task.Arg1)
+		}
+	}
+	expected := "mat:2x2:1,0,0,1 mat:2x2:1,1,2,2 mat:1x2:3,3"
+	if got := strings.Join(blocks, " "); got != expected {
+		t.Errorf("Expected row blocks %s, got %s", expected, got)
+	}
+	if len(compiled.Tasks) != 5 {
+		t.Errorf("Expected 3 block products and 2 stacking tasks, got %d tasks", len(compiled.Tasks))
+	}
+	if result := executePlanValue(t, compiled); result.String() != "mat:5x2:1,2,3,4,4,6,8,12,12,18" {
+		t.Errorf("Expected stacked product, got %s", result)
+	}
+
+	compiled, err = compile("matmul([[1, 2], [3, 4], [5, 6]], [1, x])", map[string]float64{"x": 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := compiled.Tasks[len(compiled.Tasks)-1]
+	if last.Operation != evaluator.VectorCall {
+		t.Errorf("Expected block products with a vector to be concatenated, got %+v", last)
+	}
+	if result := executePlanValue(t, compiled); result.String() != "vec:7,15,23" {
+		t.Errorf("Expected vec:7,15,23, got %s", result)
+	}
+}
+
+func TestPlanTasks_MatMulBlocksWithDefaultFolding(t *testing.T) {
+	useDefaultFolding(t)
+
+	rows := make([]string, 70)
+	for i := range rows {
+		rows[i] = fmt.Sprintf("[%d, 1]", i)
+	}
+	expression := "matmul([" + strings.Join(rows, ", ") + "], [2, 3])"
+	compiled, err := compile(expression, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	operations := make([]string, len(compiled.Tasks))
+	for i, task := range compiled.Tasks {
+		operations[i] = task.Operation
+	}
+	if got := strings.Join(operations, " "); got != "matmul matmul vector" {
+		t.Errorf("Expected two row blocks and a vector task, got %s", got)
+	}
+	expected, err := CalcInMode(expression, nil, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result := executePlanValue(t, compiled); result.String() != expected.String() {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestPlanTasks_Statistics(t *testing.T) {
+	t.Setenv("STATS_CHUNK_SIZE", "3")
+
+	tests := []struct {
+		expr       string
+		operations string
+		expected   string
+	}{
+		{"variance([2, 4, 4, 4, 5, 5, 7, x])", "moments moments moments + + variance", "4"},
+		{"mean([2, 4, 4, 4, 5, 5, 7, x]) + 1", "moments moments moments + + mean +", "6"},
+		{"linreg([1, 2, 3, 4, 5], [3, 5, 7, 9, 11])", "moments moments + linreg", "vec:2,1"},
+		{"linreg([1, 2, 3, 4, 5], [3, 5, 7, 9, 2 + x])", "+ vector linreg", "vec:2,1"},
+		{"correlation([1, 2, 3, 4], [x, 7, 5, 3])", "moments moments + correlation", "-1"},
+		{"stddev([1, x])", "stddev", "4"},
+		{"median([9, 1, 5, x, 3])", "median", "5"},
+		{"percentile([1, 2, 3, 4, x], 25)", "percentile", "2"},
+	}
+
+	for _, tt := range tests {
+		compiled, err := compile(tt.expr, map[string]float64{"x": 9})
+		if err != nil {
+			t.Fatalf("compile(%s) failed: %v", tt.expr, err)
+		}
+		operations := make([]string, len(compiled.Tasks))
+		for i, task := range compiled.Tasks {
+			operations[i] = task.Operation
+		}
+		if got := strings.Join(operations, " "); got != tt.operations {
+			t.Errorf("Expected %s to plan %s, got %s", tt.expr, tt.operations, got)
+		}
+		if result := executePlanValue(t, compiled); result.String() != tt.expected {
+			t.Errorf("Expected %s = %s, got %s", tt.expr, tt.expected, result)
+		}
+	}
+}
+
+func TestPlanTasks_StatisticsWithDefaultFolding(t *testing.T) {
+	useDefaultFolding(t)
+
+	values := make([]string, 5000)
+	for i := range values {
+		values[i] = strconv.Itoa(i % 7)
+	}
+	expression := "stddev([" + strings.Join(values, ", ") + "])"
+	compiled, err := compile(expression, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	moments := 0
+	for _, task := range compiled.Tasks {
+		if task.Operation == evaluator.MomentsCall {
+			moments++
+		}
+	}
+	if moments != 5 {
+		t.Errorf("Expected 5 moments tasks, got %d", moments)
+	}
+	expected, err := CalcInMode(expression, nil, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result := executePlanValue(t, compiled); math.Abs(result.Float()-expected.Float()) > 1e-9 {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
+
+func TestCompleteExpression_Matrix(t *testing.T) {
+	compiled, err := compile("v = [x, 2, 3]; w = v*2; [[1, 0], [0, x]]", map[string]float64{"x": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result, Bindings: compiled.Bindings}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Result != nil || expr.Vector != nil {
+		t.Errorf("Expected no scalar result for a matrix, got %v and %v", expr.Result, expr.Vector)
+	}
+	if len(expr.Matrix) != 2 || expr.Matrix[1][1] != 4 || expr.Matrix[0][1] != 0 {
+		t.Errorf("Expected matrix rows, got %v", expr.Matrix)
+	}
+	if v := expr.Assignments["v"]; v.Result != nil || len(v.Vector) != 3 || v.Vector[0] != 4 {
+		t.Errorf("Expected vector assignment v, got %+v", v)
+	}
+	if w := expr.Assignments["w"]; len(w.Vector) != 3 || w.Vector[2] != 6 {
+		t.Errorf("Expected vector assignment w, got %+v", w)
+	}
+
+	compiled, err = compile("cross([1, 0, 0], [0, x, 0])", map[string]float64{"x": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+	expr = &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if len(expr.Vector) != 3 || expr.Vector[2] != 4 || expr.Matrix != nil {
+		t.Errorf("Expected vector 0,0,4, got %v", expr.Vector)
+	}
+}
+
+func TestPlanTasks_Average(t *testing.T) {
+	planned, err := plan("avg(2, 4, 9)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	last := planned[len(planned)-1]
+	if last.Operation != "/" || last.Arg2 != "3" {
+		t.Errorf("Expected final division by 3, got %+v", last)
+	}
+
+	if _, err := plan("max()"); err == nil {
+		t.Error("Expected error for aggregate without arguments")
+	}
+}
+
+func TestPlanTasks_MatchesCalc(t *testing.T) {
+	exprs := []string{
+		"sum(0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7)",
+		"avg(0.1, 0.7, 1.0, 3.3)",
+		"max(1, -2, sqrt(2))*min(3, 0.5)",
+		"-(2+3)^2/7",
+		"pi*2+e-tau/phi",
+		"6.02e23*1.5e-3/-1e-10",
+		"0.1+0.2+0.3-0.4+0.5-0.6+0.7",
+		"0.1*0.2/0.3*0.7/0.11",
+	}
+
+	for _, expr := range exprs {
+		want, err := Calc(expr)
+		if err != nil {
+			t.Fatalf("Calc(%q) unexpected error: %v", expr, err)
+		}
+		compiled, err := compile(expr, nil)
+		if err != nil {
+			t.Fatalf("compile(%q) unexpected error: %v", expr, err)
+		}
+		if got := executePlan(t, compiled); got != want {
+			t.Errorf("Distributed result of %q = %v, Calc = %v", expr, got, want)
+		}
+	}
+}
+
+func TestPlanTasks_LiteralFormatting(t *testing.T) {
+	planned, err := plan("6.02e23*pi")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if planned[0].Arg1 != "6.02e+23" {
+		t.Errorf("Expected 6.02e+23, got %s", planned[0].Arg1)
+	}
+	if planned[0].Arg2 != "3.141592653589793" {
+		t.Errorf("Expected full precision pi, got %s", planned[0].Arg2)
+	}
+
+	if _, err := plan("x+1"); err == nil {
+		t.Error("Expected error for unknown identifier")
+	}
+}
+
+func TestPlanTasks_Variables(t *testing.T) {
+	compiled, err := compile("principal*(1+rate)^2", map[string]float64{"principal": 1000, "rate": 0.07})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	planned := compiled.Tasks
+	if planned[0].Arg1 != "1" || planned[0].Arg2 != "0.07" {
+		t.Errorf("Expected rate to be substituted as a literal, got %+v", planned[0])
+	}
+	if root := planned[len(planned)-1]; root.Arg1 != "1000" {
+		t.Errorf("Expected principal to be substituted as a literal, got %+v", root)
+	}
+}
+
+func TestPlanTasks_ProgramSharesIntermediates(t *testing.T) {
+	compiled, err := compile("x = 2+3; y = x*4; y - x", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(compiled.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks with x computed once, got %d", len(compiled.Tasks))
+	}
+	x, y, last := compiled.Tasks[0], compiled.Tasks[1], compiled.Tasks[2]
+	if y.Arg1 != "$"+x.ID || last.Arg1 != "$"+y.ID || last.Arg2 != "$"+x.ID {
+		t.Errorf("Expected y and the final statement to reuse x, got %+v %+v", y, last)
+	}
+	if compiled.Bindings["x"] != "$"+x.ID || compiled.Bindings["y"] != "$"+y.ID {
+		t.Errorf("Unexpected bindings %v", compiled.Bindings)
+	}
+	if compiled.Result != "$"+last.ID {
+		t.Errorf("Expected result to reference %s, got %s", last.ID, compiled.Result)
+	}
+	if got := executePlan(t, compiled); got != 15 {
+		t.Errorf("Expected 15, got %v", got)
+	}
+}
+
+func TestPlanTasks_ProgramResultIsNotLastTask(t *testing.T) {
+	compiled, err := compile("x = 2*3; y = x+1; x", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if compiled.Result != "$"+compiled.Tasks[0].ID {
+		t.Errorf("Expected result to reference x, got %s", compiled.Result)
+	}
+
+	compiled, err = compile("x = 4; -x", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 0 || compiled.Result != "-4" || compiled.Bindings["x"] != "4" {
+		t.Errorf("Expected literal-only program without tasks, got %+v", compiled)
+	}
+}
+
+func TestCompleteExpression(t *testing.T) {
+	compiled, err := compile("x = 2+3; y = x*4; y - x", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result, Bindings: compiled.Bindings}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || done {
+		t.Fatalf("Expected pending expression, got done=%v err=%v", done, err)
+	}
+
+	finishTasks(t, compiled.Tasks)
+
+	done, err := completeExpression(expr, compiled.Tasks)
+	if err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if *expr.Result != 15 || assigned(expr.Assignments, "x") != 5 || assigned(expr.Assignments, "y") != 20 {
+		t.Errorf("Unexpected result %v and assignments %v", *expr.Result, expr.Assignments)
+	}
+}
+
+func TestPlanTasks_ExactMode(t *testing.T) {
+	compiled, err := compileInMode("0.1 + 0.2 - x/3", map[string]float64{"x": 0.3}, evaluator.ModeExact)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if compiled.Tasks[0].Arg1 != "rat:1/10" || compiled.Tasks[0].Arg2 != "rat:1/5" {
+		t.Errorf("Expected exact literal arguments, got %+v", compiled.Tasks[0])
+	}
+	if got := executePlanValue(t, compiled).String(); got != "rat:1/5" {
+		t.Errorf("Expected rat:1/5, got %s", got)
+	}
+
+	compiled, err = compileInMode("avg(1, 2, 2) + -1/3", nil, evaluator.ModeExact)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := executePlanValue(t, compiled).String(); got != "rat:4/3" {
+		t.Errorf("Expected rat:4/3, got %s", got)
+	}
+
+	if _, err := compileInMode("pi*2", nil, evaluator.ModeExact); err == nil {
+		t.Error("Expected irrational constant to be rejected in exact mode")
+	}
+}
+
+func TestCompleteExpression_ExactMode(t *testing.T) {
+	compiled, err := compileInMode("x = 1/3; x + 0.1", nil, evaluator.ModeExact)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", Mode: evaluator.ModeExact, ResultRef: compiled.Result, Bindings: compiled.Bindings}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Exact == nil || *expr.Exact != "13/30" {
+		t.Errorf("Expected exact 13/30, got %v", expr.Exact)
+	}
+	if expr.Decimal == nil || *expr.Decimal != "0.433333333333333333333333333333" {
+		t.Errorf("Unexpected decimal rendering %v", expr.Decimal)
+	}
+	if *expr.Result != 13.0/30 || assigned(expr.Assignments, "x") != 1.0/3 {
+		t.Errorf("Unexpected approximations %v %v", *expr.Result, expr.Assignments)
+	}
+}
+
+func TestPlanTasks_IntegerOperators(t *testing.T) {
+	compiled, err := compile("(0xFF & 0b1010) << 2 | -7 // 2 % 3", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ops := make(map[string]bool)
+	for _, task := range compiled.Tasks {
+		ops[task.Operation] = true
+	}
+	for _, op := range []string{"&", "<<", "|", "//", "%"} {
+		if !ops[op] {
+			t.Errorf("Expected a %s task, got %+v", op, compiled.Tasks)
+		}
+	}
+	if compiled.Tasks[0].Arg1 != "255" || compiled.Tasks[0].Arg2 != "10" {
+		t.Errorf("Expected prefixed literals to be sent as decimal integers, got %+v", compiled.Tasks[0])
+	}
+	if got := executePlanValue(t, compiled).String(); got != "42" {
+		t.Errorf("Expected 42, got %s", got)
+	}
+}
+
+func TestCompleteExpression_Integer(t *testing.T) {
+	compiled, err := compile("0x7FFFFFFFFFFFFFFF - 1", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result, Bindings: compiled.Bindings}
+	if done, err := completeExpression(expr, compiled.Tasks); err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if expr.Exact == nil || *expr.Exact != "9223372036854775806" {
+		t.Errorf("Expected exact 64-bit result, got %v", expr.Exact)
+	}
+	if expr.Decimal != nil {
+		t.Errorf("Expected no decimal rendering for integers, got %v", *expr.Decimal)
+	}
+}
+
+func TestPlanTasks_ConditionalIsLazy(t *testing.T) {
+	compiled, err := compile("if(x > 2, x * 10, sqrt(x) + 1) + 1", map[string]float64{"x": 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	var conditional *models.Task
+	for _, task := range compiled.Tasks {
+		statuses[task.Operation] = task.Status
+		if task.Operation == "if" {
+			conditional = task
+		}
+	}
+	if statuses[">"] != "pending" || statuses["+"] != "pending" {
+		t.Errorf("Expected condition and consumer to be pending, got %v", statuses)
+	}
+	if statuses["*"] != "waiting" || statuses["sqrt"] != "waiting" {
+		t.Errorf("Expected both branches to wait for the condition, got %v", statuses)
+	}
+	if conditional == nil || conditional.Status != "waiting" || conditional.Condition != "$expr_task1" {
+		t.Fatalf("Expected a waiting if task conditioned on the comparison, got %+v", conditional)
+	}
+
+	if got := executePlan(t, compiled); got != 41 {
+		t.Errorf("Expected 41, got %v", got)
+	}
+	for _, task := range compiled.Tasks {
+		want := "done"
+		if task.Guard == "!$expr_task1" {
+			want = "skipped"
+		}
+		if task.Status != want {
+			t.Errorf("Expected task %s (%s) to be %s, got %s", task.ID, task.Operation, want, task.Status)
+		}
+	}
+}
+
+func TestPlanTasks_ConstantConditionPlansOneBranch(t *testing.T) {
+	planned, err := plan("if(1, 2 * 3, 4 * 5)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(planned) != 1 || planned[0].Arg1 != "2" || planned[0].Status != "pending" {
+		t.Errorf("Expected only the selected branch to be planned, got %+v", planned)
+	}
+
+	compiled, err := compile("if(flag, 2 * 3, 4 * 5) + (flag || 0)", map[string]float64{"flag": 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, task := range compiled.Tasks {
+		if task.Arg1 == "2" || task.Operation == "if" || task.Status != "pending" {
+			t.Errorf("Unexpected task %+v", task)
+		}
+	}
+	if got := executePlan(t, compiled); got != 20 {
+		t.Errorf("Expected 20, got %v", got)
+	}
+}
+
+func TestPlanTasks_LogicalOperatorsShortCircuit(t *testing.T) {
+	compiled, err := compile("x != 0 && 10 / x > 1", map[string]float64{"x": 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := executePlan(t, compiled); got != 0 {
+		t.Errorf("Expected 0, got %v", got)
+	}
+	for _, task := range compiled.Tasks {
+		if task.Operation == "/" && task.Status != "skipped" {
+			t.Errorf("Expected the division to be skipped, got %s", task.Status)
+		}
+	}
+
+	compiled, err = compile("x || 2 + 3", map[string]float64{"x": 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := executePlan(t, compiled); got != 1 {
+		t.Errorf("Expected || to yield 1, got %v", got)
+	}
+}
+
+func TestPlanTasks_NestedConditionals(t *testing.T) {
+	for x, expected := range map[float64]float64{-5: -1, 0: 0, 5: 1} {
+		compiled, err := compile("if(x < 0, -1, if(x > 0, 1, 0)) * 1", map[string]float64{"x": x})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := executePlan(t, compiled); got != expected {
+			t.Errorf("sign(%v): expected %v, got %v", x, expected, got)
+		}
+	}
+
+	compiled, err := compile("y = if(x > 1, x * 2, x - 1); y + 1", map[string]float64{"x": 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := executePlan(t, compiled); got != 0 {
+		t.Errorf("Expected 0, got %v", got)
+	}
+}
+
+func TestPlanTasks_ConditionalMatchesCalc(t *testing.T) {
+	expressions := []string{
+		"if(2 > 1 && 3 > 2, 1 + 1, 0)",
+		"!(1 < 2) || 3 == 3",
+		"if(x >= 0.5, x * 2, -x) + (x == 0.5)",
+		"if(if(x, 0, 1), 10, 20)",
+	}
+	for _, expr := range expressions {
+		for _, mode := range []string{"", evaluator.ModeExact} {
+			want, err := CalcInMode(expr, map[string]float64{"x": 0.5}, mode)
+			if err != nil {
+				t.Fatalf("CalcInMode(%q) unexpected error: %v", expr, err)
+			}
+			compiled, err := compileInMode(expr, map[string]float64{"x": 0.5}, mode)
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", expr, err)
+			}
+			if got := executePlanValue(t, compiled); got.String() != want.String() {
+				t.Errorf("%q in mode %q: tasks gave %s, Calc gave %s", expr, mode, got, want)
+			}
+		}
+	}
+}
+
+func TestAdvanceTasks(t *testing.T) {
+	compiled, err := compile("if(x > 0, if(x > 10, x * 2, x * 3), x * 4)", map[string]float64{"x": 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if changed := advanceTasks(compiled.Tasks); len(changed) != 0 {
+		t.Errorf("Expected nothing to change before the condition is computed, got %d", len(changed))
+	}
+
+	cond := compiled.Tasks[0]
+	one := 1.0
+	cond.Result, cond.Status = &one, "done"
+
+	changed := advanceTasks(compiled.Tasks)
+	released := map[string]string{}
+	for _, task := range changed {
+		released[task.Operation+task.Arg2] = task.Status
+	}
+	if released[">10"] != "pending" || released["*4"] != "skipped" {
+		t.Errorf("Expected inner condition released and else branch skipped, got %v", released)
+	}
+	for _, task := range compiled.Tasks {
+		if task.Guard != "" && task.Guard != "$expr_task1" && task.Guard != "!$expr_task1" && task.Status != "waiting" {
+			t.Errorf("Expected inner branch task %s to keep waiting, got %s", task.ID, task.Status)
+		}
+	}
+}
+
+func TestCompleteExpression_SkippedBranch(t *testing.T) {
+	compiled, err := compile("if(x > 1, x * 2, x - 1)", map[string]float64{"x": 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	done, err := completeExpression(expr, compiled.Tasks)
+	if err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if *expr.Result != 6 {
+		t.Errorf("Expected 6, got %v", *expr.Result)
+	}
+}
+
+func TestCompleteExpression_FailedTask(t *testing.T) {
+	compiled, err := compile("x / 0 + x * 3", map[string]float64{"x": 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message := "деление на ноль"
+	failed := compiled.Tasks[0]
+	failed.Status, failed.Error = "failed", &message
+
+	for _, task := range advanceTasks(compiled.Tasks) {
+		if task == failed || task.Status != "skipped" {
+			t.Errorf("Expected remaining task %s to be skipped, got %s", task.ID, task.Status)
+		}
+	}
+	for _, task := range compiled.Tasks {
+		if task.Status == "pending" || task.Status == "waiting" {
+			t.Errorf("Expected task %s to be cancelled, got %s", task.ID, task.Status)
+		}
+	}
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result}
+	done, err := completeExpression(expr, compiled.Tasks)
+	if err != nil || !done {
+		t.Fatalf("Expected finished expression, got done=%v err=%v", done, err)
+	}
+	if expr.Status != models.StatusFailed || expr.Error == nil || *expr.Error != message || expr.Result != nil {
+		t.Errorf("Expected failed expression with the task error, got %+v", expr)
+	}
+}
+
+func TestPlanTasks_CommonSubexpressions(t *testing.T) {
+	tests := []struct {
+		expression string
+		operations string
+	}{
+		{"(2*3)+(2*3)", "* +"},
+		{"(2*3)+(3*2)", "* +"},
+		{"(2^3)+(3^2)", "^ ^ +"},
+		{"sqrt(2)*sqrt(2) + sqrt(2)", "sqrt * +"},
+		{"x = 2*3; 2*3 + x", "* +"},
+		{"sum(2*3, 2*3, 2*3, 2*3)", "* + +"},
+		{"2*3 + if(2 > 1, 2*3, 0)", "* > if +"},
+		{"if(2 > 1, 2*3, 0) + 2*3", "> * if * +"},
+		{"if(2 > 1, 2*3, 0) + if(2 > 1, 3*2, 0)", "> * if +"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			compiled, err := compile(test.expression, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var operations []string
+			for _, task := range compiled.Tasks {
+				operations = append(operations, task.Operation)
+			}
+			if strings.Join(operations, " ") != test.operations {
+				t.Errorf("Expected tasks %q, got %q", test.operations, strings.Join(operations, " "))
+			}
+
+			expected, err := Calc(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result := executePlan(t, compiled); result != expected {
+				t.Errorf("Expected %v, got %v", expected, result)
+			}
+		})
+	}
+}
+
+func TestCompleteExpression_SharedChildren(t *testing.T) {
+	compiled, err := compile("a = 2+3; a*a - (3+2)", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(compiled.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(compiled.Tasks))
+	}
+	mul := compiled.Tasks[1]
+	if mul.Arg1 != mul.Arg2 || mul.Arg1 != compiled.Bindings["a"] {
+		t.Errorf("Expected both operands to share the addition task, got %s and %s", mul.Arg1, mul.Arg2)
+	}
+	finishTasks(t, compiled.Tasks)
+
+	expr := &models.Expression{ID: "expr", ResultRef: compiled.Result, Bindings: compiled.Bindings}
+	done, err := completeExpression(expr, compiled.Tasks)
+	if err != nil || !done {
+		t.Fatalf("Expected completed expression, got done=%v err=%v", done, err)
+	}
+	if *expr.Result != 20 || assigned(expr.Assignments, "a") != 5 {
+		t.Errorf("Expected 20 with a=5, got %v and %v", *expr.Result, expr.Assignments)
+	}
+}
+
+func TestPlanTasks_RebalancesChains(t *testing.T) {
+	terms := make([]string, 64)
+	for i := range terms {
+		terms[i] = strconv.Itoa(i + 1)
+	}
+
+	tests := []struct {
+		expression   string
+		tasks        int
+		depth        int
+		criticalPath int64
+		expected     float64
+	}{
+		{strings.Join(terms, "+"), 63, 6, 6000, 2080},
+		{strings.Join(terms, "-"), 63, 63, 63000, -2078},
+		{"1e308/10*10", 2, 2, 4000, 1e308},
+		{"2*3*4*5*6*7*8*9", 7, 3, 6000, 362880},
+		{"1/2/3/4", 3, 3, 6000, 1.0 / 24},
+		{"2^3 + 4", 2, 2, 3000, 12},
+		{"if(2 > 1, 2*3, 0) + 1", 3, 3, 4000, 7},
+		{"7", 0, 0, 0, 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			compiled, err := compile(test.expression, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if compiled.Dispatched() != test.tasks {
+				t.Errorf("Expected %d tasks, got %d", test.tasks, compiled.Dispatched())
+			}
+			if compiled.Depth != test.depth || compiled.CriticalPath != test.criticalPath {
+				t.Errorf("Expected depth %d and critical path %d, got %d and %d",
+					test.depth, test.criticalPath, compiled.Depth, compiled.CriticalPath)
+			}
+			if result := executePlan(t, compiled); result != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	planned := []*models.Task{
+		{ID: "t1", Arg1: "1", Arg2: "2", Operation: "+", OperationTime: 1000},
+		{ID: "t2", Arg1: "3", Arg2: "4", Operation: "*", OperationTime: 5000},
+		{ID: "t3", Arg1: "$t1", Arg2: "$t1", Operation: "*", OperationTime: 2000},
+		{ID: "t4", Arg1: "$t3", Arg2: "$t2", Operation: "-", OperationTime: 1000},
+	}
+
+	depth, total := criticalPath(planned)
+	if depth != 3 {
+		t.Errorf("Expected depth 3, got %d", depth)
+	}
+	if total != 6000 {
+		t.Errorf("Expected critical path 6000, got %d", total)
+	}
+}
+
+func assigned(assignments map[string]models.Value, name string) float64 {
+	if value, ok := assignments[name]; ok && value.Result != nil {
+		return *value.Result
+	}
+	return math.NaN()
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"calculator/evaluator"
 	"calculator/models"
+	"calculator/parser"
 	"errors"
 	"fmt"
 	"os"
@@ -11,15 +13,6 @@ import (
 	"time"
 )
 
-type Operation struct {
-	Type     string
-	Priority int
-	Left     *Operation
-	Right    *Operation
-	Value    float64
-	IsValue  bool
-}
-
 var (
 	expressions = make(map[string]*models.Expression)
 	tasks       = make(map[string]*models.Task)
@@ -51,57 +44,32 @@ func CreateExpression(expr string) (*models.Expression, error) {
 func splitExpressionIntoTasks(exp *models.Expression) error {
 	fmt.Printf("Разбираем выражение: %s\n", exp.Expression)
 
-	tree, err := parseExpression(exp.Expression)
+	program, err := parser.ParseProgram(exp.Expression)
 	if err != nil {
 		fmt.Printf("Ошибка разбора выражения: %v\n", err)
 		return err
 	}
 
-	taskCounter := 1
-	var createTasks func(*Operation) (string, error)
-	createTasks = func(op *Operation) (string, error) {
-		if op.IsValue {
-			fmt.Printf("Создаем значение: %v\n", op.Value)
-			return fmt.Sprintf("%v", op.Value), nil
-		}
-
-		leftArg, err := createTasks(op.Left)
-		if err != nil {
-			return "", err
-		}
-
-		rightArg, err := createTasks(op.Right)
-		if err != nil {
-			return "", err
-		}
-
-		taskID := fmt.Sprintf("%s_task%d", exp.ID, taskCounter)
-		taskCounter++
-
-		task := &models.Task{
-			ID:            taskID,
-			ExpressionID:  exp.ID,
-			Arg1:          leftArg,
-			Arg2:          rightArg,
-			Operation:     op.Type,
-			OperationTime: getOperationTime(op.Type),
-			Status:        "pending",
-		}
-
-		fmt.Printf("Создаем задачу: %+v\n", task)
-
-		mu.Lock()
-		tasks[taskID] = task
-		mu.Unlock()
-
-		return fmt.Sprintf("$%s", taskID), nil
-	}
-
-	_, err = createTasks(tree)
+	plan, err := planTasks(exp.ID, program, exp.Variables, exp.Mode)
 	if err != nil {
 		fmt.Printf("Ошибка создания задач: %v\n", err)
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	exp.ResultRef = plan.Result
+	exp.Bindings = plan.Bindings
+	exp.Folded = plan.Folded
+	exp.Dispatched = plan.Dispatched()
+	exp.Depth = plan.Depth
+	exp.CriticalPath = plan.CriticalPath
+	exp.Numerics = plan.Numerics
+	for _, task := range plan.Tasks {
+		fmt.Printf("Создаем задачу: %+v\n", task)
+		tasks[task.ID] = task
 	}
-	return err
+	return nil
 }
 
 func getOperationTime(op string) int64 {
@@ -112,11 +80,32 @@ func getOperationTime(op string) int64 {
 		return getEnvInt64("TIME_SUBTRACTION_MS", 1000)
 	case "*":
 		return getEnvInt64("TIME_MULTIPLICATION_MS", 2000)
-	case "/":
+	case "/", "//", "%":
 		return getEnvInt64("TIME_DIVISION_MS", 2000)
-	default:
-		return 1000
-	}
+	case "^":
+		return getEnvInt64("TIME_POWER_MS", 2000)
+	case "&", "|", "xor", "<<", ">>":
+		return getEnvInt64("TIME_BITWISE_MS", 1000)
+	case "<", "<=", "==", "!=", ">=", ">", "not":
+		return getEnvInt64("TIME_COMPARISON_MS", 1000)
+	case evaluator.Conditional, evaluator.PlusMinus, evaluator.IntervalCall, evaluator.ComplexCall, evaluator.ConvertCall, evaluator.VectorCall, evaluator.MatrixCall:
+		return 0
+	case "neg":
+		return getEnvInt64("TIME_NEGATION_MS", 1000)
+	case "min", "max", evaluator.Integrate, evaluator.Solve:
+		return getFunctionTime(op)
+	case evaluator.RangeSum, evaluator.RangeProduct:
+		return getEnvInt64("TIME_RANGE_CHUNK_MS", 1000)
+	}
+	if evaluator.IsFunction(op) || evaluator.IsNumberTheory(op) || evaluator.IsMatrixOperation(op) || evaluator.IsStatistic(op) || op == evaluator.TrialDivision || op == evaluator.MomentsCall {
+		return getFunctionTime(op)
+	}
+	return 1000
+}
+
+func getFunctionTime(name string) int64 {
+	fallback := getEnvInt64("TIME_FUNCTION_MS", 1000)
+	return getEnvInt64("TIME_"+strings.ToUpper(name)+"_MS", fallback)
 }
 
 func getEnvInt64(key string, fallback int64) int64 {
@@ -168,31 +157,24 @@ func SubmitTaskResult(taskID string, result float64) error {
 	if expr != nil {
 		fmt.Printf("Проверяем статус выражения %s\n", expr.ID)
 
-		var exprTasks []models.Task
+		var exprTasks []*models.Task
 		for _, t := range tasks {
 			if t.ExpressionID == expr.ID {
-				exprTasks = append(exprTasks, *t)
+				exprTasks = append(exprTasks, t)
 			}
 		}
 
-		allDone := true
-		var lastResult float64
-		for _, t := range exprTasks {
-			fmt.Printf("  Задача %s: статус=%s\n", t.ID, t.Status)
-			if t.Status != "done" {
-				allDone = false
-				break
-			}
-			if t.Result != nil {
-				lastResult = *t.Result
-			}
+		advanceTasks(exprTasks)
+		done, err := completeExpression(expr, exprTasks)
+		if err != nil {
+			return err
 		}
-
-		if allDone {
-			expr.Status = models.StatusDone
-			expr.Result = &lastResult
+		if done && expr.Result != nil {
 			fmt.Printf("Выражение %s завершено с результатом %v\n",
 				expr.ID, *expr.Result)
+		} else if done {
+			fmt.Printf("Выражение %s завершено со статусом %s\n",
+				expr.ID, expr.Status)
 		} else {
 			expr.Status = models.StatusComputing
 			fmt.Printf("Выражение %s в процессе вычисления\n", expr.ID)
@@ -225,85 +207,3 @@ func GetTask(id string) (*models.Task, bool) {
 	task, exists := tasks[id]
 	return task, exists
 }
-
-func buildTree(tokens []string) (*Operation, error) {
-	if len(tokens) == 0 {
-		return nil, errors.New("пустое выражение")
-	}
-
-	for i := len(tokens) - 1; i >= 0; i-- {
-		if tokens[i] == "+" || tokens[i] == "-" {
-			left, err := buildTree(tokens[:i])
-			if err != nil {
-				return nil, fmt.Errorf("ошибка в левой части: %v", err)
-			}
-			right, err := buildTree(tokens[i+1:])
-			if err != nil {
-				return nil, fmt.Errorf("ошибка в правой части: %v", err)
-			}
-			return &Operation{
-				Type:     tokens[i],
-				Priority: 1,
-				Left:     left,
-				Right:    right,
-			}, nil
-		}
-	}
-
-	for i := len(tokens) - 1; i >= 0; i-- {
-		if tokens[i] == "*" || tokens[i] == "/" {
-			left, err := buildTree(tokens[:i])
-			if err != nil {
-				return nil, fmt.Errorf("ошибка в левой части: %v", err)
-			}
-			right, err := buildTree(tokens[i+1:])
-			if err != nil {
-				return nil, fmt.Errorf("ошибка в правой части: %v", err)
-			}
-			return &Operation{
-				Type:     tokens[i],
-				Priority: 2,
-				Left:     left,
-				Right:    right,
-			}, nil
-		}
-	}
-
-	val, err := strconv.ParseFloat(strings.Join(tokens, ""), 64)
-	if err != nil {
-		return nil, fmt.Errorf("некорректное число: %v", err)
-	}
-	return &Operation{
-		IsValue: true,
-		Value:   val,
-	}, nil
-}
-
-func parseExpression(expr string) (*Operation, error) {
-	tokens := tokenize(expr)
-	return buildTree(tokens)
-}
-
-func tokenize(expr string) []string {
-	var tokens []string
-	var num strings.Builder
-
-	for _, c := range expr {
-		switch c {
-		case '+', '-', '*', '/':
-			if num.Len() > 0 {
-				tokens = append(tokens, num.String())
-				num.Reset()
-			}
-			tokens = append(tokens, string(c))
-		case ' ':
-			continue
-		default:
-			num.WriteRune(c)
-		}
-	}
-	if num.Len() > 0 {
-		tokens = append(tokens, num.String())
-	}
-	return tokens
-}
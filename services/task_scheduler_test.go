@@ -0,0 +1,89 @@
+package services
+
+import (
+	"calculator/models"
+	"testing"
+)
+
+func TestGetOperationTime_Power(t *testing.T) {
+	t.Setenv("TIME_POWER_MS", "1500")
+	if got := getOperationTime("^"); got != 1500 {
+		t.Errorf("Expected 1500, got %d", got)
+	}
+}
+
+func TestGetOperationTime_Functions(t *testing.T) {
+	t.Setenv("TIME_FUNCTION_MS", "300")
+	t.Setenv("TIME_SQRT_MS", "700")
+
+	if got := getOperationTime("sqrt"); got != 700 {
+		t.Errorf("Expected sqrt time 700, got %d", got)
+	}
+	if got := getOperationTime("sin"); got != 300 {
+		t.Errorf("Expected sin to fall back to 300, got %d", got)
+	}
+}
+
+func TestGetOperationTime_Integer(t *testing.T) {
+	t.Setenv("TIME_DIVISION_MS", "1200")
+	t.Setenv("TIME_BITWISE_MS", "50")
+
+	for _, op := range []string{"//", "%"} {
+		if got := getOperationTime(op); got != 1200 {
+			t.Errorf("Expected %s time 1200, got %d", op, got)
+		}
+	}
+	for _, op := range []string{"&", "|", "xor", "<<", ">>"} {
+		if got := getOperationTime(op); got != 50 {
+			t.Errorf("Expected %s time 50, got %d", op, got)
+		}
+	}
+}
+
+func TestGetOperationTime_Comparison(t *testing.T) {
+	t.Setenv("TIME_COMPARISON_MS", "40")
+
+	for _, op := range []string{"<", "<=", "==", "!=", ">=", ">", "not"} {
+		if got := getOperationTime(op); got != 40 {
+			t.Errorf("Expected %s time 40, got %d", op, got)
+		}
+	}
+	if got := getOperationTime("if"); got != 0 {
+		t.Errorf("Expected if to be resolved by the orchestrator without delay, got %d", got)
+	}
+}
+
+func TestGetOperationTime_Interval(t *testing.T) {
+	for _, op := range []string{"±", "interval"} {
+		if got := getOperationTime(op); got != 0 {
+			t.Errorf("Expected %s to build the interval without delay, got %d", op, got)
+		}
+	}
+}
+
+func TestSubmitTaskResult_VectorResult(t *testing.T) {
+	expr, err := CreateExpression("a = 1 + 2; [1, 2]")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for {
+		task, err := GetNextTask()
+		if err != nil {
+			break
+		}
+		if task.ExpressionID != expr.ID {
+			continue
+		}
+		if err := SubmitTaskResult(task.ID, 3); err != nil {
+			t.Fatalf("SubmitTaskResult failed: %v", err)
+		}
+	}
+
+	if expr.Status != models.StatusDone {
+		t.Errorf("Expected status done, got %s", expr.Status)
+	}
+	if expr.Result != nil {
+		t.Errorf("Expected no float result, got %v", *expr.Result)
+	}
+}
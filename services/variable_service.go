@@ -0,0 +1,93 @@
+package services
+
+import (
+	"calculator/evaluator"
+	"calculator/models"
+	"calculator/parser"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type VariableService struct {
+	db *DatabaseService
+}
+
+func NewVariableService(db *DatabaseService) *VariableService {
+	return &VariableService{db: db}
+}
+
+func (vs *VariableService) SetVariable(userID int, req *models.VariableRequest) (*models.Variable, error) {
+	if err := checkVariableName(req.Name); err != nil {
+		return nil, err
+	}
+	if req.Value != nil && req.Values != nil {
+		return nil, fmt.Errorf("variable must have either a value or a list of values")
+	}
+	if req.Values != nil {
+		if len(req.Values) == 0 {
+			return nil, fmt.Errorf("variable values must not be empty")
+		}
+		return vs.db.SetList(userID, req.Name, req.Values)
+	}
+	if req.Value == nil {
+		return nil, fmt.Errorf("variable value is required")
+	}
+
+	return vs.db.SetVariable(userID, req.Name, *req.Value)
+}
+
+func (vs *VariableService) ImportCSV(userID int, data io.Reader) ([]*models.Variable, error) {
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV must contain a header row and at least one data row")
+	}
+
+	header := records[0]
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if err := checkVariableName(name); err != nil {
+			return nil, fmt.Errorf("column %d: %v", i+1, err)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+		header[i] = name
+	}
+
+	columns := make([][]float64, len(header))
+	for row, record := range records[1:] {
+		for i, cell := range record {
+			value, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %q: invalid number %q", row+2, header[i], cell)
+			}
+			columns[i] = append(columns[i], value)
+		}
+	}
+
+	return vs.db.SetLists(userID, header, columns)
+}
+
+func (vs *VariableService) GetVariables(userID int) ([]*models.Variable, error) {
+	return vs.db.GetUserVariables(userID)
+}
+
+func checkVariableName(name string) error {
+	if !parser.IsIdentifier(name) {
+		return fmt.Errorf("variable name must be a valid identifier")
+	}
+	if evaluator.IsReserved(name) {
+		return fmt.Errorf("name %q is reserved", name)
+	}
+	return nil
+}
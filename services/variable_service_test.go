@@ -0,0 +1,120 @@
+package services
+
+import (
+	"calculator/models"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestVariableService_SetVariable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewVariableService(&DatabaseService{db: db})
+	value := 0.07
+
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "rate", value, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.SetVariable(1, &models.VariableRequest{Name: "rate", Value: &value}); err != nil {
+		t.Fatalf("Failed to set variable: %v", err)
+	}
+
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "prices", "[1,2.5]", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := service.SetVariable(1, &models.VariableRequest{Name: "prices", Values: []float64{1, 2.5}}); err != nil {
+		t.Fatalf("Failed to set list variable: %v", err)
+	}
+
+	invalid := []models.VariableRequest{
+		{Name: "", Value: &value},
+		{Name: "2x", Value: &value},
+		{Name: "a b", Value: &value},
+		{Name: "pi", Value: &value},
+		{Name: "sqrt", Value: &value},
+		{Name: "sum", Value: &value},
+		{Name: "rate", Value: nil},
+		{Name: "rate", Values: []float64{}},
+		{Name: "rate", Value: &value, Values: []float64{1}},
+		{Name: "mean", Values: []float64{1}},
+	}
+	for _, req := range invalid {
+		req := req
+		if _, err := service.SetVariable(1, &req); err == nil {
+			t.Errorf("Expected error for variable %q", req.Name)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestVariableService_ImportCSV(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewVariableService(&DatabaseService{db: db})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "height", "[1.7,1.82]", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "weight", "[65,-80]", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	variables, err := service.ImportCSV(1, strings.NewReader("height, weight\n1.7, 65\n1.82,-80\n"))
+	if err != nil {
+		t.Fatalf("Failed to import CSV: %v", err)
+	}
+	if len(variables) != 2 || variables[1].Name != "weight" || variables[1].Values[1] != -80 {
+		t.Errorf("Unexpected variables %+v", variables)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "height", "[1.7]", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO variables").
+		WithArgs(1, "weight", "[65]", sqlmock.AnyArg()).
+		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	if _, err := service.ImportCSV(1, strings.NewReader("height,weight\n1.7,65\n")); err == nil {
+		t.Error("Expected error when a column cannot be saved")
+	}
+
+	invalid := []string{
+		"",
+		"x,y\n",
+		"x,y\n1,2,3\n",
+		"x,y\n1,abc\n",
+		"x,x\n1,2\n",
+		"2x\n1\n",
+		"pi\n1\n",
+		"x,\"y\n1,2\n",
+	}
+	for _, data := range invalid {
+		if _, err := service.ImportCSV(1, strings.NewReader(data)); err == nil {
+			t.Errorf("Expected error for CSV %q", data)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package symbolic
+
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"fmt"
+	"strconv"
+)
+
+func Diff(node parser.Node, variable string) (parser.Node, error) {
+	derivative, err := derive(node, variable)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(derivative)
+}
+
+func derive(node parser.Node, x string) (parser.Node, error) {
+	if !dependsOn(node, x) {
+		return number(0), nil
+	}
+
+	switch n := node.(type) {
+	case *parser.Ident:
+		return number(1), nil
+	case *parser.UnaryOp:
+		if n.Op == "!" {
+			return number(0), nil
+		}
+		du, err := derive(n.Operand, x)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.UnaryOp{Op: n.Op, Operand: du}, nil
+	case *parser.BinaryOp:
+		return deriveBinary(n, x)
+	case *parser.Call:
+		return deriveCall(n, x)
+	}
+	return nil, fmt.Errorf("неизвестный узел выражения %T", node)
+}
+
+func deriveBinary(n *parser.BinaryOp, x string) (parser.Node, error) {
+	if evaluator.IsComparison(n.Op) || n.Op == "&&" || n.Op == "||" {
+		return number(0), nil
+	}
+
+	u, v := n.Left, n.Right
+	du, err := derive(u, x)
+	if err != nil {
+		return nil, err
+	}
+	dv, err := derive(v, x)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case "+", "-":
+		return binary(n.Op, du, dv), nil
+	case "*":
+		return binary("+", binary("*", du, v), binary("*", u, dv)), nil
+	case "/":
+		return binary("/", binary("-", binary("*", du, v), binary("*", u, dv)), binary("^", v, number(2))), nil
+	case "^":
+		if !dependsOn(v, x) {
+			return binary("*", binary("*", v, binary("^", u, binary("-", v, number(1)))), du), nil
+		}
+		if !dependsOn(u, x) {
+			return binary("*", binary("*", n, call("ln", u)), dv), nil
+		}
+		return binary("*", n, binary("+", binary("*", dv, call("ln", u)), binary("/", binary("*", v, du), u))), nil
+	}
+	return nil, fmt.Errorf("операция %s не дифференцируема", n.Op)
+}
+
+func deriveCall(n *parser.Call, x string) (parser.Node, error) {
+	switch n.Name {
+	case OpDiff:
+		if len(n.Args) != 2 {
+			return nil, fmt.Errorf("функция diff ожидает 2 аргумента, получено %d", len(n.Args))
+		}
+		variable, ok := n.Args[1].(*parser.Ident)
+		if !ok {
+			return nil, fmt.Errorf("второй аргумент diff должен быть именем переменной")
+		}
+		inner, err := derive(n.Args[0], variable.Name)
+		if err != nil {
+			return nil, err
+		}
+		return derive(inner, x)
+	case evaluator.RangeSum, evaluator.RangeProduct:
+		if index, ok := evaluator.RangeIndex(n); ok {
+			return deriveRange(n, index, x)
+		}
+		if n.Name == evaluator.RangeProduct && len(n.Args) > 0 {
+			product := n.Args[0]
+			for _, arg := range n.Args[1:] {
+				product = binary("*", product, arg)
+			}
+			return derive(product, x)
+		}
+		return deriveSum(n, x)
+	case evaluator.Integrate, evaluator.Solve:
+		return deriveNumeric(n, x)
+	case evaluator.Conditional:
+		if len(n.Args) != 3 {
+			return nil, fmt.Errorf("функция if ожидает 3 аргумента, получено %d", len(n.Args))
+		}
+		then, err := derive(n.Args[1], x)
+		if err != nil {
+			return nil, err
+		}
+		otherwise, err := derive(n.Args[2], x)
+		if err != nil {
+			return nil, err
+		}
+		return call(n.Name, n.Args[0], then, otherwise), nil
+	case evaluator.ConvertCall:
+		if len(n.Args) != 2 {
+			return nil, fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", n.Name, len(n.Args))
+		}
+		return derive(n.Args[0], x)
+	case evaluator.ComplexCall:
+		if len(n.Args) != 2 {
+			return nil, fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", n.Name, len(n.Args))
+		}
+		re, err := derive(n.Args[0], x)
+		if err != nil {
+			return nil, err
+		}
+		im, err := derive(n.Args[1], x)
+		if err != nil {
+			return nil, err
+		}
+		return call(n.Name, re, im), nil
+	case evaluator.VectorCall, evaluator.MatrixCall:
+		elements := make([]parser.Node, len(n.Args))
+		for i, arg := range n.Args {
+			element, err := derive(arg, x)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return call(n.Name, elements...), nil
+	case "mean":
+		if len(n.Args) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", n.Name, len(n.Args))
+		}
+		elements, err := derive(n.Args[0], x)
+		if err != nil {
+			return nil, err
+		}
+		return call(n.Name, elements), nil
+	case "avg":
+		total, err := deriveSum(n, x)
+		if err != nil {
+			return nil, err
+		}
+		return binary("/", total, number(len(n.Args))), nil
+	}
+
+	if !evaluator.IsFunction(n.Name) {
+		return nil, fmt.Errorf("функция %s не дифференцируема", n.Name)
+	}
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", n.Name, len(n.Args))
+	}
+	u := n.Args[0]
+	du, err := derive(u, x)
+	if err != nil {
+		return nil, err
+	}
+
+	var outer parser.Node
+	switch n.Name {
+	case "sqrt":
+		outer = binary("/", number(1), binary("*", number(2), n))
+	case "abs":
+		outer = binary("/", u, n)
+	case "sin":
+		outer = call("cos", u)
+	case "cos":
+		outer = &parser.UnaryOp{Op: "-", Operand: call("sin", u)}
+	case "tan":
+		outer = binary("/", number(1), binary("^", call("cos", u), number(2)))
+	case "ln":
+		outer = binary("/", number(1), u)
+	case "log10":
+		outer = binary("/", number(1), binary("*", u, call("ln", number(10))))
+	case "exp":
+		outer = n
+	case "re", "im", "conj":
+		return call(n.Name, du), nil
+	default:
+		return number(0), nil
+	}
+	return binary("*", outer, du), nil
+}
+
+func deriveSum(n *parser.Call, x string) (parser.Node, error) {
+	var total parser.Node = number(0)
+	for _, arg := range n.Args {
+		da, err := derive(arg, x)
+		if err != nil {
+			return nil, err
+		}
+		total = binary("+", total, da)
+	}
+	return total, nil
+}
+
+func deriveRange(n *parser.Call, index, x string) (parser.Node, error) {
+	from, to, body := n.Args[1], n.Args[2], n.Args[3]
+	if dependsOn(from, x) || dependsOn(to, x) || n.Name == evaluator.RangeProduct {
+		return nil, fmt.Errorf("функция %s не дифференцируема", n.Name)
+	}
+	if index == x {
+		return number(0), nil
+	}
+	db, err := derive(body, x)
+	if err != nil {
+		return nil, err
+	}
+	if !dependsOn(db, index) {
+		return binary("*", rangeCount(from, to), db), nil
+	}
+	return call(n.Name, n.Args[0], from, to, db), nil
+}
+
+func deriveNumeric(n *parser.Call, x string) (parser.Node, error) {
+	nc, err := evaluator.ParseNumeric(n)
+	if err != nil {
+		return nil, err
+	}
+	var df parser.Node
+	if nc.Variable != x && dependsOn(nc.Body, x) {
+		if df, err = derive(nc.Body, x); err != nil {
+			return nil, err
+		}
+	}
+
+	if nc.Name == evaluator.Solve {
+		if df == nil {
+			return number(0), nil
+		}
+		dt, err := derive(nc.Body, nc.Variable)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.UnaryOp{Op: "-", Operand: binary("/", replace(df, nc.Variable, n), replace(dt, nc.Variable, n))}, nil
+	}
+
+	da, err := derive(nc.From, x)
+	if err != nil {
+		return nil, err
+	}
+	db, err := derive(nc.To, x)
+	if err != nil {
+		return nil, err
+	}
+	result := binary("-", binary("*", replace(nc.Body, nc.Variable, nc.To), db), binary("*", replace(nc.Body, nc.Variable, nc.From), da))
+	if df != nil {
+		args := append([]parser.Node{df}, n.Args[1:]...)
+		result = binary("+", call(n.Name, args...), result)
+	}
+	return result, nil
+}
+
+func replace(node parser.Node, name string, with parser.Node) parser.Node {
+	switch n := node.(type) {
+	case *parser.Ident:
+		if n.Name == name {
+			return with
+		}
+	case *parser.UnaryOp:
+		return &parser.UnaryOp{Op: n.Op, Operand: replace(n.Operand, name, with), At: n.At}
+	case *parser.BinaryOp:
+		return &parser.BinaryOp{Op: n.Op, Left: replace(n.Left, name, with), Right: replace(n.Right, name, with), At: n.At}
+	case *parser.Call:
+		args := make([]parser.Node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = replace(arg, name, with)
+		}
+		return &parser.Call{Name: n.Name, Args: args, At: n.At}
+	}
+	return node
+}
+
+func rangeCount(from, to parser.Node) parser.Node {
+	return call("max", binary("+", binary("-", to, from), number(1)), number(0))
+}
+
+func dependsOn(node parser.Node, x string) bool {
+	found := false
+	parser.Inspect(node, func(n parser.Node) bool {
+		if ident, ok := n.(*parser.Ident); ok && ident.Name == x {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func binary(op string, left, right parser.Node) parser.Node {
+	return &parser.BinaryOp{Op: op, Left: left, Right: right}
+}
+
+func call(name string, args ...parser.Node) parser.Node {
+	return &parser.Call{Name: name, Args: args}
+}
+
+func number(n int) parser.Node {
+	return &parser.Number{Value: float64(n), Literal: strconv.Itoa(n), Integer: true}
+}
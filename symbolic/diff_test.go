@@ -0,0 +1,88 @@
+package symbolic
+
+import (
+	"calculator/parser"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"5", "0"},
+		{"y", "0"},
+		{"x", "1"},
+		{"3*x^2 + 2*x + 1", "6*x + 2"},
+		{"x*y", "y"},
+		{"1/x", "-1/x^2"},
+		{"sqrt(x)", "1/(2*sqrt(x))"},
+		{"sin(x)*cos(x)", "cos(x)^2 - sin(x)^2"},
+		{"exp(2*x)", "2*exp(2*x)"},
+		{"ln(x^2)", "2/x"},
+		{"2^x", "2^x*ln(2)"},
+		{"x^x", "(ln(x) + x/x)*x^x"},
+		{"if(x>0, x^2, -x)", "if(x > 0, 2*x, -1)"},
+		{"avg(x, 3*x)", "2"},
+		{"floor(x)", "0"},
+		{"diff(x^3, x)", "6*x"},
+		{"prod(x, y, x)", "2*x*y"},
+		{"sum(i, 1, 10, i*x^2)", "sum(i, 1, 10, 2*i*x)"},
+		{"sum(i, 1, 10, x^2 + i)", "20*x"},
+		{"sum(x, 1, 10, x^2)", "0"},
+		{"integrate(x^2, 0, 1)", "0"},
+		{"complex(x^2, 3*x)", "complex(2*x, 3)"},
+		{"x^2*(5 km/h) to mph", "2*(5 km/h)*x"},
+		{"re(x^2) + x*2i", "2i + re(2*x)"},
+		{"integrate(t^2, t, 0, x)", "x^2"},
+		{"integrate(t*x, t, 0, x^2)", "2*x^4 + integrate(t, t, 0, x^2)"},
+		{"solve(t^2 - x, t, 0, 10)", "1/(2*solve(t^2 - x, t, 0, 10))"},
+		{"[x^2, x, 1]", "[2*x, 1, 0]"},
+		{"[[x, x^2], [1, y*x]]", "[[1, 2*x], [0, y]]"},
+		{"mean([x^2, 3*x, 1])", "mean([2*x, 3, 0])"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := parser.Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := Diff(node, "x")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := parser.Format(result); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiff_Errors(t *testing.T) {
+	tests := []string{
+		"x % 2",
+		"x // 2",
+		"x & 1",
+		"max(x, 1)",
+		"sum(i, 1, x, i)",
+		"prod(i, 1, 10, i*x)",
+		"integrate(x, 0)",
+		"integrate(t % x, t, 0, 1)",
+		"x!",
+		"det([[x, 1], [2, x]])",
+		"stddev([x, 1, 2])",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			node, err := parser.Parse(expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := Diff(node, "x"); err == nil {
+				t.Errorf("Expected error for %s", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package symbolic
+
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	OpSimplify = "simplify"
+	OpExpand   = "expand"
+	OpDiff     = "diff"
+)
+
+func Simplify(node parser.Node) (parser.Node, error) {
+	s, err := normalize(node, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.node(), nil
+}
+
+func Expand(node parser.Node) (parser.Node, error) {
+	s, err := normalize(node, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.node(), nil
+}
+
+func SimplifyProgram(program *parser.Program) (*parser.Program, error) {
+	simplified := &parser.Program{Statements: make([]*parser.Statement, len(program.Statements))}
+	for i, stmt := range program.Statements {
+		value, err := Simplify(stmt.Value)
+		if err != nil {
+			return nil, err
+		}
+		simplified.Statements[i] = &parser.Statement{Name: stmt.Name, Value: value, At: stmt.At}
+	}
+	return simplified, nil
+}
+
+func normalize(node parser.Node, expand bool) (*sum, error) {
+	switch n := node.(type) {
+	case *parser.Number:
+		value, err := literal(n)
+		if err != nil {
+			return nil, err
+		}
+		return constant(value), nil
+	case *parser.Ident:
+		return atom(&parser.Ident{Name: n.Name}), nil
+	case *parser.UnaryOp:
+		operand, err := normalize(n.Operand, expand)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "+":
+			return operand, nil
+		case "-":
+			return neg(operand), nil
+		}
+		return fold("not", []*sum{operand}, func(args []parser.Node) parser.Node {
+			return &parser.UnaryOp{Op: n.Op, Operand: args[0]}
+		})
+	case *parser.BinaryOp:
+		return normalizeBinary(n, expand)
+	case *parser.Call:
+		return normalizeCall(n, expand)
+	}
+	return nil, fmt.Errorf("неизвестный узел выражения %T", node)
+}
+
+func normalizeBinary(n *parser.BinaryOp, expand bool) (*sum, error) {
+	left, err := normalize(n.Left, expand)
+	if err != nil {
+		return nil, err
+	}
+	right, err := normalize(n.Right, expand)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case "+":
+		return add(left, right), nil
+	case "-":
+		return add(left, neg(right)), nil
+	case "*":
+		return mul(left, right, expand)
+	case "/":
+		inverse, err := pow(right, big.NewRat(-1, 1), expand)
+		if err != nil {
+			return nil, err
+		}
+		return mul(left, inverse, expand)
+	case "^":
+		if exp, ok := right.value(); ok {
+			return pow(left, exp, expand)
+		}
+		return atom(&parser.BinaryOp{Op: n.Op, Left: left.node(), Right: right.node()}), nil
+	case evaluator.PlusMinus:
+		return atom(&parser.BinaryOp{Op: n.Op, Left: left.node(), Right: right.node()}), nil
+	case "&&", "||":
+		if value, ok := left.value(); ok {
+			if (value.Sign() != 0) == (n.Op == "||") {
+				return constant(big.NewRat(boolInt(n.Op == "||"), 1)), nil
+			}
+			return fold("!=", []*sum{right, &sum{}}, func(args []parser.Node) parser.Node {
+				return &parser.BinaryOp{Op: "!=", Left: args[0], Right: args[1]}
+			})
+		}
+	}
+	return fold(n.Op, []*sum{left, right}, func(args []parser.Node) parser.Node {
+		return &parser.BinaryOp{Op: n.Op, Left: args[0], Right: args[1]}
+	})
+}
+
+func normalizeQuantity(n *parser.Call, expand bool) (*sum, error) {
+	if n.Name == evaluator.UnitCall {
+		return atom(n), nil
+	}
+	value, err := normalize(n.Args[0], expand)
+	if err != nil {
+		return nil, err
+	}
+	return atom(&parser.Call{Name: n.Name, Args: []parser.Node{value.node(), n.Args[1]}}), nil
+}
+
+func normalizeCall(n *parser.Call, expand bool) (*sum, error) {
+	if n.Name == OpDiff {
+		if len(n.Args) != 2 {
+			return nil, fmt.Errorf("функция diff ожидает 2 аргумента, получено %d", len(n.Args))
+		}
+		variable, ok := n.Args[1].(*parser.Ident)
+		if !ok {
+			return nil, fmt.Errorf("второй аргумент diff должен быть именем переменной")
+		}
+		derivative, err := derive(n.Args[0], variable.Name)
+		if err != nil {
+			return nil, err
+		}
+		return normalize(derivative, expand)
+	}
+
+	if index, ok := evaluator.RangeIndex(n); ok {
+		return normalizeRange(n, index, expand)
+	}
+	if evaluator.IsNumeric(n.Name) {
+		return normalizeNumeric(n, expand)
+	}
+	if (n.Name == evaluator.UnitCall || n.Name == evaluator.ConvertCall) && len(n.Args) == 2 {
+		return normalizeQuantity(n, expand)
+	}
+
+	args := make([]*sum, len(n.Args))
+	for i, arg := range n.Args {
+		normalized, err := normalize(arg, expand)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = normalized
+	}
+	call := func(args []parser.Node) parser.Node {
+		return &parser.Call{Name: n.Name, Args: args}
+	}
+
+	switch {
+	case n.Name == evaluator.Conditional:
+		if len(args) != 3 {
+			return nil, fmt.Errorf("функция if ожидает 3 аргумента, получено %d", len(args))
+		}
+		if cond, ok := args[0].value(); ok {
+			if cond.Sign() != 0 {
+				return args[1], nil
+			}
+			return args[2], nil
+		}
+		return atom(call(nodes(args))), nil
+	case n.Name == "sum" || n.Name == "avg":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("функция %s ожидает хотя бы один аргумент", n.Name)
+		}
+		total := &sum{}
+		for _, arg := range args {
+			total = add(total, arg)
+		}
+		if n.Name == "avg" {
+			total = scale(total, big.NewRat(1, int64(len(args))))
+		}
+		return total, nil
+	case n.Name == evaluator.RangeProduct && len(args) > 0:
+		product := args[0]
+		for _, arg := range args[1:] {
+			var err error
+			if product, err = mul(product, arg, expand); err != nil {
+				return nil, err
+			}
+		}
+		return product, nil
+	case n.Name == evaluator.IntervalCall || evaluator.IsMatrixOperation(n.Name) || evaluator.IsStatistic(n.Name):
+		return atom(call(nodes(args))), nil
+	case n.Name == evaluator.ComplexCall:
+		if len(args) != 2 {
+			return nil, fmt.Errorf("функция %s ожидает 2 аргумента, получено %d", n.Name, len(args))
+		}
+		if im, ok := args[1].value(); ok && im.Sign() == 0 {
+			return args[0], nil
+		}
+		if _, ok := n.Args[0].(*parser.Number); ok {
+			if _, ok := n.Args[1].(*parser.Number); ok {
+				return atom(n), nil
+			}
+		}
+		return atom(call(nodes(args))), nil
+	case n.Name == "sqrt" && len(args) == 1:
+		return pow(args[0], big.NewRat(1, 2), expand)
+	case evaluator.IsAggregate(n.Name):
+		if len(args) == 0 {
+			return nil, fmt.Errorf("функция %s ожидает хотя бы один аргумент", n.Name)
+		}
+		values := make([]*big.Rat, len(args))
+		for i, arg := range args {
+			value, ok := arg.value()
+			if !ok {
+				return atom(call(nodes(args))), nil
+			}
+			values[i] = value
+		}
+		op, err := evaluator.AggregateOperation(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		result, err := evaluator.Reduce(values, func(left, right *big.Rat) (*big.Rat, error) {
+			return evaluator.ApplyRat(op, left, right)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return constant(result), nil
+	case evaluator.IsNumberTheory(n.Name):
+		return foldNumberTheory(n.Name, args, call)
+	case evaluator.IsFunction(n.Name):
+		if len(args) != 1 {
+			return nil, fmt.Errorf("функция %s ожидает 1 аргумент, получено %d", n.Name, len(args))
+		}
+		return fold(n.Name, args, call)
+	}
+	return nil, fmt.Errorf("неизвестная функция %q", n.Name)
+}
+
+func normalizeRange(n *parser.Call, index string, expand bool) (*sum, error) {
+	bounds := make([]parser.Node, 2)
+	for i, arg := range n.Args[1:3] {
+		normalized, err := normalize(arg, expand)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = normalized.node()
+	}
+	body, err := normalize(n.Args[3], expand)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dependsOn(body.node(), index) {
+		count := rangeCount(bounds[0], bounds[1])
+		if n.Name == evaluator.RangeProduct {
+			return normalize(binary("^", body.node(), count), expand)
+		}
+		return normalize(binary("*", count, body.node()), expand)
+	}
+	return atom(call(n.Name, n.Args[0], bounds[0], bounds[1], body.node())), nil
+}
+
+func normalizeNumeric(n *parser.Call, expand bool) (*sum, error) {
+	if _, err := evaluator.ParseNumeric(n); err != nil {
+		return nil, err
+	}
+	args := make([]parser.Node, len(n.Args))
+	for i, arg := range n.Args {
+		if i == 1 && len(n.Args) == 4 {
+			args[i] = arg
+			continue
+		}
+		normalized, err := normalize(arg, expand)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = normalized.node()
+	}
+	return atom(call(n.Name, args...)), nil
+}
+
+func fold(op string, args []*sum, build func([]parser.Node) parser.Node) (*sum, error) {
+	values := make([]*big.Rat, len(args))
+	for i, arg := range args {
+		value, ok := arg.value()
+		if !ok {
+			return atom(build(nodes(args))), nil
+		}
+		values[i] = value
+	}
+
+	if result, err := evaluator.ApplyRat(op, values...); err == nil {
+		return constant(result), nil
+	} else if !evaluator.IsFunction(op) {
+		return nil, err
+	}
+
+	approximation, _ := values[0].Float64()
+	result, err := evaluator.Apply(op, approximation)
+	if err != nil {
+		return nil, err
+	}
+	if result != math.Trunc(result) {
+		return atom(build(nodes(args))), nil
+	}
+	return constant(new(big.Rat).SetFloat64(result)), nil
+}
+
+func foldNumberTheory(op string, args []*sum, build func([]parser.Node) parser.Node) (*sum, error) {
+	values := make([]evaluator.Value, len(args))
+	for i, arg := range args {
+		value, ok := arg.value()
+		if !ok {
+			return atom(build(nodes(args))), nil
+		}
+		values[i] = evaluator.Rat{Rat: value}
+	}
+	result, err := evaluator.ApplyValue(op, values...)
+	if err != nil {
+		return nil, err
+	}
+	if exact, ok := result.(evaluator.Rat); ok {
+		return constant(exact.Rat), nil
+	}
+	return atom(build(nodes(args))), nil
+}
+
+func nodes(args []*sum) []parser.Node {
+	result := make([]parser.Node, len(args))
+	for i, arg := range args {
+		result[i] = arg.node()
+	}
+	return result
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package symbolic
+
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"x+x", "2*x"},
+		{"2*(x+1)", "2*x + 2"},
+		{"x-(x+1)", "-1"},
+		{"3-x", "-x + 3"},
+		{"x*y*x", "x^2*y"},
+		{"x/x", "x/x"},
+		{"x/x^2", "1/x"},
+		{"x^2/x", "x^2/x"},
+		{"(x+1)/(x+1)", "(x + 1)/(x + 1)"},
+		{"(x+1)^2", "(x + 1)^2"},
+		{"0.1+0.2", "3/10"},
+		{"1/3*x + x/6", "x/2"},
+		{"sqrt(8)", "sqrt(8)"},
+		{"sqrt(16)*x", "4*x"},
+		{"sqrt(x)^2", "x"},
+		{"sqrt(x^2)", "abs(x)"},
+		{"sqrt(4*x^2*y^4)", "2*abs(x)*y^2"},
+		{"(x^2)^(3/2)", "abs(x)^3"},
+		{"(x^3)^(1/3)", "(x^3)^(1/3)"},
+		{"(x*y)^(1/2)", "sqrt(x*y)"},
+		{"(-8)^(1/3)", "(-8)^(1/3)"},
+		{"8^(1/3)", "2"},
+		{"(1/x)^-1", "1/(1/x)"},
+		{"(1/x)^0", "(1/x)^0"},
+		{"2^10", "1024"},
+		{"x^y*x^y", "(x^y)^2"},
+		{"x % 2", "x%2"},
+		{"sum(x, x, 1) - 1", "2*x"},
+		{"avg(x, 3*x)", "2*x"},
+		{"max(1, 5, 3) * x", "5*x"},
+		{"max(x, 1)", "max(x, 1)"},
+		{"if(2 > 1, x, y)", "x"},
+		{"if(c, x+x, y)", "if(c, 2*x, y)"},
+		{"0 && x", "0"},
+		{"1 || x", "1"},
+		{"abs(-4) + x", "x + 4"},
+		{"sin(x) - sin(x)", "0"},
+		{"diff(x^3, x)", "3*x^2"},
+		{"prod(x, y, x)", "x^2*y"},
+		{"sum(i, 1, 10, i*(x+x))", "sum(i, 1, 10, 2*i*x)"},
+		{"sum(i, 1, 10, x)", "10*x"},
+		{"sum(i, 1, n, i - i + 2)", "2*max(n, 0)"},
+		{"prod(i, 1, 3, x + i - i)", "x^3"},
+		{"sum(i, 5, 1, x + i - i)", "0"},
+		{"integrate(x*(1+1), 0, y + y)", "integrate(2*x, 0, 2*y)"},
+		{"solve(t - 2 - 2, t, 0, 10)", "solve(t - 4, t, 0, 10)"},
+		{"(x+x)±0.5", "(2*x)±(1/2)"},
+		{"2*(x ± 1)", "2*x±1"},
+		{"complex(x+x, 0)", "2*x"},
+		{"x*2.5i + 0", "2.5i*x"},
+		{"[x+x, 3]", "[2*x, 3]"},
+		{"(x+x)*(3 m) + 0", "2*(3 m)*x"},
+		{"(x + x to km) to mi", "2*x to km to mi"},
+		{"5!*x - binom(4, 2)", "120*x - 6"},
+		{"gcd(x+x, 12) + lcm(4, 6)", "gcd(2*x, 12) + 12"},
+		{"(x+x)!", "(2*x)!"},
+		{"factor(360)", "factor(360)"},
+		{"[x+x, 3, 0*y]", "[2*x, 3, 0]"},
+		{"det([[x, 1], [2, x-x]])", "det([[x, 1], [2, 0]])"},
+		{"percentile([x+x, 3, 1], 2*25)", "percentile([2*x, 3, 1], 50)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := parser.Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := Simplify(node)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := parser.Format(result); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"(x+1)^2", "x^2 + 2*x + 1"},
+		{"(x+1)*(x-1)", "x^2 - 1"},
+		{"(a+b)^3", "a^3 + 3*a^2*b + 3*a*b^2 + b^3"},
+		{"x*(y+z) - x*y", "x*z"},
+		{"(x+1)/(x+1)", "x/(x + 1) + 1/(x + 1)"},
+		{"(x+1)^(1/2)", "sqrt(x + 1)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			node, err := parser.Parse(test.expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result, err := Expand(node)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := parser.Format(result); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSimplify_PreservesValues(t *testing.T) {
+	tests := []string{
+		"x/x",
+		"(x+1)/(x+1)",
+		"x^2/x",
+		"x/x^2",
+		"sqrt(x^2)",
+		"(x^2)^(3/2)",
+		"sqrt(x)^2",
+		"(x^3)^(1/3)",
+		"(-8)^(1/3) + x",
+		"(1/x)^-1",
+		"(1/x)^0",
+	}
+
+	for _, expr := range tests {
+		node, err := parser.Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", expr, err)
+		}
+		simplified, err := Simplify(node)
+		if err != nil {
+			t.Fatalf("Simplify(%s) failed: %v", expr, err)
+		}
+		for _, x := range []float64{-2, -1, 0, 0.5, 3} {
+			scope := map[string]evaluator.Value{"x": evaluator.Float(x)}
+			want, wantErr := evaluator.Evaluate(node, scope, "")
+			got, gotErr := evaluator.Evaluate(simplified, scope, "")
+			if (wantErr == nil) != (gotErr == nil) {
+				t.Errorf("%s at x=%v: expected error %v, got %v from %s", expr, x, wantErr, gotErr, parser.Format(simplified))
+				continue
+			}
+			if wantErr == nil && !sameValue(want, got) {
+				t.Errorf("%s at x=%v: expected %s, got %s from %s", expr, x, want, got, parser.Format(simplified))
+			}
+		}
+	}
+}
+
+func sameValue(a, b evaluator.Value) bool {
+	return cmplx.Abs(complexOf(a)-complexOf(b)) < 1e-9
+}
+
+func complexOf(value evaluator.Value) complex128 {
+	if c, ok := value.(evaluator.Complex); ok {
+		return complex128(c)
+	}
+	return complex(value.Float(), 0)
+}
+
+func TestSimplify_Errors(t *testing.T) {
+	tests := []string{
+		"x/0",
+		"1/(x-x)",
+		"unknown(x)",
+		"diff(x, 2)",
+		"diff(x)",
+		"if(x, 1)",
+		"(-1)!",
+		"modpow(3, 2, 0)",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			node, err := parser.Parse(expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, err := Simplify(node); err == nil {
+				t.Errorf("Expected error for %s", expr)
+			}
+		})
+	}
+}
+
+func TestSimplifyProgram(t *testing.T) {
+	program, err := parser.ParseProgram("y = x + x; y*y/y")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	simplified, err := SimplifyProgram(program)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "y = 2*x; y^2/y"
+	if got := parser.FormatProgram(simplified); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,428 @@
+package symbolic
+
+import (
+	"calculator/evaluator"
+	"calculator/parser"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+const (
+	maxExpandPower = 64
+	maxTerms       = 10000
+)
+
+type factor struct {
+	base parser.Node
+	key  string
+	exp  *big.Rat
+}
+
+type term struct {
+	coef    *big.Rat
+	factors []factor
+}
+
+type sum struct {
+	terms []*term
+}
+
+func constant(r *big.Rat) *sum {
+	if r.Sign() == 0 {
+		return &sum{}
+	}
+	return &sum{terms: []*term{{coef: new(big.Rat).Set(r)}}}
+}
+
+func atom(node parser.Node) *sum {
+	return &sum{terms: []*term{{coef: big.NewRat(1, 1), factors: []factor{newFactor(node, big.NewRat(1, 1))}}}}
+}
+
+func newFactor(base parser.Node, exp *big.Rat) factor {
+	return factor{base: base, key: parser.Format(base), exp: exp}
+}
+
+func (s *sum) value() (*big.Rat, bool) {
+	switch {
+	case len(s.terms) == 0:
+		return new(big.Rat), true
+	case len(s.terms) == 1 && len(s.terms[0].factors) == 0:
+		return s.terms[0].coef, true
+	}
+	return nil, false
+}
+
+func (t *term) key() string {
+	parts := make([]string, len(t.factors))
+	for i, f := range t.factors {
+		parts[i] = f.key + "^" + f.exp.RatString()
+	}
+	return strings.Join(parts, "*")
+}
+
+func (t *term) degree() *big.Rat {
+	degree := new(big.Rat)
+	for _, f := range t.factors {
+		degree.Add(degree, f.exp)
+	}
+	return degree
+}
+
+func add(a, b *sum) *sum {
+	result := &sum{}
+	index := make(map[string]int, len(a.terms)+len(b.terms))
+	for _, t := range append(append([]*term(nil), a.terms...), b.terms...) {
+		key := t.key()
+		if i, ok := index[key]; ok {
+			merged := *result.terms[i]
+			merged.coef = new(big.Rat).Add(merged.coef, t.coef)
+			result.terms[i] = &merged
+			continue
+		}
+		index[key] = len(result.terms)
+		result.terms = append(result.terms, t)
+	}
+
+	terms := result.terms[:0]
+	for _, t := range result.terms {
+		if t.coef.Sign() != 0 {
+			terms = append(terms, t)
+		}
+	}
+	result.terms = terms
+	return result
+}
+
+func scale(s *sum, r *big.Rat) *sum {
+	if r.Sign() == 0 {
+		return &sum{}
+	}
+	result := &sum{terms: make([]*term, len(s.terms))}
+	for i, t := range s.terms {
+		result.terms[i] = &term{coef: new(big.Rat).Mul(t.coef, r), factors: t.factors}
+	}
+	return result
+}
+
+func neg(s *sum) *sum {
+	return scale(s, big.NewRat(-1, 1))
+}
+
+func mul(a, b *sum, expand bool) (*sum, error) {
+	if c, ok := a.value(); ok {
+		return scale(b, c), nil
+	}
+	if c, ok := b.value(); ok {
+		return scale(a, c), nil
+	}
+	if !expand {
+		return &sum{terms: []*term{mulTerms(a.term(), b.term())}}, nil
+	}
+	if len(a.terms)*len(b.terms) > maxTerms {
+		return nil, fmt.Errorf("слишком много слагаемых после раскрытия скобок")
+	}
+
+	result := &sum{}
+	for _, left := range a.terms {
+		for _, right := range b.terms {
+			result = add(result, &sum{terms: []*term{mulTerms(left, right)}})
+		}
+	}
+	return result, nil
+}
+
+func (s *sum) term() *term {
+	if len(s.terms) == 1 {
+		return s.terms[0]
+	}
+	return &term{coef: big.NewRat(1, 1), factors: []factor{newFactor(s.node(), big.NewRat(1, 1))}}
+}
+
+func mulTerms(a, b *term) *term {
+	result := &term{coef: new(big.Rat).Mul(a.coef, b.coef)}
+	type exponents struct {
+		base               parser.Node
+		positive, negative *big.Rat
+		zero               bool
+	}
+	var keys []string
+	index := make(map[string]*exponents, len(a.factors)+len(b.factors))
+	for _, f := range append(append([]factor(nil), a.factors...), b.factors...) {
+		e, ok := index[f.key]
+		if !ok {
+			e = &exponents{base: f.base, positive: new(big.Rat), negative: new(big.Rat)}
+			index[f.key] = e
+			keys = append(keys, f.key)
+		}
+		switch f.exp.Sign() {
+		case 1:
+			e.positive.Add(e.positive, f.exp)
+		case -1:
+			e.negative.Add(e.negative, f.exp)
+		default:
+			e.zero = true
+		}
+	}
+
+	var factors []factor
+	for _, key := range keys {
+		e := index[key]
+		if total := new(big.Rat).Add(e.positive, e.negative); total.Sign() < 0 {
+			factors = appendFactor(result, factors, e.base, key, total)
+			continue
+		}
+		if e.positive.Sign() > 0 {
+			factors = appendFactor(result, factors, e.base, key, e.positive)
+		}
+		if e.negative.Sign() < 0 {
+			factors = appendFactor(result, factors, e.base, key, e.negative)
+		}
+		if e.zero && e.positive.Sign() == 0 && e.negative.Sign() == 0 {
+			factors = append(factors, factor{base: e.base, key: key, exp: new(big.Rat)})
+		}
+	}
+	sort.SliceStable(factors, func(i, j int) bool { return factors[i].key < factors[j].key })
+	result.factors = factors
+	return result
+}
+
+func appendFactor(t *term, factors []factor, base parser.Node, key string, exp *big.Rat) []factor {
+	if number, ok := base.(*parser.Number); ok && exp.IsInt() {
+		if value, err := literal(number); err == nil {
+			if power, err := evaluator.ApplyRat("^", value, exp); err == nil {
+				t.coef.Mul(t.coef, power)
+				return factors
+			}
+		}
+	}
+	return append(factors, factor{base: base, key: key, exp: exp})
+}
+
+func pow(s *sum, exp *big.Rat, expand bool) (*sum, error) {
+	if exp.Sign() == 0 && !s.inverted() {
+		return constant(big.NewRat(1, 1)), nil
+	}
+	if len(s.terms) == 0 {
+		if exp.Sign() < 0 {
+			return nil, fmt.Errorf("деление на ноль")
+		}
+		return &sum{}, nil
+	}
+
+	if len(s.terms) > 1 {
+		if expand && exp.IsInt() && exp.Sign() > 0 && exp.Num().Int64() <= maxExpandPower {
+			result := constant(big.NewRat(1, 1))
+			for i := int64(0); i < exp.Num().Int64(); i++ {
+				var err error
+				if result, err = mul(result, s, true); err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		}
+		return power(s, exp), nil
+	}
+
+	t := s.terms[0]
+	if exp.IsInt() {
+		if exp.Sign() <= 0 && s.inverted() {
+			return power(s, exp), nil
+		}
+		result := &term{coef: big.NewRat(1, 1), factors: []factor{newFactor(numberNode(t.coef), exp)}}
+		if coef, ok := powRat(t.coef, exp); ok {
+			result = &term{coef: coef}
+		}
+		for _, f := range t.factors {
+			result.factors = append(result.factors, factor{base: f.base, key: f.key, exp: new(big.Rat).Mul(f.exp, exp)})
+		}
+		return &sum{terms: []*term{mulTerms(result, &term{coef: big.NewRat(1, 1)})}}, nil
+	}
+
+	coef, ok := powRat(t.coef, exp)
+	if !ok || t.coef.Sign() < 0 {
+		return power(s, exp), nil
+	}
+	if len(t.factors) == 0 {
+		return constant(coef), nil
+	}
+	result := &term{coef: coef}
+	if len(t.factors) == 1 && t.factors[0].exp.Sign() > 0 && t.factors[0].exp.Cmp(big.NewRat(1, 1)) <= 0 {
+		f := t.factors[0]
+		result.factors = []factor{{base: f.base, key: f.key, exp: new(big.Rat).Mul(f.exp, exp)}}
+		return &sum{terms: []*term{mulTerms(result, &term{coef: big.NewRat(1, 1)})}}, nil
+	}
+	if exp.Denom().Cmp(big.NewInt(2)) != 0 {
+		return power(s, exp), nil
+	}
+	for _, f := range t.factors {
+		if !f.exp.IsInt() || f.exp.Num().Bit(0) != 0 {
+			return power(s, exp), nil
+		}
+		combined := new(big.Rat).Mul(f.exp, exp)
+		base := f.base
+		if combined.Num().Bit(0) != 0 {
+			base = &parser.Call{Name: "abs", Args: []parser.Node{f.base}}
+		}
+		result.factors = append(result.factors, newFactor(base, combined))
+	}
+	return &sum{terms: []*term{mulTerms(result, &term{coef: big.NewRat(1, 1)})}}, nil
+}
+
+func power(s *sum, exp *big.Rat) *sum {
+	return &sum{terms: []*term{{coef: big.NewRat(1, 1), factors: []factor{newFactor(s.node(), exp)}}}}
+}
+
+func (s *sum) inverted() bool {
+	for _, t := range s.terms {
+		for _, f := range t.factors {
+			if f.exp.Sign() < 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func powRat(base, exp *big.Rat) (*big.Rat, bool) {
+	if exp.IsInt() {
+		power, err := evaluator.ApplyRat("^", base, exp)
+		return power, err == nil
+	}
+	if base.Sign() < 0 || !exp.Denom().IsInt64() || exp.Denom().Int64() > maxExpandPower {
+		return nil, false
+	}
+	degree := exp.Denom().Int64()
+	num, ok := intRoot(base.Num(), degree)
+	if !ok {
+		return nil, false
+	}
+	den, ok := intRoot(base.Denom(), degree)
+	if !ok {
+		return nil, false
+	}
+	power, err := evaluator.ApplyRat("^", new(big.Rat).SetFrac(num, den), new(big.Rat).SetInt(exp.Num()))
+	return power, err == nil
+}
+
+func intRoot(n *big.Int, degree int64) (*big.Int, bool) {
+	if n.BitLen() > 1000 {
+		return nil, false
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	guess := int64(math.Round(math.Pow(f, 1/float64(degree))))
+	for _, candidate := range []int64{guess - 1, guess, guess + 1} {
+		if candidate < 0 {
+			continue
+		}
+		root := big.NewInt(candidate)
+		if new(big.Int).Exp(root, big.NewInt(degree), nil).Cmp(n) == 0 {
+			return root, true
+		}
+	}
+	return nil, false
+}
+
+func (s *sum) node() parser.Node {
+	if len(s.terms) == 0 {
+		return numberNode(new(big.Rat))
+	}
+
+	terms := append([]*term(nil), s.terms...)
+	sort.SliceStable(terms, func(i, j int) bool { return terms[i].before(terms[j]) })
+
+	result := terms[0].node(terms[0].coef.Sign() < 0)
+	for _, t := range terms[1:] {
+		op := "+"
+		if t.coef.Sign() < 0 {
+			op = "-"
+		}
+		result = &parser.BinaryOp{Op: op, Left: result, Right: t.node(false)}
+	}
+	return result
+}
+
+func (t *term) before(other *term) bool {
+	if cmp := t.degree().Cmp(other.degree()); cmp != 0 {
+		return cmp > 0
+	}
+	for i := 0; i < len(t.factors) && i < len(other.factors); i++ {
+		a, b := t.factors[i], other.factors[i]
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		if cmp := a.exp.Cmp(b.exp); cmp != 0 {
+			return cmp > 0
+		}
+	}
+	return len(t.factors) < len(other.factors)
+}
+
+func (t *term) node(negative bool) parser.Node {
+	coef := new(big.Rat).Abs(t.coef)
+	var numerator, denominator []parser.Node
+	if !coef.IsInt() {
+		denominator = append(denominator, numberNode(new(big.Rat).SetInt(coef.Denom())))
+	}
+	if coef.Num().Cmp(big.NewInt(1)) != 0 {
+		numerator = append(numerator, numberNode(new(big.Rat).SetInt(coef.Num())))
+	}
+	for _, f := range t.factors {
+		if f.exp.Sign() >= 0 {
+			numerator = append(numerator, powerNode(f.base, f.exp))
+		} else {
+			denominator = append(denominator, powerNode(f.base, new(big.Rat).Neg(f.exp)))
+		}
+	}
+
+	result := product(numerator)
+	if negative {
+		result = &parser.UnaryOp{Op: "-", Operand: result}
+	}
+	if len(denominator) > 0 {
+		result = &parser.BinaryOp{Op: "/", Left: result, Right: product(denominator)}
+	}
+	return result
+}
+
+func product(factors []parser.Node) parser.Node {
+	if len(factors) == 0 {
+		return numberNode(big.NewRat(1, 1))
+	}
+	result := factors[0]
+	for _, f := range factors[1:] {
+		result = &parser.BinaryOp{Op: "*", Left: result, Right: f}
+	}
+	return result
+}
+
+func powerNode(base parser.Node, exp *big.Rat) parser.Node {
+	switch {
+	case exp.Cmp(big.NewRat(1, 1)) == 0:
+		return base
+	case exp.Cmp(big.NewRat(1, 2)) == 0:
+		return &parser.Call{Name: "sqrt", Args: []parser.Node{base}}
+	}
+	return &parser.BinaryOp{Op: "^", Left: base, Right: numberNode(exp)}
+}
+
+func numberNode(r *big.Rat) parser.Node {
+	if r.Sign() < 0 {
+		return &parser.UnaryOp{Op: "-", Operand: numberNode(new(big.Rat).Neg(r))}
+	}
+	if !r.IsInt() {
+		return &parser.BinaryOp{Op: "/", Left: numberNode(new(big.Rat).SetInt(r.Num())), Right: numberNode(new(big.Rat).SetInt(r.Denom()))}
+	}
+	value, _ := r.Float64()
+	return &parser.Number{Value: value, Literal: r.Num().String(), Integer: true}
+}
+
+func literal(n *parser.Number) (*big.Rat, error) {
+	value, err := evaluator.Literal(n.Literal, evaluator.ModeExact)
+	if err != nil {
+		return nil, err
+	}
+	return value.(evaluator.Rat).Rat, nil
+}
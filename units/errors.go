@@ -0,0 +1,46 @@
+package units
+
+import "fmt"
+
+const CodeDimensionMismatch = "dimension_mismatch"
+
+type DimensionError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Operation string `json:"operation"`
+	Left      string `json:"left"`
+	Right     string `json:"right,omitempty"`
+}
+
+func (e *DimensionError) Error() string {
+	return e.Message
+}
+
+func Mismatch(op string, left, right Unit) *DimensionError {
+	return &DimensionError{
+		Code:      CodeDimensionMismatch,
+		Message:   fmt.Sprintf("несовместимые размерности в операции %q: %s и %s", op, describe(left), describe(right)),
+		Operation: op,
+		Left:      left.Dim.String(),
+		Right:     right.Dim.String(),
+	}
+}
+
+func NotDimensionless(op string, u Unit) *DimensionError {
+	return &DimensionError{
+		Code:      CodeDimensionMismatch,
+		Message:   fmt.Sprintf("операция %q ожидает безразмерный аргумент, получено %s", op, describe(u)),
+		Operation: op,
+		Left:      u.Dim.String(),
+	}
+}
+
+func describe(u Unit) string {
+	if u.Name == "" {
+		return "безразмерная величина"
+	}
+	if u.Name == u.Dim.String() {
+		return u.Name
+	}
+	return u.Name + " (" + u.Dim.String() + ")"
+}
@@ -0,0 +1,353 @@
+package units
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var baseUnits = [...]string{"m", "kg", "s", "A", "K", "mol", "cd"}
+
+type Dimension [len(baseUnits)]int
+
+type Unit struct {
+	Name  string
+	Scale float64
+	Dim   Dimension
+}
+
+type term struct {
+	name string
+	exp  int
+}
+
+var (
+	mu    sync.RWMutex
+	table = map[string]Unit{}
+)
+
+var defaultDefinitions = `
+km = 1000 m
+cm = 0.01 m
+mm = 0.001 m
+mi = 1609.344 m
+yd = 0.9144 m
+ft = 0.3048 m
+in = 0.0254 m
+g = 0.001 kg
+mg = 0.000001 kg
+t = 1000 kg
+lb = 0.45359237 kg
+oz = 0.028349523125 kg
+ms = 0.001 s
+min = 60 s
+h = 3600 s
+d = 86400 s
+mph = 1 mi/h
+N = 1 kg*m/s^2
+J = 1 N*m
+W = 1 J/s
+Pa = 1 N/m^2
+Hz = s^-1
+C = 1 A*s
+V = 1 W/A
+L = 0.001 m^3
+kWh = 3600000 J
+`
+
+func init() {
+	for i, name := range baseUnits {
+		var dim Dimension
+		dim[i] = 1
+		table[name] = Unit{Name: name, Scale: 1, Dim: dim}
+	}
+	if err := LoadDefinitions(strings.NewReader(defaultDefinitions)); err != nil {
+		panic(err)
+	}
+}
+
+func (d Dimension) IsZero() bool {
+	return d == Dimension{}
+}
+
+func (d Dimension) String() string {
+	terms := make([]term, 0, len(d))
+	for i, exp := range d {
+		if exp != 0 {
+			terms = append(terms, term{name: baseUnits[i], exp: exp})
+		}
+	}
+	if len(terms) == 0 {
+		return "1"
+	}
+	return render(terms)
+}
+
+func ParseDimension(s string) (Dimension, error) {
+	var dim Dimension
+	terms, err := parseTerms(s)
+	if err != nil {
+		return dim, err
+	}
+	for _, t := range terms {
+		i := baseIndex(t.name)
+		if i < 0 {
+			return dim, fmt.Errorf("неизвестная основная единица %q", t.name)
+		}
+		dim[i] += t.exp
+	}
+	return dim, nil
+}
+
+func (u Unit) Dimensionless() bool {
+	return u.Dim.IsZero()
+}
+
+func (u Unit) Mul(v Unit) Unit {
+	return combine(u, v, 1)
+}
+
+func (u Unit) Div(v Unit) Unit {
+	return combine(u, v, -1)
+}
+
+func (u Unit) Pow(p float64) (Unit, error) {
+	terms, err := parseTerms(u.Name)
+	if err != nil {
+		return Unit{}, err
+	}
+	for i, t := range terms {
+		exp := float64(t.exp) * p
+		if exp != math.Trunc(exp) {
+			return Unit{}, fmt.Errorf("единицу %s нельзя возвести в степень %v", u.Name, p)
+		}
+		terms[i].exp = int(exp)
+	}
+	var dim Dimension
+	for i, exp := range u.Dim {
+		dim[i] = int(float64(exp) * p)
+	}
+	return Unit{Name: render(terms), Scale: math.Pow(u.Scale, p), Dim: dim}, nil
+}
+
+func combine(u, v Unit, sign int) Unit {
+	left, _ := parseTerms(u.Name)
+	right, _ := parseTerms(v.Name)
+	for _, t := range right {
+		left = append(left, term{name: t.name, exp: sign * t.exp})
+	}
+	var dim Dimension
+	for i := range dim {
+		dim[i] = u.Dim[i] + sign*v.Dim[i]
+	}
+	scale := u.Scale * v.Scale
+	if sign < 0 {
+		scale = u.Scale / v.Scale
+	}
+	return Unit{Name: render(merge(left)), Scale: scale, Dim: dim}
+}
+
+func Lookup(name string) (Unit, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	u, ok := table[name]
+	return u, ok
+}
+
+func IsUnit(name string) bool {
+	_, ok := Lookup(name)
+	return ok
+}
+
+func Parse(expr string) (Unit, error) {
+	terms, err := parseTerms(expr)
+	if err != nil {
+		return Unit{}, err
+	}
+	result := Unit{Scale: 1}
+	for _, t := range terms {
+		u, ok := Lookup(t.name)
+		if !ok {
+			return Unit{}, fmt.Errorf("неизвестная единица измерения %q", t.name)
+		}
+		for i := range result.Dim {
+			result.Dim[i] += t.exp * u.Dim[i]
+		}
+		result.Scale *= math.Pow(u.Scale, float64(t.exp))
+	}
+	result.Name = render(merge(terms))
+	return result, nil
+}
+
+func Define(name string, value float64, expr string) error {
+	if !isName(name) {
+		return fmt.Errorf("некорректное имя единицы %q", name)
+	}
+	if baseIndex(name) >= 0 {
+		return fmt.Errorf("основную единицу %s нельзя переопределить", name)
+	}
+	if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return fmt.Errorf("множитель единицы %s должен быть положительным числом", name)
+	}
+	u, err := Parse(expr)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	table[name] = Unit{Name: name, Scale: value * u.Scale, Dim: u.Dim}
+	return nil
+}
+
+func LoadDefinitions(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		name, definition, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("строка %d: ожидалось определение вида \"имя = множитель единица\"", line)
+		}
+		value, expr := 1.0, strings.TrimSpace(definition)
+		if fields := strings.Fields(expr); len(fields) == 2 {
+			factor, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return fmt.Errorf("строка %d: некорректный множитель %q", line, fields[0])
+			}
+			value, expr = factor, fields[1]
+		}
+		if err := Define(strings.TrimSpace(name), value, expr); err != nil {
+			return fmt.Errorf("строка %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return LoadDefinitions(file)
+}
+
+func parseTerms(s string) ([]term, error) {
+	rest := strings.TrimSpace(s)
+	if rest == "1" {
+		return nil, nil
+	}
+	var terms []term
+	sign := 1
+	for {
+		part := rest
+		i := strings.IndexAny(rest, "*/")
+		if i >= 0 {
+			part = rest[:i]
+		}
+		name, power, raised := strings.Cut(strings.TrimSpace(part), "^")
+		exp := 1
+		if raised {
+			n, err := strconv.Atoi(strings.TrimSpace(power))
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("некорректная степень единицы %q", strings.TrimSpace(part))
+			}
+			exp = n
+		}
+		if name = strings.TrimSpace(name); !isName(name) {
+			return nil, fmt.Errorf("некорректная единица измерения %q", s)
+		}
+		terms = append(terms, term{name: name, exp: sign * exp})
+		if i < 0 {
+			return terms, nil
+		}
+		sign = 1
+		if rest[i] == '/' {
+			sign = -1
+		}
+		rest = rest[i+1:]
+	}
+}
+
+func merge(terms []term) []term {
+	merged := make([]term, 0, len(terms))
+	index := make(map[string]int, len(terms))
+	for _, t := range terms {
+		if i, ok := index[t.name]; ok {
+			merged[i].exp += t.exp
+			continue
+		}
+		index[t.name] = len(merged)
+		merged = append(merged, t)
+	}
+	result := merged[:0]
+	for _, t := range merged {
+		if t.exp != 0 {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func render(terms []term) string {
+	var numerator, denominator []string
+	for _, t := range terms {
+		if t.exp > 0 {
+			numerator = append(numerator, power(t.name, t.exp))
+		}
+	}
+	if len(numerator) == 0 {
+		for _, t := range terms {
+			denominator = append(denominator, power(t.name, t.exp))
+		}
+		return strings.Join(denominator, "*")
+	}
+	name := strings.Join(numerator, "*")
+	for _, t := range terms {
+		if t.exp < 0 {
+			name += "/" + power(t.name, -t.exp)
+		}
+	}
+	return name
+}
+
+func power(name string, exp int) string {
+	if exp == 1 {
+		return name
+	}
+	return name + "^" + strconv.Itoa(exp)
+}
+
+func baseIndex(name string) int {
+	for i, base := range baseUnits {
+		if base == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func isName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
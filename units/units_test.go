@@ -0,0 +1,117 @@
+package units
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr  string
+		name  string
+		scale float64
+		dim   string
+	}{
+		{"m", "m", 1, "m"},
+		{"km/h", "km/h", 1000.0 / 3600, "m/s"},
+		{"kg*m/s^2", "kg*m/s^2", 1, "m*kg/s^2"},
+		{"m/s/s", "m/s^2", 1, "m/s^2"},
+		{"m*m/m", "m", 1, "m"},
+		{"s^-1", "s^-1", 1, "s^-1"},
+		{"N", "N", 1, "m*kg/s^2"},
+		{"kWh", "kWh", 3.6e6, "m^2*kg/s^2"},
+		{"mph", "mph", 1609.344 / 3600, "m/s"},
+		{"L", "L", 0.001, "m^3"},
+	}
+
+	for _, tt := range tests {
+		u, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if u.Name != tt.name || math.Abs(u.Scale-tt.scale) > 1e-12*tt.scale || u.Dim.String() != tt.dim {
+			t.Errorf("Parse(%q) = %+v, expected %s with scale %v and dimension %s", tt.expr, u, tt.name, tt.scale, tt.dim)
+		}
+	}
+
+	for _, expr := range []string{"", "furlong", "m^", "m^0", "m^x", "m/", "*m", "2m"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error", expr)
+		}
+	}
+}
+
+func TestUnitArithmetic(t *testing.T) {
+	km, _ := Parse("km")
+	h, _ := Parse("h")
+	speed := km.Div(h)
+	if speed.Name != "km/h" || speed.Dim.String() != "m/s" {
+		t.Errorf("Expected km/h with dimension m/s, got %+v", speed)
+	}
+	if ratio := speed.Div(speed); !ratio.Dimensionless() || ratio.Name != "" {
+		t.Errorf("Expected a dimensionless ratio, got %+v", ratio)
+	}
+
+	area, err := km.Pow(2)
+	if err != nil || area.Name != "km^2" || area.Scale != 1e6 {
+		t.Errorf("Expected km^2 with scale 1e6, got %+v, %v", area, err)
+	}
+	if side, err := area.Pow(0.5); err != nil || side != km {
+		t.Errorf("Expected square root of km^2 to be km, got %+v, %v", side, err)
+	}
+	if _, err := km.Pow(0.5); err == nil {
+		t.Error("Expected error for square root of km")
+	}
+}
+
+func TestDimension(t *testing.T) {
+	for _, s := range []string{"1", "m", "m*kg/s^2", "s^-1", "A*K/mol/cd"} {
+		dim, err := ParseDimension(s)
+		if err != nil {
+			t.Fatalf("ParseDimension(%q) failed: %v", s, err)
+		}
+		if dim.String() != s {
+			t.Errorf("Expected %s, got %s", s, dim)
+		}
+	}
+	if _, err := ParseDimension("km"); err == nil {
+		t.Error("Expected error for non-base unit in dimension")
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	definitions := `
+# nautical units
+nmi = 1852 m
+kn = nmi/h   # knot
+`
+	if err := LoadDefinitions(strings.NewReader(definitions)); err != nil {
+		t.Fatalf("LoadDefinitions failed: %v", err)
+	}
+	kn, ok := Lookup("kn")
+	if !ok || math.Abs(kn.Scale-1852.0/3600) > 1e-12 || kn.Dim.String() != "m/s" {
+		t.Errorf("Expected knot to be defined, got %+v", kn)
+	}
+
+	for _, input := range []string{"nmi 1852 m", "x = 2 furlong", "m = 2 ft", "y = 0 m", "z = two m", "2x = 1 m"} {
+		if err := LoadDefinitions(strings.NewReader(input)); err == nil {
+			t.Errorf("LoadDefinitions(%q) expected error", input)
+		}
+	}
+	if err := LoadFile("missing-units.txt"); err == nil {
+		t.Error("Expected error for missing units file")
+	}
+}
+
+func TestDimensionError(t *testing.T) {
+	m, _ := Parse("m")
+	kg, _ := Parse("kg")
+	err := Mismatch("+", m, kg)
+	if err.Code != CodeDimensionMismatch || err.Left != "m" || err.Right != "kg" || err.Operation != "+" {
+		t.Errorf("Unexpected dimension error %+v", err)
+	}
+	if err.Error() != err.Message || !strings.Contains(err.Message, "m") {
+		t.Errorf("Unexpected message %q", err.Message)
+	}
+}